@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/metrics"
+	"github.com/aaguiarz/openfga-sync/storage"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bootstrapTokenPrefix marks a stored token as an in-progress bootstrap, so an
+// interrupted snapshot resumes from its last committed Read page
+const bootstrapTokenPrefix = "bootstrap:"
+
+// bootstrapClockSkew is subtracted from the snapshot start time so that
+// changes written just before the snapshot aren't missed if the OpenFGA
+// server's clock is behind ours. Replaying them is harmless in stateful mode.
+const bootstrapClockSkew = time.Minute
+
+// bootstrapToken encodes the snapshot start time and the Read API position
+func bootstrapToken(startTime time.Time, readToken string) string {
+	return bootstrapTokenPrefix + strconv.FormatInt(startTime.UnixNano(), 10) + ":" + readToken
+}
+
+// parseBootstrapToken decodes a token created by bootstrapToken
+func parseBootstrapToken(token string) (time.Time, string, bool) {
+	if !strings.HasPrefix(token, bootstrapTokenPrefix) {
+		return time.Time{}, "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(token, bootstrapTokenPrefix), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", false
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(0, nanos), parts[1], true
+}
+
+// needsBootstrap reports whether the stored token calls for a snapshot: either
+// bootstrap is enabled and nothing has been synced yet, or a previous
+// bootstrap was interrupted
+func needsBootstrap(cfg *config.Config, continuationToken string) bool {
+	if _, _, ok := parseBootstrapToken(continuationToken); ok {
+		return true
+	}
+	return cfg.Service.Bootstrap && continuationToken == ""
+}
+
+// runBootstrap snapshots the store's current tuples through the Read API into
+// the stateful table and returns the change stream position to continue from.
+// Each page is committed together with the bootstrap progress, and the final
+// page records a start-time position covering every change made since the
+// snapshot began.
+func runBootstrap(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, continuationToken string, logger *logrus.Logger, metrics *metrics.Metrics) (string, error) {
+	startTime, readToken, resuming := parseBootstrapToken(continuationToken)
+	if !resuming {
+		startTime = time.Now().Add(-bootstrapClockSkew)
+	}
+
+	tracer := otel.Tracer("openfga-sync/main")
+	ctx, span := tracer.Start(ctx, "sync.bootstrap",
+		trace.WithAttributes(
+			attribute.String("bootstrap.start_time", startTime.UTC().Format(time.RFC3339Nano)),
+			attribute.Bool("bootstrap.resuming", resuming),
+			attribute.String("sync.storage_type", cfg.Backend.Type),
+		),
+	)
+	defer span.End()
+
+	logger.WithFields(logrus.Fields{
+		"start_time": startTime,
+		"resuming":   resuming,
+	}).Info("Bootstrapping state from the OpenFGA Read API")
+
+	txAdapter, transactional := storageAdapter.(storage.TransactionalAdapter)
+	bootstrapStart := time.Now()
+	totalTuples := 0
+	pages := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		fetchStart := time.Now()
+		result, err := fgaFetcher.ReadTuplesWithRetry(ctx, readToken, cfg.Service.BatchSize)
+		if err != nil {
+			span.RecordError(err)
+			metrics.RecordOpenFGARequest("error", time.Since(fetchStart), "read")
+			return "", fmt.Errorf("failed to read tuples: %w", err)
+		}
+		metrics.RecordOpenFGARequest("success", time.Since(fetchStart), "read")
+
+		// The last page hands off to the change stream from the snapshot start
+		nextToken := fetcher.StartTimeToken(startTime)
+		if result.HasMore {
+			nextToken = bootstrapToken(startTime, result.ContinuationToken)
+		}
+
+		storageStart := time.Now()
+		if transactional {
+			err = txAdapter.ApplyChangesWithToken(ctx, result.Changes, nextToken)
+		} else {
+			err = storageAdapter.ApplyChanges(ctx, result.Changes)
+			if err == nil {
+				err = storageAdapter.SaveContinuationToken(ctx, nextToken)
+			}
+		}
+		if err != nil {
+			span.RecordError(err)
+			metrics.RecordStorageOperation("bootstrap", "error", time.Since(storageStart))
+			return "", fmt.Errorf("failed to apply bootstrap page: %w", err)
+		}
+		metrics.RecordStorageOperation("bootstrap", "success", time.Since(storageStart))
+		metrics.RecordChangesProcessed(len(result.Changes))
+
+		pages++
+		totalTuples += len(result.Changes)
+		logger.WithFields(logrus.Fields{
+			"page":         pages,
+			"page_tuples":  len(result.Changes),
+			"total_tuples": totalTuples,
+		}).Debug("Applied bootstrap page")
+
+		if !result.HasMore {
+			span.SetAttributes(
+				attribute.Int("bootstrap.pages", pages),
+				attribute.Int("bootstrap.tuples", totalTuples),
+			)
+			logger.WithFields(logrus.Fields{
+				"pages":       pages,
+				"tuples":      totalTuples,
+				"duration_ms": time.Since(bootstrapStart).Milliseconds(),
+				"next_token":  nextToken,
+			}).Info("Bootstrap complete, continuing with incremental sync")
+			return nextToken, nil
+		}
+
+		readToken = result.ContinuationToken
+	}
+}
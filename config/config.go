@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -16,8 +17,20 @@ type StorageMode string
 const (
 	StorageModeChangelog StorageMode = "changelog"
 	StorageModeStateful  StorageMode = "stateful"
+	StorageModeHybrid    StorageMode = "hybrid"
+	StorageModeHistory   StorageMode = "history"
 )
 
+// HasChangelog reports whether the mode appends changes to fga_changelog
+func (m StorageMode) HasChangelog() bool {
+	return m == StorageModeChangelog || m == StorageModeHybrid
+}
+
+// HasState reports whether the mode keeps current tuples in fga_tuples
+func (m StorageMode) HasState() bool {
+	return m == StorageModeStateful || m == StorageModeHybrid
+}
+
 // Config represents the application configuration
 type Config struct {
 	Server        ServerConfig        `yaml:"server"`
@@ -36,10 +49,33 @@ type ServerConfig struct {
 
 // OpenFGAConfig contains OpenFGA-specific configuration
 type OpenFGAConfig struct {
-	Endpoint string     `yaml:"endpoint" env:"OPENFGA_ENDPOINT"`
-	Token    string     `yaml:"token" env:"OPENFGA_TOKEN"`
-	StoreID  string     `yaml:"store_id" env:"OPENFGA_STORE_ID"`
-	OIDC     OIDCConfig `yaml:"oidc"`
+	Endpoint  string     `yaml:"endpoint" env:"OPENFGA_ENDPOINT"`
+	Token     string     `yaml:"token" env:"OPENFGA_TOKEN"`
+	StoreID   string     `yaml:"store_id" env:"OPENFGA_STORE_ID"`
+	StoreIDs  []string   `yaml:"store_ids" env:"OPENFGA_STORE_IDS"`   // Additional stores synced by the same process
+	AllStores bool       `yaml:"all_stores" env:"OPENFGA_ALL_STORES"` // Discover every store via the ListStores API
+	OIDC      OIDCConfig `yaml:"oidc"`
+}
+
+// Stores returns the configured store IDs, store_id first, without duplicates.
+// It is empty when stores are discovered with all_stores.
+func (c OpenFGAConfig) Stores() []string {
+	var stores []string
+	seen := make(map[string]bool)
+	for _, id := range append([]string{c.StoreID}, c.StoreIDs...) {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		stores = append(stores, id)
+	}
+	return stores
+}
+
+// MultiStore reports whether more than one store may be synced
+func (c OpenFGAConfig) MultiStore() bool {
+	return c.AllStores || len(c.Stores()) > 1
 }
 
 // OIDCConfig contains OIDC authentication configuration for OpenFGA
@@ -52,11 +88,121 @@ type OIDCConfig struct {
 	TokenIssuer  string   `yaml:"token_issuer" env:"OPENFGA_OIDC_TOKEN_ISSUER"`
 }
 
-// BackendConfig contains backend storage configuration
+// BackendConfig contains backend storage configuration. In YAML, backend may
+// also be a list of sinks, each with its own type, DSN and mode, that are all
+// fed from one fetch loop.
 type BackendConfig struct {
-	Type string      `yaml:"type" env:"BACKEND_TYPE"`
-	DSN  string      `yaml:"dsn" env:"BACKEND_DSN"`
-	Mode StorageMode `yaml:"mode" env:"BACKEND_MODE"`
+	Name  string          `yaml:"name"` // Sink name in logs and metrics, defaults to the type
+	Type  string          `yaml:"type" env:"BACKEND_TYPE"`
+	DSN   string          `yaml:"dsn" env:"BACKEND_DSN"`
+	Mode  StorageMode     `yaml:"mode" env:"BACKEND_MODE"`
+	Sinks []BackendConfig `yaml:"-"` // Set when backend is a list
+
+	// Schema and TablePrefix place the tables of the postgres and sqlite
+	// backends, so several pipelines can share one database
+	Schema      string `yaml:"schema" env:"BACKEND_SCHEMA"`             // Postgres schema; "" uses the search path
+	TablePrefix string `yaml:"table_prefix" env:"BACKEND_TABLE_PREFIX"` // Prepended to every table and index name
+
+	Partitioning PartitionConfig `yaml:"partitioning"` // Postgres changelog partitioning
+	Retention    RetentionConfig `yaml:"retention"`    // SQLite changelog retention and compaction
+
+	// BulkSize is the smallest postgres batch written with COPY and multi-row
+	// statements; 0 uses the adapter default of 100, a full ReadChanges page
+	BulkSize int `yaml:"bulk_size" env:"BACKEND_BULK_SIZE"`
+}
+
+// RetentionConfig bounds the sqlite fga_changelog with a background task that
+// deletes old changes, compacts write/delete pairs and reclaims disk space
+type RetentionConfig struct {
+	MaxAge         time.Duration `yaml:"max_age" env:"BACKEND_RETENTION_MAX_AGE"`   // Changes older than this are deleted; 0 keeps them
+	MaxRows        int64         `yaml:"max_rows" env:"BACKEND_RETENTION_MAX_ROWS"` // The oldest changes beyond this count are deleted; 0 is unlimited
+	CompactAfter   time.Duration `yaml:"compact_after"`                             // A write and the delete of the same tuple are both removed once the delete is older than this; 0 disables compaction
+	VacuumInterval time.Duration `yaml:"vacuum_interval"`                           // Minimum time between VACUUMs; 0 only checkpoints the WAL
+	CheckInterval  time.Duration `yaml:"check_interval"`                            // How often the task runs; defaults to 1h
+}
+
+// Enabled reports whether any retention, compaction or vacuum is configured
+func (r RetentionConfig) Enabled() bool {
+	return r.MaxAge > 0 || r.MaxRows > 0 || r.CompactAfter > 0 || r.VacuumInterval > 0
+}
+
+// WithDefaults returns the retention settings with unset fields defaulted
+func (r RetentionConfig) WithDefaults() RetentionConfig {
+	if r.CheckInterval == 0 {
+		r.CheckInterval = time.Hour
+	}
+	return r
+}
+
+// PartitionConfig range-partitions the postgres fga_changelog table on the
+// change timestamp, so old changes are removed by dropping whole partitions
+// instead of deleting rows
+type PartitionConfig struct {
+	Enabled         bool          `yaml:"enabled" env:"BACKEND_PARTITIONING_ENABLED"`
+	Interval        string        `yaml:"interval" env:"BACKEND_PARTITION_INTERVAL"`   // day, week or month; defaults to month
+	Premake         int           `yaml:"premake"`                                     // Upcoming partitions created ahead of time; defaults to 3
+	Retention       time.Duration `yaml:"retention" env:"BACKEND_PARTITION_RETENTION"` // Partitions entirely older than this are removed; 0 keeps everything
+	RetentionAction string        `yaml:"retention_action"`                            // drop or detach; defaults to drop
+	CheckInterval   time.Duration `yaml:"check_interval"`                              // How often partitions are maintained; defaults to 1h
+}
+
+// Partition intervals and retention actions
+const (
+	PartitionIntervalDay   = "day"
+	PartitionIntervalWeek  = "week"
+	PartitionIntervalMonth = "month"
+
+	RetentionActionDrop   = "drop"
+	RetentionActionDetach = "detach"
+)
+
+// WithDefaults returns the partitioning settings with unset fields defaulted
+func (p PartitionConfig) WithDefaults() PartitionConfig {
+	if p.Interval == "" {
+		p.Interval = PartitionIntervalMonth
+	}
+	if p.Premake == 0 {
+		p.Premake = 3
+	}
+	if p.RetentionAction == "" {
+		p.RetentionAction = RetentionActionDrop
+	}
+	if p.CheckInterval == 0 {
+		p.CheckInterval = time.Hour
+	}
+	return p
+}
+
+// UnmarshalYAML decodes either a single backend mapping or a list of sinks
+func (b *BackendConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		sinks := make([]BackendConfig, len(node.Content))
+		for i, item := range node.Content {
+			// Sinks default to changelog mode like a single backend
+			sinks[i].Mode = StorageModeChangelog
+			if err := item.Decode(&sinks[i]); err != nil {
+				return err
+			}
+		}
+		// A list of one is a plain backend
+		if len(sinks) == 1 {
+			*b = sinks[0]
+		} else {
+			*b = BackendConfig{Sinks: sinks}
+		}
+		return nil
+	}
+
+	type plain BackendConfig
+	return node.Decode((*plain)(b))
+}
+
+// SinkName returns the name of the backend as a fan-out sink
+func (b BackendConfig) SinkName() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.Type
 }
 
 // LoggingConfig contains logging-specific configuration
@@ -96,13 +242,34 @@ type ServiceConfig struct {
 	BackoffFactor    float64       `yaml:"backoff_factor" env:"BACKOFF_FACTOR"`
 	RateLimitDelay   time.Duration `yaml:"rate_limit_delay" env:"RATE_LIMIT_DELAY"`
 	EnableValidation bool          `yaml:"enable_validation" env:"ENABLE_VALIDATION"`
+	Bootstrap        bool          `yaml:"bootstrap" env:"BOOTSTRAP"`           // Snapshot current tuples via Read before the first sync (stateful mode)
+	PipelineDepth    int           `yaml:"pipeline_depth" env:"PIPELINE_DEPTH"` // Batches prefetched ahead of the writer (0 = sequential)
+	SinkBuffer       int           `yaml:"sink_buffer" env:"SINK_BUFFER"`       // Pages kept in memory for sinks lagging behind the fastest one
+	ObjectTypes      []string      `yaml:"object_types" env:"OBJECT_TYPES"`     // Fetch only these types, with one cursor per type
+	StartTime        string        `yaml:"start_time" env:"START_TIME"`         // "now" or an RFC3339 timestamp to start from when nothing has been synced yet
+	Filter           FilterConfig  `yaml:"filter"`
+}
+
+// FilterConfig contains client-side change filters for criteria the
+// ReadChanges API can't filter on. Exclude rules take precedence.
+type FilterConfig struct {
+	IncludeRelations []string `yaml:"include_relations" env:"FILTER_INCLUDE_RELATIONS"`
+	ExcludeRelations []string `yaml:"exclude_relations" env:"FILTER_EXCLUDE_RELATIONS"`
+	IncludeUserTypes []string `yaml:"include_user_types" env:"FILTER_INCLUDE_USER_TYPES"`
+	ExcludeUserTypes []string `yaml:"exclude_user_types" env:"FILTER_EXCLUDE_USER_TYPES"`
 }
 
 // LeadershipConfig contains leader election configuration
 type LeadershipConfig struct {
-	Enabled   bool   `yaml:"enabled" env:"LEADERSHIP_ENABLED"`
-	Namespace string `yaml:"namespace" env:"LEADERSHIP_NAMESPACE"`
-	LockName  string `yaml:"lock_name" env:"LEADERSHIP_LOCK_NAME"`
+	Enabled       bool          `yaml:"enabled" env:"LEADERSHIP_ENABLED"`
+	Backend       string        `yaml:"backend" env:"LEADERSHIP_BACKEND"` // kubernetes, postgres or file
+	Namespace     string        `yaml:"namespace" env:"LEADERSHIP_NAMESPACE"`
+	LockName      string        `yaml:"lock_name" env:"LEADERSHIP_LOCK_NAME"`
+	Identity      string        `yaml:"identity" env:"LEADERSHIP_IDENTITY"` // Defaults to the hostname
+	DSN           string        `yaml:"dsn" env:"LEADERSHIP_DSN"`           // Postgres DSN or lock file path
+	LeaseDuration time.Duration `yaml:"lease_duration" env:"LEADERSHIP_LEASE_DURATION"`
+	RenewDeadline time.Duration `yaml:"renew_deadline" env:"LEADERSHIP_RENEW_DEADLINE"`
+	RetryPeriod   time.Duration `yaml:"retry_period" env:"LEADERSHIP_RETRY_PERIOD"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -145,11 +312,18 @@ func DefaultConfig() *Config {
 			BackoffFactor:    2.0,
 			RateLimitDelay:   50 * time.Millisecond,
 			EnableValidation: true,
+			Bootstrap:        false,
+			PipelineDepth:    0, // Sequential unless prefetching is enabled
+			SinkBuffer:       64,
 		},
 		Leadership: LeadershipConfig{
-			Enabled:   false,
-			Namespace: "default",
-			LockName:  "openfga-sync-leader",
+			Enabled:       false,
+			Backend:       "kubernetes",
+			Namespace:     "default",
+			LockName:      "openfga-sync-leader",
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
 		},
 	}
 }
@@ -215,6 +389,14 @@ func loadFromEnv(config *Config) error {
 	if storeID := os.Getenv("OPENFGA_STORE_ID"); storeID != "" {
 		config.OpenFGA.StoreID = storeID
 	}
+	if storeIDs := os.Getenv("OPENFGA_STORE_IDS"); storeIDs != "" {
+		config.OpenFGA.StoreIDs = splitList(storeIDs)
+	}
+	if allStores := os.Getenv("OPENFGA_ALL_STORES"); allStores != "" {
+		if b, err := strconv.ParseBool(allStores); err == nil {
+			config.OpenFGA.AllStores = b
+		}
+	}
 
 	// OpenFGA OIDC configuration
 	if issuer := os.Getenv("OPENFGA_OIDC_ISSUER"); issuer != "" {
@@ -250,6 +432,40 @@ func loadFromEnv(config *Config) error {
 	if mode := os.Getenv("BACKEND_MODE"); mode != "" {
 		config.Backend.Mode = StorageMode(mode)
 	}
+	if schema := os.Getenv("BACKEND_SCHEMA"); schema != "" {
+		config.Backend.Schema = schema
+	}
+	if tablePrefix := os.Getenv("BACKEND_TABLE_PREFIX"); tablePrefix != "" {
+		config.Backend.TablePrefix = tablePrefix
+	}
+	if enabled := os.Getenv("BACKEND_PARTITIONING_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			config.Backend.Partitioning.Enabled = e
+		}
+	}
+	if interval := os.Getenv("BACKEND_PARTITION_INTERVAL"); interval != "" {
+		config.Backend.Partitioning.Interval = interval
+	}
+	if retention := os.Getenv("BACKEND_PARTITION_RETENTION"); retention != "" {
+		if r, err := time.ParseDuration(retention); err == nil {
+			config.Backend.Partitioning.Retention = r
+		}
+	}
+	if bulkSize := os.Getenv("BACKEND_BULK_SIZE"); bulkSize != "" {
+		if b, err := strconv.Atoi(bulkSize); err == nil {
+			config.Backend.BulkSize = b
+		}
+	}
+	if maxAge := os.Getenv("BACKEND_RETENTION_MAX_AGE"); maxAge != "" {
+		if m, err := time.ParseDuration(maxAge); err == nil {
+			config.Backend.Retention.MaxAge = m
+		}
+	}
+	if maxRows := os.Getenv("BACKEND_RETENTION_MAX_ROWS"); maxRows != "" {
+		if m, err := strconv.ParseInt(maxRows, 10, 64); err == nil {
+			config.Backend.Retention.MaxRows = m
+		}
+	}
 
 	// Logging configuration
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
@@ -333,6 +549,39 @@ func loadFromEnv(config *Config) error {
 			config.Service.EnableValidation = e
 		}
 	}
+	if bootstrap := os.Getenv("BOOTSTRAP"); bootstrap != "" {
+		if b, err := strconv.ParseBool(bootstrap); err == nil {
+			config.Service.Bootstrap = b
+		}
+	}
+	if startTime := os.Getenv("START_TIME"); startTime != "" {
+		config.Service.StartTime = startTime
+	}
+	if pipelineDepth := os.Getenv("PIPELINE_DEPTH"); pipelineDepth != "" {
+		if p, err := strconv.Atoi(pipelineDepth); err == nil {
+			config.Service.PipelineDepth = p
+		}
+	}
+	if sinkBuffer := os.Getenv("SINK_BUFFER"); sinkBuffer != "" {
+		if b, err := strconv.Atoi(sinkBuffer); err == nil {
+			config.Service.SinkBuffer = b
+		}
+	}
+	if objectTypes := os.Getenv("OBJECT_TYPES"); objectTypes != "" {
+		config.Service.ObjectTypes = splitList(objectTypes)
+	}
+	if relations := os.Getenv("FILTER_INCLUDE_RELATIONS"); relations != "" {
+		config.Service.Filter.IncludeRelations = splitList(relations)
+	}
+	if relations := os.Getenv("FILTER_EXCLUDE_RELATIONS"); relations != "" {
+		config.Service.Filter.ExcludeRelations = splitList(relations)
+	}
+	if userTypes := os.Getenv("FILTER_INCLUDE_USER_TYPES"); userTypes != "" {
+		config.Service.Filter.IncludeUserTypes = splitList(userTypes)
+	}
+	if userTypes := os.Getenv("FILTER_EXCLUDE_USER_TYPES"); userTypes != "" {
+		config.Service.Filter.ExcludeUserTypes = splitList(userTypes)
+	}
 
 	// Leadership configuration
 	if enabled := os.Getenv("LEADERSHIP_ENABLED"); enabled != "" {
@@ -346,6 +595,30 @@ func loadFromEnv(config *Config) error {
 	if lockName := os.Getenv("LEADERSHIP_LOCK_NAME"); lockName != "" {
 		config.Leadership.LockName = lockName
 	}
+	if backend := os.Getenv("LEADERSHIP_BACKEND"); backend != "" {
+		config.Leadership.Backend = backend
+	}
+	if identity := os.Getenv("LEADERSHIP_IDENTITY"); identity != "" {
+		config.Leadership.Identity = identity
+	}
+	if dsn := os.Getenv("LEADERSHIP_DSN"); dsn != "" {
+		config.Leadership.DSN = dsn
+	}
+	if leaseDuration := os.Getenv("LEADERSHIP_LEASE_DURATION"); leaseDuration != "" {
+		if l, err := time.ParseDuration(leaseDuration); err == nil {
+			config.Leadership.LeaseDuration = l
+		}
+	}
+	if renewDeadline := os.Getenv("LEADERSHIP_RENEW_DEADLINE"); renewDeadline != "" {
+		if r, err := time.ParseDuration(renewDeadline); err == nil {
+			config.Leadership.RenewDeadline = r
+		}
+	}
+	if retryPeriod := os.Getenv("LEADERSHIP_RETRY_PERIOD"); retryPeriod != "" {
+		if r, err := time.ParseDuration(retryPeriod); err == nil {
+			config.Leadership.RetryPeriod = r
+		}
+	}
 
 	return nil
 }
@@ -358,9 +631,18 @@ func (c *Config) validate() error {
 	if c.OpenFGA.Endpoint == "" {
 		errors = append(errors, "openfga.endpoint is required")
 	}
-	if c.OpenFGA.StoreID == "" {
+	if c.OpenFGA.AllStores {
+		if len(c.OpenFGA.Stores()) > 0 {
+			errors = append(errors, "openfga.store_id and openfga.store_ids must be empty when openfga.all_stores is enabled")
+		}
+	} else if len(c.OpenFGA.Stores()) == 0 {
 		errors = append(errors, "openfga.store_id is required")
 	}
+	if c.OpenFGA.MultiStore() && c.FanOut() {
+		errors = append(errors, "multi-store sync can't be combined with a list of backend sinks")
+	} else if c.OpenFGA.MultiStore() && !c.hasDatabaseBackend() {
+		errors = append(errors, fmt.Sprintf("multi-store sync requires a database backend, not %s", c.Backend.Type))
+	}
 
 	// Validate OpenFGA authentication: either token or OIDC config must be provided
 	hasToken := c.OpenFGA.Token != ""
@@ -385,14 +667,20 @@ func (c *Config) validate() error {
 	}
 
 	// Validate backend configuration
-	if c.Backend.Type == "" {
-		errors = append(errors, "backend.type is required")
-	}
-	if c.Backend.DSN == "" {
-		errors = append(errors, "backend.dsn is required")
-	}
-	if c.Backend.Mode != StorageModeChangelog && c.Backend.Mode != StorageModeStateful {
-		errors = append(errors, "backend.mode must be 'changelog' or 'stateful'")
+	if c.FanOut() {
+		if c.Backend.Type != "" || c.Backend.DSN != "" || c.Backend.Mode != "" {
+			errors = append(errors, "backend.type, backend.dsn and backend.mode can't be set when backend is a list of sinks")
+		}
+		seenSinks := make(map[string]bool)
+		for i, sink := range c.Backend.Sinks {
+			errors = append(errors, validateBackend(sink, fmt.Sprintf("backend[%d]", i))...)
+			if name := sink.SinkName(); seenSinks[name] {
+				errors = append(errors, fmt.Sprintf("backend[%d] reuses the sink name %q; sinks of the same type need distinct names", i, name))
+			}
+			seenSinks[sink.SinkName()] = true
+		}
+	} else {
+		errors = append(errors, validateBackend(c.Backend, "backend")...)
 	}
 
 	// Validate logging configuration
@@ -434,6 +722,73 @@ func (c *Config) validate() error {
 	if c.Service.RateLimitDelay < 0 {
 		errors = append(errors, "service.rate_limit_delay must be non-negative")
 	}
+	if c.Service.PipelineDepth < 0 {
+		errors = append(errors, "service.pipeline_depth must be non-negative")
+	}
+	if c.Service.SinkBuffer <= 0 {
+		errors = append(errors, "service.sink_buffer must be positive")
+	}
+	seenTypes := make(map[string]bool)
+	for _, objectType := range c.Service.ObjectTypes {
+		if objectType == "" || strings.ContainsAny(objectType, ":#") {
+			errors = append(errors, fmt.Sprintf("service.object_types contains invalid type %q", objectType))
+		} else if seenTypes[objectType] {
+			errors = append(errors, fmt.Sprintf("service.object_types contains duplicate type %q", objectType))
+		}
+		seenTypes[objectType] = true
+	}
+	if len(c.Service.ObjectTypes) > 1 && c.FanOut() {
+		errors = append(errors, "service.object_types with more than one type can't be combined with a list of backend sinks")
+	} else if len(c.Service.ObjectTypes) > 1 && !c.hasDatabaseBackend() {
+		errors = append(errors, fmt.Sprintf("service.object_types with more than one type requires a database backend, not %s", c.Backend.Type))
+	}
+	if c.Service.Bootstrap {
+		for _, sink := range c.Sinks() {
+			if !sink.Mode.HasState() && sink.Mode != StorageModeHistory {
+				errors = append(errors, "service.bootstrap requires backend.mode 'stateful', 'hybrid' or 'history'")
+				break
+			}
+		}
+	}
+	if c.Service.StartTime != "" {
+		if _, err := ParseStartTime(c.Service.StartTime, time.Now()); err != nil {
+			errors = append(errors, err.Error())
+		}
+		if c.Service.Bootstrap {
+			errors = append(errors, "service.start_time cannot be combined with service.bootstrap")
+		}
+	}
+
+	// Validate leadership configuration
+	if c.Leadership.Enabled {
+		validLeadershipBackends := []string{"kubernetes", "postgres", "file"}
+		if !contains(validLeadershipBackends, c.Leadership.Backend) {
+			errors = append(errors, "leadership.backend must be one of: kubernetes, postgres, file")
+		}
+		if c.Leadership.LockName == "" {
+			errors = append(errors, "leadership.lock_name is required when leadership is enabled")
+		}
+		if c.Leadership.RetryPeriod <= 0 {
+			errors = append(errors, "leadership.retry_period must be positive")
+		}
+		if c.Leadership.Backend == "kubernetes" {
+			if c.Leadership.Namespace == "" {
+				errors = append(errors, "leadership.namespace is required for the kubernetes backend")
+			}
+			if c.Leadership.LeaseDuration <= c.Leadership.RenewDeadline {
+				errors = append(errors, "leadership.lease_duration must be greater than leadership.renew_deadline")
+			}
+			if c.Leadership.RenewDeadline <= c.Leadership.RetryPeriod {
+				errors = append(errors, "leadership.renew_deadline must be greater than leadership.retry_period")
+			}
+		}
+		if c.Leadership.Backend == "postgres" && c.Leadership.DSN == "" && c.Backend.Type != "postgres" {
+			errors = append(errors, "leadership.dsn is required for the postgres backend unless backend.type is postgres")
+		}
+		if c.Leadership.Backend == "file" && c.Leadership.DSN == "" && c.Backend.Type != "sqlite" {
+			errors = append(errors, "leadership.dsn (lock file path) is required for the file backend unless backend.type is sqlite")
+		}
+	}
 
 	if len(errors) > 0 {
 		return fmt.Errorf("validation errors: %s", strings.Join(errors, ", "))
@@ -442,6 +797,210 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validateBackend validates one backend or sink, naming fields after path
+func validateBackend(b BackendConfig, path string) []string {
+	var errors []string
+	if b.Type == "" {
+		errors = append(errors, path+".type is required")
+	}
+	if b.DSN == "" {
+		errors = append(errors, path+".dsn is required")
+	}
+	switch b.Mode {
+	case StorageModeChangelog, StorageModeStateful:
+	case StorageModeHybrid:
+		if b.Type != "postgres" && b.Type != "sqlite" {
+			errors = append(errors, fmt.Sprintf("%s.mode 'hybrid' requires a postgres or sqlite backend, not %s", path, b.Type))
+		}
+	case StorageModeHistory:
+		if b.Type != "postgres" && b.Type != "sqlite" && b.Type != "mysql" && b.Type != "mariadb" {
+			errors = append(errors, fmt.Sprintf("%s.mode 'history' requires a postgres, mysql or sqlite backend, not %s", path, b.Type))
+		}
+	default:
+		errors = append(errors, path+".mode must be 'changelog', 'stateful', 'hybrid' or 'history'")
+	}
+	if b.Schema != "" {
+		if b.Type != "postgres" {
+			errors = append(errors, fmt.Sprintf("%s.schema requires a postgres backend, not %s", path, b.Type))
+		}
+		if !validIdentifier(b.Schema, maxSchemaLength) {
+			errors = append(errors, fmt.Sprintf("%s.schema must be lowercase letters, digits and underscores, starting with a letter or underscore, at most %d characters", path, maxSchemaLength))
+		}
+	}
+	if b.TablePrefix != "" {
+		if b.Type != "postgres" && b.Type != "sqlite" {
+			errors = append(errors, fmt.Sprintf("%s.table_prefix requires a postgres or sqlite backend, not %s", path, b.Type))
+		}
+		if !validIdentifier(b.TablePrefix, maxTablePrefixLength) {
+			errors = append(errors, fmt.Sprintf("%s.table_prefix must be lowercase letters, digits and underscores, starting with a letter or underscore, at most %d characters", path, maxTablePrefixLength))
+		}
+	}
+	if b.Partitioning.Enabled {
+		errors = append(errors, validatePartitioning(b, path+".partitioning")...)
+	}
+	errors = append(errors, validateRetention(b, path+".retention")...)
+	if b.BulkSize < 0 {
+		errors = append(errors, path+".bulk_size can't be negative")
+	} else if b.BulkSize > 0 && b.Type != "postgres" {
+		errors = append(errors, fmt.Sprintf("%s.bulk_size requires a postgres backend, not %s", path, b.Type))
+	}
+	// Kafka can't hold the continuation token, so without a persistent
+	// checkpoint every restart republishes the whole change history
+	if b.Type == "kafka" && b.DSN != "" {
+		switch checkpointType(b.DSN) {
+		case "file", "sqlite", "postgres":
+		default:
+			errors = append(errors, path+".dsn of a kafka backend must set a file, sqlite or postgres checkpoint")
+		}
+	}
+	if len(b.Sinks) > 0 {
+		errors = append(errors, path+" can't itself be a list of sinks")
+	}
+	return errors
+}
+
+// checkpointType returns the checkpoint type of a JSON DSN, or "" if it
+// doesn't set one
+func checkpointType(dsn string) string {
+	var parsed struct {
+		Checkpoint struct {
+			Type string `json:"type"`
+		} `json:"checkpoint"`
+	}
+	if err := json.Unmarshal([]byte(dsn), &parsed); err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Checkpoint.Type)
+}
+
+// validatePartitioning checks the changelog partitioning of one backend
+func validatePartitioning(b BackendConfig, path string) []string {
+	var errors []string
+	p := b.Partitioning
+	if b.Type != "postgres" {
+		errors = append(errors, fmt.Sprintf("%s requires a postgres backend, not %s", path, b.Type))
+	}
+	if !b.Mode.HasChangelog() {
+		errors = append(errors, fmt.Sprintf("%s requires changelog or hybrid mode, not %s", path, b.Mode))
+	}
+	switch p.Interval {
+	case "", PartitionIntervalDay, PartitionIntervalWeek, PartitionIntervalMonth:
+	default:
+		errors = append(errors, path+".interval must be 'day', 'week' or 'month'")
+	}
+	if p.Premake < 0 {
+		errors = append(errors, path+".premake can't be negative")
+	}
+	if p.Retention < 0 {
+		errors = append(errors, path+".retention can't be negative")
+	}
+	switch p.RetentionAction {
+	case "", RetentionActionDrop, RetentionActionDetach:
+	default:
+		errors = append(errors, path+".retention_action must be 'drop' or 'detach'")
+	}
+	if p.CheckInterval < 0 {
+		errors = append(errors, path+".check_interval can't be negative")
+	}
+	return errors
+}
+
+// validateRetention checks the changelog retention of one backend
+func validateRetention(b BackendConfig, path string) []string {
+	var errors []string
+	r := b.Retention
+	if r.MaxAge < 0 {
+		errors = append(errors, path+".max_age can't be negative")
+	}
+	if r.MaxRows < 0 {
+		errors = append(errors, path+".max_rows can't be negative")
+	}
+	if r.CompactAfter < 0 {
+		errors = append(errors, path+".compact_after can't be negative")
+	}
+	if r.VacuumInterval < 0 {
+		errors = append(errors, path+".vacuum_interval can't be negative")
+	}
+	if r.CheckInterval < 0 {
+		errors = append(errors, path+".check_interval can't be negative")
+	}
+	if r.Enabled() {
+		if b.Type != "sqlite" {
+			errors = append(errors, fmt.Sprintf("%s requires a sqlite backend, not %s", path, b.Type))
+		}
+		if !b.Mode.HasChangelog() {
+			errors = append(errors, fmt.Sprintf("%s requires changelog or hybrid mode, not %s", path, b.Mode))
+		}
+	}
+	return errors
+}
+
+// Schema and table prefix limits. Postgres truncates identifiers past 63
+// bytes, and the longest prefixed name is the 28-character
+// idx_fga_tuple_history_object index.
+const (
+	maxSchemaLength      = 63
+	maxTablePrefixLength = 32
+)
+
+// validIdentifier reports whether name can be used unquoted in SQL without
+// Postgres folding its case
+func validIdentifier(name string, maxLength int) bool {
+	if len(name) > maxLength {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FanOut reports whether backend is a list of sinks fed from one fetch loop
+func (c *Config) FanOut() bool {
+	return len(c.Backend.Sinks) > 0
+}
+
+// Sinks returns the sinks of a backend list, or the single backend
+func (c *Config) Sinks() []BackendConfig {
+	if c.FanOut() {
+		return c.Backend.Sinks
+	}
+	return []BackendConfig{c.Backend}
+}
+
+// SinkNames returns the name of every sink, in configuration order
+func (c *Config) SinkNames() []string {
+	var names []string
+	for _, sink := range c.Sinks() {
+		names = append(names, sink.SinkName())
+	}
+	return names
+}
+
+// ForSink returns a copy of the configuration with backend set to sink, for
+// code that handles one backend at a time
+func (c *Config) ForSink(sink BackendConfig) *Config {
+	sinkCfg := *c
+	sinkCfg.Backend = sink
+	return &sinkCfg
+}
+
+// hasDatabaseBackend reports whether the backend keeps continuation tokens in
+// its own tables, and so can track one per store and object type. The openfga,
+// kafka, nats, redis, file, parquet and webhook sinks keep a single token.
+func (c *Config) hasDatabaseBackend() bool {
+	switch c.Backend.Type {
+	case "openfga", "kafka", "nats", "redis", "file", "parquet", "webhook":
+		return false
+	}
+	return true
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -452,6 +1011,31 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// splitList parses a comma-separated environment variable, trimming whitespace
+// and dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseStartTime parses a service.start_time value: "now" resolves to now,
+// anything else must be an RFC3339 timestamp
+func ParseStartTime(value string, now time.Time) (time.Time, error) {
+	if strings.EqualFold(value, "now") {
+		return now, nil
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("service.start_time must be \"now\" or an RFC3339 timestamp: %q", value)
+	}
+	return startTime, nil
+}
+
 // IsChangelogMode returns true if the storage mode is changelog
 func (c *Config) IsChangelogMode() bool {
 	return c.Backend.Mode == StorageModeChangelog
@@ -461,3 +1045,13 @@ func (c *Config) IsChangelogMode() bool {
 func (c *Config) IsStatefulMode() bool {
 	return c.Backend.Mode == StorageModeStateful
 }
+
+// IsHybridMode returns true if the storage mode is hybrid
+func (c *Config) IsHybridMode() bool {
+	return c.Backend.Mode == StorageModeHybrid
+}
+
+// IsHistoryMode returns true if the storage mode is history
+func (c *Config) IsHistoryMode() bool {
+	return c.Backend.Mode == StorageModeHistory
+}
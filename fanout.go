@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/metrics"
+	"github.com/aaguiarz/openfga-sync/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// sink is one backend of the service, with its own adapter and checkpoint
+type sink struct {
+	name    string
+	cfg     *config.Config // Service configuration with backend set to this sink
+	adapter storage.StorageAdapter
+}
+
+// newSinks creates a storage adapter for the backend, or for every sink of a
+// backend list, and reports their background maintenance to metrics. If one
+// fails, the adapters already created are closed.
+func newSinks(cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) ([]sink, error) {
+	var sinks []sink
+	for _, backend := range cfg.Sinks() {
+		sinkCfg := cfg
+		if cfg.FanOut() {
+			sinkCfg = cfg.ForSink(backend)
+		}
+
+		adapter, err := storage.NewStorageAdapter(sinkCfg, logger)
+		if err != nil {
+			closeSinks(sinks, logger)
+			return nil, fmt.Errorf("sink %s: %w", backend.SinkName(), err)
+		}
+		name := backend.SinkName()
+		if maintained, ok := adapter.(storage.MaintainedAdapter); ok {
+			maintained.OnMaintenance(func(report storage.MaintenanceReport) {
+				metrics.RecordStorageMaintenance(name, map[string]int64{
+					"max_age":    report.ExpiredRows,
+					"max_rows":   report.OverflowRows,
+					"compaction": report.CompactedRows,
+				}, report.ReclaimedBytes, report.DatabaseBytes)
+			})
+		}
+		sinks = append(sinks, sink{name: name, cfg: sinkCfg, adapter: adapter})
+	}
+	return sinks, nil
+}
+
+// closeSinks closes the adapter of every sink
+func closeSinks(sinks []sink, logger *logrus.Logger) {
+	for _, s := range sinks {
+		if err := s.adapter.Close(); err != nil {
+			logger.WithError(err).WithField("sink", s.name).Error("Failed to close storage adapter gracefully")
+		} else {
+			logger.WithField("sink", s.name).Debug("Storage adapter closed gracefully")
+		}
+	}
+}
+
+// runSync runs the sync loops of a single backend, or feeds a list of sinks
+// from one fetch loop
+func runSync(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, sinks []sink, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) error {
+	if len(sinks) == 1 {
+		return runStores(ctx, fgaFetcher, sinks[0].adapter, cfg, logger, metrics)
+	}
+	return runFanOut(ctx, fgaFetcher, sinks, cfg, logger, metrics)
+}
+
+// runFanOut feeds several sinks from one fetch loop. Fetched pages are kept in
+// a shared tail that every sink reads from its own checkpoint, so a slow or
+// failing sink lags behind without holding up the others. The fetch loop
+// follows the fastest sink; a sink that falls more than service.sink_buffer
+// pages behind fetches its own pages until it reaches the tail again.
+func runFanOut(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, sinks []sink, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) error {
+	metrics.UpdateSyncedStores(1)
+	defer metrics.UpdateSyncedStores(0)
+
+	// A single object type is fetched with the type filter, and sinks that
+	// track per-type cursors keep it under that type, as in runStore
+	if len(cfg.Service.ObjectTypes) == 1 {
+		objectType := cfg.Service.ObjectTypes[0]
+		typeFetcher, err := newFetcher(cfg, fgaFetcher.StoreID(), objectType, logger)
+		if err != nil {
+			return fmt.Errorf("failed to initialize OpenFGA fetcher for type %s: %w", objectType, err)
+		}
+		defer typeFetcher.Close()
+		fgaFetcher = typeFetcher
+
+		typeSinks := make([]sink, len(sinks))
+		for i, s := range sinks {
+			typeSinks[i] = s
+			if cursorAdapter, ok := s.adapter.(storage.CursorAdapter); ok {
+				typeSinks[i].adapter = cursorAdapter.ForObjectType(objectType)
+				defer typeSinks[i].adapter.Close()
+			}
+		}
+		sinks = typeSinks
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	tail := newChangeTail(cfg.Service.SinkBuffer)
+
+	var wg sync.WaitGroup
+	for _, s := range sinks {
+		wg.Add(1)
+		go func(s sink) {
+			defer wg.Done()
+			runSink(ctx, fgaFetcher, s, tail, cfg, logger, metrics)
+		}(s)
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	logger.WithField("sinks", len(sinks)).Info("Starting fan-out sync")
+
+	timer := time.NewTimer(cfg.Service.PollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			behind, err := fillTail(ctx, fgaFetcher, tail, cfg, logger, metrics)
+			if err != nil {
+				logger.WithError(err).Error("Failed to sync changes")
+				metrics.RecordChangesError()
+				// Continue running despite errors
+			}
+
+			// Only wait for the poll interval once caught up
+			if behind && err == nil {
+				timer.Reset(0)
+			} else {
+				timer.Reset(cfg.Service.PollInterval)
+			}
+		}
+	}
+}
+
+// fillTail runs one fan-out fetch cycle, appending pages to the tail while
+// OpenFGA reports more changes, up to cfg.Service.MaxChanges per cycle. It
+// waits whenever the fastest sink is more than cfg.Service.PipelineDepth pages
+// behind the head. It returns true if the cycle stopped on its budget with
+// changes still pending.
+func fillTail(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, tail *changeTail, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) (bool, error) {
+	cycleChanges := 0
+	pages := 0
+
+	for {
+		head, err := tail.waitForLead(ctx, cfg.Service.PipelineDepth)
+		if err != nil {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			return false, nil
+		}
+
+		result, err := fetchChanges(ctx, fgaFetcher, cfg, head, metrics)
+		if err != nil {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			if ctx.Err() != nil {
+				return false, nil
+			}
+			return false, err
+		}
+
+		// OpenFGA keeps returning a token at the head of the stream, so an
+		// empty page means we've caught up
+		if result.TotalFetched == 0 || result.ContinuationToken == "" {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			logger.Debug("No new changes found")
+			return false, nil
+		}
+
+		tail.append(head, result)
+		cycleChanges += result.TotalFetched
+		pages++
+
+		if !result.HasMore {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			if pages > 1 {
+				logger.WithFields(logrus.Fields{
+					"pages":   pages,
+					"changes": cycleChanges,
+				}).Info("Caught up with change backlog")
+			}
+			return false, nil
+		}
+
+		metrics.UpdateCatchUp(true, cycleChanges)
+
+		if cfg.Service.MaxChanges > 0 && cycleChanges >= cfg.Service.MaxChanges {
+			logger.WithFields(logrus.Fields{
+				"pages":       pages,
+				"changes":     cycleChanges,
+				"max_changes": cfg.Service.MaxChanges,
+			}).Info("Reached per-cycle change limit, continuing backlog in next cycle")
+			return true, nil
+		}
+	}
+}
+
+// runSink writes the tail to one sink from its own checkpoint until ctx is
+// cancelled. A failed write is retried after poll_interval while the other
+// sinks move on. Once the sink's position is no longer in the tail it fetches
+// its own pages from OpenFGA, and rejoins the tail when it reaches a page the
+// fetch loop has read.
+func runSink(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, s sink, tail *changeTail, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) {
+	sinkLogger := logger.WithField("sink", s.name)
+
+	var token string
+	for {
+		var err error
+		token, err = resolveStartToken(ctx, fgaFetcher, s.adapter, s.cfg, logger, metrics)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		sinkLogger.WithError(err).Error("Failed to resolve sink start position")
+		metrics.RecordSinkError(s.name)
+		if !sleepContext(ctx, cfg.Service.PollInterval) {
+			return
+		}
+	}
+
+	sinkLogger.WithFields(logrus.Fields{
+		"backend_type":       s.cfg.Backend.Type,
+		"storage_mode":       s.cfg.Backend.Mode,
+		"continuation_token": token,
+	}).Info("Starting sink from continuation token")
+
+	for {
+		result, pagesBehind, inTail, changed := tail.next(s.name, token)
+		metrics.UpdateSinkPosition(s.name, pagesBehind, !inTail)
+
+		// Caught up with the fetch loop: wait for its next page
+		if inTail && result == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			continue
+		}
+
+		// Outside the tail syncChanges fetches the page itself
+		processed, hasMore, err := syncChanges(ctx, fgaFetcher, s.adapter, s.cfg, &token, result, logger, metrics)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			sinkLogger.WithError(err).Error("Failed to sync changes to sink")
+			metrics.RecordChangesError()
+			metrics.RecordSinkError(s.name)
+		case !inTail && (processed == 0 || !hasMore):
+			// Caught up with OpenFGA ahead of the fetch loop; keep polling
+			// on our own until the tail reaches this position
+		default:
+			continue
+		}
+
+		if !sleepContext(ctx, cfg.Service.PollInterval) {
+			return
+		}
+	}
+}
+
+// sleepContext waits for d, returning false if ctx is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// changeTail is the in-memory end of the change stream shared by the sinks of
+// a fan-out. Each page is keyed by the continuation token it was fetched from,
+// which is the checkpoint of a sink that has written everything before it.
+type changeTail struct {
+	mu        sync.Mutex
+	capacity  int
+	pages     []tailPage        // Oldest first, at most capacity
+	head      string            // Continuation token after the newest page
+	started   bool              // Whether head has been set
+	positions map[string]string // Continuation token of each sink
+	changed   chan struct{}     // Closed and replaced whenever the tail or a position moves
+}
+
+// tailPage is a fetched page and the continuation token it was fetched from
+type tailPage struct {
+	token  string
+	result *fetcher.FetchResult
+}
+
+// newChangeTail creates a tail holding up to capacity pages. Its head is the
+// position of the first sink to report one.
+func newChangeTail(capacity int) *changeTail {
+	return &changeTail{
+		capacity:  capacity,
+		positions: make(map[string]string),
+		changed:   make(chan struct{}),
+	}
+}
+
+// notifyLocked wakes every goroutine waiting for the tail to change
+func (t *changeTail) notifyLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// next records that the sink has written everything before token and returns
+// the page that starts there, with how many pages the sink is behind the head.
+// inTail is false when token is neither the head nor the start of a page in
+// the tail; the sink then has to fetch the page itself. A nil result with
+// inTail true means the sink has caught up. changed is closed when the tail
+// next moves.
+func (t *changeTail) next(name, token string) (result *fetcher.FetchResult, pagesBehind int, inTail bool, changed <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.started {
+		t.head = token
+		t.started = true
+		t.notifyLocked()
+	}
+	if position, ok := t.positions[name]; !ok || position != token {
+		t.positions[name] = token
+		t.notifyLocked()
+	}
+
+	if token == t.head {
+		return nil, 0, true, t.changed
+	}
+	for i, page := range t.pages {
+		if page.token == token {
+			return page.result, len(t.pages) - i, true, t.changed
+		}
+	}
+	return nil, 0, false, t.changed
+}
+
+// waitForLead waits until the tail has a head and the most advanced sink in
+// the tail is at most depth pages behind it, and returns the head
+func (t *changeTail) waitForLead(ctx context.Context, depth int) (string, error) {
+	for {
+		t.mu.Lock()
+		head, ready, changed := t.head, t.started && t.leadLocked() <= depth, t.changed
+		t.mu.Unlock()
+
+		if ready {
+			return head, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// leadLocked returns how many pages the most advanced sink in the tail is
+// behind the head. With no sink in the tail, the whole tail is unread.
+func (t *changeTail) leadLocked() int {
+	lead := len(t.pages)
+	for _, position := range t.positions {
+		if position == t.head {
+			return 0
+		}
+		for i, page := range t.pages {
+			if page.token == position && len(t.pages)-i < lead {
+				lead = len(t.pages) - i
+			}
+		}
+	}
+	return lead
+}
+
+// append adds a page fetched from token, dropping the oldest page once the
+// tail is full. Pages that don't start at the head are ignored.
+func (t *changeTail) append(token string, result *fetcher.FetchResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.started || token != t.head {
+		return
+	}
+
+	t.pages = append(t.pages, tailPage{token: token, result: result})
+	if len(t.pages) > t.capacity {
+		t.pages[0] = tailPage{}
+		t.pages = t.pages[1:]
+	}
+	t.head = result.ContinuationToken
+	t.notifyLocked()
+}
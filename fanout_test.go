@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// testMetrics is shared by the tests since metrics register globally
+var testMetrics = sync.OnceValue(metrics.New)
+
+// page returns a fetched page of one change that continues at next
+func page(next string) *fetcher.FetchResult {
+	return &fetcher.FetchResult{
+		Changes:           []fetcher.ChangeEvent{{ObjectType: "document", ObjectID: next}},
+		ContinuationToken: next,
+		HasMore:           true,
+		TotalFetched:      1,
+	}
+}
+
+func TestChangeTail_DropsPagesBeyondCapacity(t *testing.T) {
+	tail := newChangeTail(2)
+
+	// The first position reported becomes the head
+	if result, behind, inTail, _ := tail.next("slow", "t0"); result != nil || behind != 0 || !inTail {
+		t.Fatalf("Expected the first sink to be caught up at the head, got %v/%d/%v", result, behind, inTail)
+	}
+	tail.append("t0", page("t1"))
+	tail.append("t1", page("t2"))
+	tail.append("t2", page("t3"))
+
+	// Pages not fetched from the head are ignored
+	tail.append("t1", page("other"))
+	if tail.head != "t3" || len(tail.pages) != 2 {
+		t.Fatalf("Expected head t3 with 2 pages, got %s with %d", tail.head, len(tail.pages))
+	}
+
+	// The oldest page was dropped, so the sink fetches it itself
+	if result, _, inTail, _ := tail.next("slow", "t0"); result != nil || inTail {
+		t.Errorf("Expected t0 to have left the tail, got %v/%v", result, inTail)
+	}
+	result, behind, inTail, _ := tail.next("slow", "t1")
+	if !inTail || result == nil || result.ContinuationToken != "t2" || behind != 2 {
+		t.Errorf("Expected the page from t1, 2 pages behind, got %v/%d/%v", result, behind, inTail)
+	}
+}
+
+func TestChangeTail_RejoinsAtPageBoundary(t *testing.T) {
+	tail := newChangeTail(2)
+	tail.next("fast", "t0")
+	tokens := []string{"t0", "t1", "t2", "t3", "t4"}
+	for i, token := range tokens[:4] {
+		tail.append(token, page(tokens[i+1]))
+		tail.next("fast", tokens[i+1])
+	}
+
+	// A sink behind the tail is detached until it reaches the start of a
+	// page, then reads the tail again
+	if _, _, inTail, _ := tail.next("lagging", "t1"); inTail {
+		t.Fatal("Expected t1 to be outside the tail")
+	}
+	result, behind, inTail, _ := tail.next("lagging", "t2")
+	if !inTail || result == nil || behind != 2 {
+		t.Fatalf("Expected to rejoin at t2, 2 pages behind, got %v/%d/%v", result, behind, inTail)
+	}
+	result, behind, inTail, _ = tail.next("lagging", "t4")
+	if !inTail || result != nil || behind != 0 {
+		t.Errorf("Expected to be caught up at the head, got %v/%d/%v", result, behind, inTail)
+	}
+}
+
+func TestChangeTail_LeadFollowsTheMostAdvancedSink(t *testing.T) {
+	tail := newChangeTail(10)
+	tail.next("a", "t0")
+	tokens := []string{"t0", "t1", "t2", "t3", "t4"}
+	for i, token := range tokens[:4] {
+		tail.append(token, page(tokens[i+1]))
+	}
+
+	// a at the start of the tail, b two pages further, c outside it
+	tail.next("b", "t2")
+	tail.next("c", "elsewhere")
+	if lead := tail.leadLocked(); lead != 2 {
+		t.Errorf("Expected a lead of 2 pages, got %d", lead)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if head, err := tail.waitForLead(ctx, 2); err != nil || head != "t4" {
+		t.Errorf("Expected head t4 within a depth of 2, got %q/%v", head, err)
+	}
+	if _, err := tail.waitForLead(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait while the lead exceeds the depth, got %v", err)
+	}
+
+	// Moving the leading sink wakes the fetch loop
+	done := make(chan string, 1)
+	go func() {
+		head, _ := tail.waitForLead(context.Background(), 0)
+		done <- head
+	}()
+	tail.next("b", "t4")
+	select {
+	case head := <-done:
+		if head != "t4" {
+			t.Errorf("Expected head t4, got %q", head)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the lead to shrink")
+	}
+
+	// Without a sink in the tail, every page is unread
+	tail.next("a", "gone")
+	tail.next("b", "gone")
+	if lead := tail.leadLocked(); lead != 4 {
+		t.Errorf("Expected a lead of 4 pages with no sink in the tail, got %d", lead)
+	}
+}
+
+// memorySink is a storage adapter that records changes and its token in
+// memory, or fails every write
+type memorySink struct {
+	mu      sync.Mutex
+	fail    bool
+	changes []fetcher.ChangeEvent
+	token   string
+}
+
+func (m *memorySink) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("sink unavailable")
+	}
+	m.changes = append(m.changes, changes...)
+	return nil
+}
+
+func (m *memorySink) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return m.WriteChanges(ctx, changes)
+}
+
+func (m *memorySink) GetLastContinuationToken(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token, nil
+}
+
+func (m *memorySink) SaveContinuationToken(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = token
+	return nil
+}
+
+func (m *memorySink) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (m *memorySink) Close() error { return nil }
+
+func (m *memorySink) state() (int, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.changes), m.token
+}
+
+func TestRunFanOut_FailingSinkKeepsItsCheckpoint(t *testing.T) {
+	storeID := "01HVMMBCMGZNT3SED4Z17ECXCA"
+	pages := 3
+
+	// ReadChanges serves one change per page, then an empty page at the head
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stores/"+storeID+"/changes" {
+			http.NotFound(w, r)
+			return
+		}
+		current := 0
+		fmt.Sscanf(r.URL.Query().Get("continuation_token"), "tok-%d", &current)
+		w.Header().Set("Content-Type", "application/json")
+		if current >= pages {
+			fmt.Fprintf(w, `{"changes": [], "continuation_token": "tok-%d"}`, current)
+			return
+		}
+		fmt.Fprintf(w, `{
+			"changes": [{"tuple_key": {"user": "user:anne", "relation": "viewer", "object": "document:doc-%d"}, "operation": "TUPLE_OPERATION_WRITE", "timestamp": "2024-06-01T12:00:00Z"}],
+			"continuation_token": "tok-%d"
+		}`, current, current+1)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	cfg := config.DefaultConfig()
+	cfg.Service.PollInterval = 10 * time.Millisecond
+	cfg.Service.BatchSize = 1
+
+	options := fetcher.DefaultFetchOptions()
+	options.RateLimitDelay = 0
+	options.RetryConfig.MaxRetries = 0
+	fgaFetcher, err := fetcher.NewOpenFGAFetcherWithOptions(server.URL, storeID, "", logger, options)
+	if err != nil {
+		t.Fatalf("Failed to create fetcher: %v", err)
+	}
+	defer fgaFetcher.Close()
+
+	healthy := &memorySink{}
+	failing := &memorySink{fail: true}
+	sinks := []sink{
+		{name: "healthy", cfg: cfg, adapter: healthy},
+		{name: "failing", cfg: cfg, adapter: failing},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runFanOut(ctx, fgaFetcher, sinks, cfg, logger, testMetrics())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if written, token := healthy.state(); written == pages && token == fmt.Sprintf("tok-%d", pages) {
+			break
+		}
+		if time.Now().After(deadline) {
+			written, token := healthy.state()
+			t.Fatalf("Timed out waiting for the healthy sink, got %d changes at %q", written, token)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runFanOut() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for runFanOut to stop")
+	}
+
+	if written, token := failing.state(); written != 0 || token != "" {
+		t.Errorf("Expected the failing sink to keep its checkpoint, got %d changes at %q", written, token)
+	}
+}
@@ -0,0 +1,56 @@
+package fetcher
+
+// ChangeFilter selects changes client-side by relation and user type, for
+// criteria the ReadChanges API can't filter on. Empty include lists match
+// everything, and exclude lists take precedence over include lists.
+type ChangeFilter struct {
+	IncludeRelations []string `json:"include_relations,omitempty"`
+	ExcludeRelations []string `json:"exclude_relations,omitempty"`
+	IncludeUserTypes []string `json:"include_user_types,omitempty"`
+	ExcludeUserTypes []string `json:"exclude_user_types,omitempty"`
+}
+
+// IsEmpty reports whether the filter lets every change through
+func (c ChangeFilter) IsEmpty() bool {
+	return len(c.IncludeRelations) == 0 && len(c.ExcludeRelations) == 0 &&
+		len(c.IncludeUserTypes) == 0 && len(c.ExcludeUserTypes) == 0
+}
+
+// Matches reports whether change passes the filter
+func (c ChangeFilter) Matches(change ChangeEvent) bool {
+	return matchesRule(c.IncludeRelations, c.ExcludeRelations, change.Relation) &&
+		matchesRule(c.IncludeUserTypes, c.ExcludeUserTypes, change.UserType)
+}
+
+// Apply returns the changes that pass the filter
+func (c ChangeFilter) Apply(changes []ChangeEvent) []ChangeEvent {
+	if c.IsEmpty() {
+		return changes
+	}
+
+	var filtered []ChangeEvent
+	for _, change := range changes {
+		if c.Matches(change) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
+}
+
+// matchesRule checks value against an include and an exclude list
+func matchesRule(include, exclude []string, value string) bool {
+	for _, excluded := range exclude {
+		if value == excluded {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, included := range include {
+		if value == included {
+			return true
+		}
+	}
+	return false
+}
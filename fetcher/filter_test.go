@@ -0,0 +1,37 @@
+package fetcher
+
+import "testing"
+
+func TestChangeFilter(t *testing.T) {
+	viewer := ChangeEvent{Relation: "viewer", UserType: "user"}
+	editor := ChangeEvent{Relation: "editor", UserType: "user"}
+	groupViewer := ChangeEvent{Relation: "viewer", UserType: "group"}
+
+	tests := []struct {
+		name     string
+		filter   ChangeFilter
+		change   ChangeEvent
+		expected bool
+	}{
+		{"empty filter matches everything", ChangeFilter{}, editor, true},
+		{"included relation", ChangeFilter{IncludeRelations: []string{"viewer"}}, viewer, true},
+		{"relation not included", ChangeFilter{IncludeRelations: []string{"viewer"}}, editor, false},
+		{"excluded relation", ChangeFilter{ExcludeRelations: []string{"editor"}}, editor, false},
+		{"excluded user type", ChangeFilter{ExcludeUserTypes: []string{"group"}}, groupViewer, false},
+		{"included user type", ChangeFilter{IncludeUserTypes: []string{"group"}}, groupViewer, true},
+		{"exclude wins over include", ChangeFilter{IncludeRelations: []string{"viewer"}, ExcludeUserTypes: []string{"group"}}, groupViewer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(tt.change); got != tt.expected {
+				t.Errorf("Matches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	filtered := ChangeFilter{IncludeRelations: []string{"viewer"}}.Apply([]ChangeEvent{viewer, editor, groupViewer})
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 changes after filtering, got %d", len(filtered))
+	}
+}
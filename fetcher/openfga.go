@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	openfga "github.com/openfga/go-sdk"
 	"github.com/openfga/go-sdk/client"
 	"github.com/openfga/go-sdk/credentials"
 	"github.com/sirupsen/logrus"
@@ -44,6 +45,8 @@ type FetchOptions struct {
 	RateLimitDelay   time.Duration `json:"rate_limit_delay"`
 	ConcurrentPages  int           `json:"concurrent_pages"`
 	EnableValidation bool          `json:"enable_validation"`
+	ObjectType       string        `json:"object_type,omitempty"` // Only fetch changes for this type (ReadChanges type filter)
+	Filter           ChangeFilter  `json:"filter"`                // Client-side relation and user type rules
 }
 
 // DefaultFetchOptions provides sensible defaults
@@ -98,7 +101,9 @@ type TupleKey struct {
 	ObjectID   string `json:"object_id"`
 }
 
-// FetchResult represents the result of a fetch operation
+// FetchResult represents the result of a fetch operation. TotalFetched counts
+// the entries OpenFGA returned, including any dropped by client-side filters,
+// so a page with no matching changes still advances the continuation token.
 type FetchResult struct {
 	Changes           []ChangeEvent `json:"changes"`
 	ContinuationToken string        `json:"continuation_token"`
@@ -233,6 +238,18 @@ func (f *OpenFGAFetcher) GetStats() FetcherStats {
 	return f.stats
 }
 
+// StoreID returns the ID of the store changes are fetched from
+func (f *OpenFGAFetcher) StoreID() string {
+	return f.storeID
+}
+
+// ObjectType returns the type changes are filtered on, or "" for all types
+func (f *OpenFGAFetcher) ObjectType() string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.options.ObjectType
+}
+
 // Close cleans up resources like rate limiter
 func (f *OpenFGAFetcher) Close() {
 	if f.rateLimiter != nil {
@@ -273,6 +290,7 @@ func (f *OpenFGAFetcher) FetchChangesWithPaging(ctx context.Context, continuatio
 	ctx, span := tracer.Start(ctx, "openfga.fetch_changes",
 		trace.WithAttributes(
 			attribute.String("openfga.store_id", f.storeID),
+			attribute.String("openfga.object_type", f.options.ObjectType),
 			attribute.String("openfga.continuation_token", continuationToken),
 			attribute.Int64("openfga.page_size", int64(pageSize)),
 		),
@@ -282,17 +300,21 @@ func (f *OpenFGAFetcher) FetchChangesWithPaging(ctx context.Context, continuatio
 	f.logger.WithFields(logrus.Fields{
 		"continuation_token": continuationToken,
 		"page_size":          pageSize,
+		"object_type":        f.options.ObjectType,
 	}).Debug("Fetching changes from OpenFGA with paging")
 
 	options := client.ClientReadChangesOptions{}
-	if continuationToken != "" {
+	body := client.ClientReadChangesRequest{Type: f.options.ObjectType}
+	if startTime, ok := ParseStartTimeToken(continuationToken); ok {
+		body.StartTime = startTime
+	} else if continuationToken != "" {
 		options.ContinuationToken = &continuationToken
 	}
 	if pageSize > 0 {
 		options.PageSize = &pageSize
 	}
 
-	response, err := f.client.ReadChanges(ctx).Options(options).Execute()
+	response, err := f.client.ReadChanges(ctx).Body(body).Options(options).Execute()
 	if err != nil {
 		span.RecordError(err)
 		span.SetAttributes(attribute.String("error.message", err.Error()))
@@ -306,6 +328,9 @@ func (f *OpenFGAFetcher) FetchChangesWithPaging(ctx context.Context, continuatio
 			f.logger.WithError(err).Warn("Failed to parse change event, skipping")
 			continue
 		}
+		if !f.options.Filter.Matches(changeEvent) {
+			continue
+		}
 		changes = append(changes, changeEvent)
 	}
 
@@ -316,16 +341,23 @@ func (f *OpenFGAFetcher) FetchChangesWithPaging(ctx context.Context, continuatio
 		hasMore = nextToken != ""
 	}
 
+	// Without changes since the start time OpenFGA returns no token, so keep
+	// the start time as the position to poll from
+	if nextToken == "" && !body.StartTime.IsZero() {
+		nextToken = continuationToken
+	}
+
 	result := &FetchResult{
 		Changes:           changes,
 		ContinuationToken: nextToken,
 		HasMore:           hasMore,
-		TotalFetched:      len(changes),
+		TotalFetched:      len(response.Changes),
 	}
 
 	// Add span attributes for the result
 	span.SetAttributes(
 		attribute.Int("openfga.changes_count", len(changes)),
+		attribute.Int("openfga.changes_filtered", len(response.Changes)-len(changes)),
 		attribute.String("openfga.next_token", nextToken),
 		attribute.Bool("openfga.has_more", hasMore),
 	)
@@ -339,6 +371,184 @@ func (f *OpenFGAFetcher) FetchChangesWithPaging(ctx context.Context, continuatio
 	return result, nil
 }
 
+// startTimeTokenPrefix marks a position expressed as a change stream start
+// time rather than an OpenFGA continuation token
+const startTimeTokenPrefix = "start_time:"
+
+// StartTimeToken returns a position that reads changes from startTime onwards.
+// It can be stored and passed anywhere a continuation token is accepted.
+func StartTimeToken(startTime time.Time) string {
+	return startTimeTokenPrefix + startTime.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseStartTimeToken extracts the start time from a token created by StartTimeToken
+func ParseStartTimeToken(token string) (time.Time, bool) {
+	if !strings.HasPrefix(token, startTimeTokenPrefix) {
+		return time.Time{}, false
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(token, startTimeTokenPrefix))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return startTime, true
+}
+
+// ReadTuplesWithPaging reads a page of the store's current tuples via the Read API.
+// Tuples are returned as write events so they can be applied like changes.
+func (f *OpenFGAFetcher) ReadTuplesWithPaging(ctx context.Context, continuationToken string, pageSize int32) (*FetchResult, error) {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/fetcher")
+	ctx, span := tracer.Start(ctx, "openfga.read_tuples",
+		trace.WithAttributes(
+			attribute.String("openfga.store_id", f.storeID),
+			attribute.String("openfga.continuation_token", continuationToken),
+			attribute.Int64("openfga.page_size", int64(pageSize)),
+		),
+	)
+	defer span.End()
+
+	consistency := openfga.CONSISTENCYPREFERENCE_HIGHER_CONSISTENCY
+	options := client.ClientReadOptions{
+		Consistency: &consistency,
+	}
+	if continuationToken != "" {
+		options.ContinuationToken = &continuationToken
+	}
+	if pageSize > 0 {
+		options.PageSize = &pageSize
+	}
+
+	response, err := f.client.Read(ctx).Body(client.ClientReadRequest{}).Options(options).Execute()
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("error.message", err.Error()))
+		return nil, fmt.Errorf("failed to read tuples: %w", err)
+	}
+
+	// Read can't filter by type alone, so the object type is matched here too
+	var tuples []ChangeEvent
+	for _, tuple := range response.Tuples {
+		changeEvent, err := f.parseChangeEvent(openfga.TupleChange{
+			TupleKey:  tuple.Key,
+			Operation: openfga.TUPLEOPERATION_WRITE,
+			Timestamp: tuple.Timestamp,
+		})
+		if err != nil {
+			f.logger.WithError(err).Warn("Failed to parse tuple, skipping")
+			continue
+		}
+		if f.options.ObjectType != "" && changeEvent.ObjectType != f.options.ObjectType {
+			continue
+		}
+		if !f.options.Filter.Matches(changeEvent) {
+			continue
+		}
+		tuples = append(tuples, changeEvent)
+	}
+
+	result := &FetchResult{
+		Changes:           tuples,
+		ContinuationToken: response.ContinuationToken,
+		HasMore:           response.ContinuationToken != "",
+		TotalFetched:      len(response.Tuples),
+	}
+
+	span.SetAttributes(
+		attribute.Int("openfga.tuples_count", len(tuples)),
+		attribute.Bool("openfga.has_more", result.HasMore),
+	)
+
+	f.logger.WithFields(logrus.Fields{
+		"tuples_count": len(tuples),
+		"has_more":     result.HasMore,
+	}).Debug("Successfully read tuples from OpenFGA")
+
+	return result, nil
+}
+
+// ReadTuplesWithRetry reads a page of current tuples with rate limiting and retry logic
+func (f *OpenFGAFetcher) ReadTuplesWithRetry(ctx context.Context, continuationToken string, pageSize int32) (*FetchResult, error) {
+	startTime := time.Now()
+	var result *FetchResult
+
+	// Apply rate limiting
+	if f.rateLimiter != nil {
+		select {
+		case <-f.rateLimiter.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	err := f.retryWithBackoff(ctx, func() error {
+		var err error
+		result, err = f.ReadTuplesWithPaging(ctx, continuationToken, pageSize)
+		return err
+	})
+
+	latency := time.Since(startTime)
+	tuplesCount := 0
+	if result != nil {
+		tuplesCount = len(result.Changes)
+	}
+	f.updateStats(err == nil, tuplesCount, latency)
+
+	if err != nil {
+		f.logger.WithFields(logrus.Fields{
+			"continuation_token": continuationToken,
+			"page_size":          pageSize,
+			"latency_ms":         latency.Milliseconds(),
+			"error":              err.Error(),
+		}).Error("Failed to read tuples after retries")
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListStores returns the IDs of all stores visible to the fetcher's credentials,
+// following ListStores pagination to the end
+func (f *OpenFGAFetcher) ListStores(ctx context.Context) ([]string, error) {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/fetcher")
+	ctx, span := tracer.Start(ctx, "openfga.list_stores")
+	defer span.End()
+
+	var storeIDs []string
+	var continuationToken string
+	for {
+		options := client.ClientListStoresOptions{}
+		if continuationToken != "" {
+			options.ContinuationToken = &continuationToken
+		}
+
+		var response *client.ClientListStoresResponse
+		err := f.retryWithBackoff(ctx, func() error {
+			var err error
+			response, err = f.client.ListStores(ctx).Options(options).Execute()
+			return err
+		})
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to list stores: %w", err)
+		}
+
+		for _, store := range response.Stores {
+			storeIDs = append(storeIDs, store.Id)
+		}
+
+		if response.ContinuationToken == "" || len(response.Stores) == 0 {
+			break
+		}
+		continuationToken = response.ContinuationToken
+	}
+
+	span.SetAttributes(attribute.Int("openfga.stores_count", len(storeIDs)))
+	f.logger.WithField("stores_count", len(storeIDs)).Debug("Listed OpenFGA stores")
+
+	return storeIDs, nil
+}
+
 // FetchAllChanges fetches all available changes by automatically handling pagination
 func (f *OpenFGAFetcher) FetchAllChanges(ctx context.Context, startToken string, maxChanges int) (*FetchResult, error) {
 	f.logger.WithFields(logrus.Fields{
@@ -374,8 +584,9 @@ func (f *OpenFGAFetcher) FetchAllChanges(ctx context.Context, startToken string,
 		allChanges = append(allChanges, result.Changes...)
 		totalFetched += len(result.Changes)
 
-		// Check if we have more changes
-		if !result.HasMore || result.ContinuationToken == "" {
+		// Check if we have more changes. At the end of the stream OpenFGA
+		// still returns a token, but with an empty page.
+		if !result.HasMore || result.ContinuationToken == "" || result.TotalFetched == 0 {
 			f.logger.WithField("total_fetched", totalFetched).Info("No more changes available")
 			break
 		}
@@ -583,37 +794,16 @@ func parseObjectTypeAndID(object string) (string, string) {
 	return "object", object
 }
 
-// GetChangesSince fetches all changes since a given timestamp
+// GetChangesSince fetches all changes made at or after since. It reads the
+// change stream from that point using the ReadChanges start_time parameter, so
+// earlier history is never fetched.
 func (f *OpenFGAFetcher) GetChangesSince(ctx context.Context, since time.Time, maxChanges int) (*FetchResult, error) {
 	f.logger.WithFields(logrus.Fields{
 		"since":       since,
 		"max_changes": maxChanges,
 	}).Info("Fetching changes since timestamp")
 
-	// Start from the beginning and filter by timestamp
-	result, err := f.FetchAllChanges(ctx, "", maxChanges)
-	if err != nil {
-		return nil, err
-	}
-
-	// Filter changes by timestamp
-	var filteredChanges []ChangeEvent
-	for _, change := range result.Changes {
-		if change.Timestamp.After(since) || change.Timestamp.Equal(since) {
-			filteredChanges = append(filteredChanges, change)
-		}
-	}
-
-	result.Changes = filteredChanges
-	result.TotalFetched = len(filteredChanges)
-
-	f.logger.WithFields(logrus.Fields{
-		"total_changes":    len(result.Changes),
-		"filtered_changes": len(filteredChanges),
-		"since":            since,
-	}).Info("Filtered changes by timestamp")
-
-	return result, nil
+	return f.FetchAllChanges(ctx, StartTimeToken(since), maxChanges)
 }
 
 // ValidateChangeEvent validates that a change event has all required fields
@@ -673,7 +863,12 @@ func (f *OpenFGAFetcher) updateStats(success bool, changesCount int, latency tim
 
 // retryWithBackoff executes a function with exponential backoff retry logic
 func (f *OpenFGAFetcher) retryWithBackoff(ctx context.Context, operation func() error) error {
-	config := f.options.RetryConfig
+	return RetryWithBackoff(ctx, f.options.RetryConfig, f.logger, operation)
+}
+
+// RetryWithBackoff executes a function with exponential backoff retry logic,
+// retrying up to config.MaxRetries times after the first attempt
+func RetryWithBackoff(ctx context.Context, config RetryConfig, logger *logrus.Logger, operation func() error) error {
 	delay := config.InitialDelay
 
 	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
@@ -697,7 +892,7 @@ func (f *OpenFGAFetcher) retryWithBackoff(ctx context.Context, operation func()
 			return nil
 		}
 
-		f.logger.WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"attempt":     attempt + 1,
 			"max_retries": config.MaxRetries,
 			"delay":       delay,
@@ -822,8 +1017,9 @@ func (f *OpenFGAFetcher) FetchAllChangesWithOptions(ctx context.Context, startTo
 		allChanges = append(allChanges, result.Changes...)
 		totalFetched += len(result.Changes)
 
-		// Check if we have more changes
-		if !result.HasMore || result.ContinuationToken == "" {
+		// Check if we have more changes. At the end of the stream OpenFGA
+		// still returns a token, but with an empty page.
+		if !result.HasMore || result.ContinuationToken == "" || result.TotalFetched == 0 {
 			f.logger.WithField("total_fetched", totalFetched).Info("No more changes available")
 			break
 		}
@@ -846,35 +1042,13 @@ func (f *OpenFGAFetcher) FetchAllChangesWithOptions(ctx context.Context, startTo
 	}, nil
 }
 
-// GetChangesSinceWithOptions fetches changes since a timestamp with advanced options
+// GetChangesSinceWithOptions fetches changes made at or after since with
+// advanced options, starting the change stream at that point
 func (f *OpenFGAFetcher) GetChangesSinceWithOptions(ctx context.Context, since time.Time, options FetchOptions) (*FetchResult, error) {
 	f.logger.WithFields(logrus.Fields{
 		"since":       since,
 		"max_changes": options.MaxChanges,
 	}).Info("Fetching changes since timestamp with options")
 
-	// Start from the beginning and filter by timestamp
-	result, err := f.FetchAllChangesWithOptions(ctx, "", options)
-	if err != nil {
-		return nil, err
-	}
-
-	// Filter changes by timestamp
-	var filteredChanges []ChangeEvent
-	for _, change := range result.Changes {
-		if change.Timestamp.After(since) || change.Timestamp.Equal(since) {
-			filteredChanges = append(filteredChanges, change)
-		}
-	}
-
-	result.Changes = filteredChanges
-	result.TotalFetched = len(filteredChanges)
-
-	f.logger.WithFields(logrus.Fields{
-		"total_changes":    len(result.Changes),
-		"filtered_changes": len(filteredChanges),
-		"since":            since,
-	}).Info("Filtered changes by timestamp")
-
-	return result, nil
+	return f.FetchAllChangesWithOptions(ctx, StartTimeToken(since), options)
 }
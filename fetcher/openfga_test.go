@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -340,6 +342,229 @@ func TestFetchOptions(t *testing.T) {
 	}
 }
 
+func TestStartTimeToken(t *testing.T) {
+	startTime := time.Date(2024, 6, 1, 12, 30, 0, 123456789, time.UTC)
+
+	token := StartTimeToken(startTime)
+	parsed, ok := ParseStartTimeToken(token)
+	if !ok {
+		t.Fatalf("Expected %q to parse as a start time token", token)
+	}
+	if !parsed.Equal(startTime) {
+		t.Errorf("Expected start time %v, got %v", startTime, parsed)
+	}
+
+	for _, token := range []string{"", "01HXYZ-continuation-token", "start_time:not-a-time"} {
+		if _, ok := ParseStartTimeToken(token); ok {
+			t.Errorf("Expected %q not to parse as a start time token", token)
+		}
+	}
+}
+
+func TestReadTuplesWithPaging(t *testing.T) {
+	storeID := "01HVMMBCMGZNT3SED4Z17ECXCA"
+	var requestBody map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stores/"+storeID+"/read" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"tuples": [
+				{"key": {"user": "user:anne", "relation": "viewer", "object": "document:roadmap"}, "timestamp": "2024-06-01T12:00:00Z"}
+			],
+			"continuation_token": "next-page"
+		}`)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	f, err := NewOpenFGAFetcher(server.URL, storeID, "", logger)
+	if err != nil {
+		t.Fatalf("Failed to create fetcher: %v", err)
+	}
+	defer f.Close()
+
+	result, err := f.ReadTuplesWithPaging(context.Background(), "", 50)
+	if err != nil {
+		t.Fatalf("Failed to read tuples: %v", err)
+	}
+
+	if requestBody["consistency"] != "HIGHER_CONSISTENCY" {
+		t.Errorf("Expected HIGHER_CONSISTENCY read, got %v", requestBody["consistency"])
+	}
+	if len(result.Changes) != 1 {
+		t.Fatalf("Expected 1 tuple, got %d", len(result.Changes))
+	}
+	if !result.HasMore || result.ContinuationToken != "next-page" {
+		t.Errorf("Expected more pages with token next-page, got %v/%q", result.HasMore, result.ContinuationToken)
+	}
+
+	tuple := result.Changes[0]
+	if tuple.Operation != "TUPLE_OPERATION_WRITE" {
+		t.Errorf("Expected write operation, got %s", tuple.Operation)
+	}
+	if tuple.ObjectType != "document" || tuple.ObjectID != "roadmap" || tuple.UserID != "anne" {
+		t.Errorf("Unexpected tuple parsed: %+v", tuple)
+	}
+}
+
+func TestFetchChangesWithTypeFilter(t *testing.T) {
+	storeID := "01HVMMBCMGZNT3SED4Z17ECXCA"
+	var requestedType string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stores/"+storeID+"/changes" {
+			http.NotFound(w, r)
+			return
+		}
+		requestedType = r.URL.Query().Get("type")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"changes": [
+				{"tuple_key": {"user": "user:anne", "relation": "viewer", "object": "document:roadmap"}, "operation": "TUPLE_OPERATION_WRITE", "timestamp": "2024-06-01T12:00:00Z"},
+				{"tuple_key": {"user": "group:eng#member", "relation": "viewer", "object": "document:roadmap"}, "operation": "TUPLE_OPERATION_WRITE", "timestamp": "2024-06-01T12:00:01Z"}
+			],
+			"continuation_token": "next-page"
+		}`)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	options := DefaultFetchOptions()
+	options.RateLimitDelay = 0
+	options.ObjectType = "document"
+	options.Filter = ChangeFilter{ExcludeUserTypes: []string{"group"}}
+	f, err := NewOpenFGAFetcherWithOptions(server.URL, storeID, "", logger, options)
+	if err != nil {
+		t.Fatalf("Failed to create fetcher: %v", err)
+	}
+	defer f.Close()
+
+	result, err := f.FetchChangesWithPaging(context.Background(), "", 50)
+	if err != nil {
+		t.Fatalf("Failed to fetch changes: %v", err)
+	}
+
+	if requestedType != "document" {
+		t.Errorf("Expected ReadChanges type filter 'document', got %q", requestedType)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].UserID != "anne" {
+		t.Errorf("Expected only anne's change after filtering, got %+v", result.Changes)
+	}
+	if result.TotalFetched != 2 {
+		t.Errorf("Expected TotalFetched to count filtered changes, got %d", result.TotalFetched)
+	}
+}
+
+func TestGetChangesSince(t *testing.T) {
+	storeID := "01HVMMBCMGZNT3SED4Z17ECXCA"
+	since := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var startTimes, tokens []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stores/"+storeID+"/changes" {
+			http.NotFound(w, r)
+			return
+		}
+		startTimes = append(startTimes, r.URL.Query().Get("start_time"))
+		tokens = append(tokens, r.URL.Query().Get("continuation_token"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("continuation_token") == "" {
+			fmt.Fprint(w, `{
+				"changes": [
+					{"tuple_key": {"user": "user:anne", "relation": "viewer", "object": "document:roadmap"}, "operation": "TUPLE_OPERATION_WRITE", "timestamp": "2024-06-01T12:00:01Z"}
+				],
+				"continuation_token": "page-2"
+			}`)
+			return
+		}
+		// OpenFGA keeps returning a token at the end of the stream
+		fmt.Fprint(w, `{"changes": [], "continuation_token": "page-2"}`)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	options := DefaultFetchOptions()
+	options.RateLimitDelay = 0
+	f, err := NewOpenFGAFetcherWithOptions(server.URL, storeID, "", logger, options)
+	if err != nil {
+		t.Fatalf("Failed to create fetcher: %v", err)
+	}
+	defer f.Close()
+
+	result, err := f.GetChangesSince(context.Background(), since, 0)
+	if err != nil {
+		t.Fatalf("Failed to get changes since %v: %v", since, err)
+	}
+
+	if len(startTimes) != 2 {
+		t.Fatalf("Expected 2 ReadChanges requests, got %d", len(startTimes))
+	}
+	requested, err := time.Parse(time.RFC3339Nano, startTimes[0])
+	if err != nil || !requested.Equal(since) {
+		t.Errorf("Expected first request to start at %v, got %q", since, startTimes[0])
+	}
+	if tokens[1] != "page-2" {
+		t.Errorf("Expected second request to continue from page-2, got %q", tokens[1])
+	}
+	if len(result.Changes) != 1 || result.Changes[0].UserID != "anne" {
+		t.Errorf("Expected anne's change, got %+v", result.Changes)
+	}
+	if result.ContinuationToken != "page-2" {
+		t.Errorf("Expected continuation token page-2, got %q", result.ContinuationToken)
+	}
+}
+
+func TestListStores(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stores" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("continuation_token") == "" {
+			fmt.Fprint(w, `{"stores": [{"id": "01HVMMBCMGZNT3SED4Z17ECXCA", "name": "one"}], "continuation_token": "page-2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"stores": [{"id": "01HVMMBCMGZNT3SED4Z17ECXCB", "name": "two"}], "continuation_token": ""}`)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// Discovery doesn't need a store
+	f, err := NewOpenFGAFetcher(server.URL, "", "", logger)
+	if err != nil {
+		t.Fatalf("Failed to create fetcher: %v", err)
+	}
+	defer f.Close()
+
+	storeIDs, err := f.ListStores(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list stores: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 paged requests, got %d", requests)
+	}
+	if len(storeIDs) != 2 || storeIDs[0] != "01HVMMBCMGZNT3SED4Z17ECXCA" || storeIDs[1] != "01HVMMBCMGZNT3SED4Z17ECXCB" {
+		t.Errorf("Unexpected store IDs: %v", storeIDs)
+	}
+}
+
 func TestNewOpenFGAFetcherWithOptions(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
@@ -0,0 +1,143 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// KubernetesElector elects a leader using a coordination.k8s.io Lease
+type KubernetesElector struct {
+	client   kubernetes.Interface
+	cfg      config.LeadershipConfig
+	identity string
+	logger   *logrus.Logger
+}
+
+// NewKubernetesElector creates an elector backed by a Lease in cfg.Namespace
+func NewKubernetesElector(client kubernetes.Interface, cfg config.LeadershipConfig, identity string, logger *logrus.Logger) *KubernetesElector {
+	return &KubernetesElector{
+		client:   client,
+		cfg:      cfg,
+		identity: identity,
+		logger:   logger,
+	}
+}
+
+// newKubernetesClient creates a clientset from the in-cluster service account
+func newKubernetesClient() (kubernetes.Interface, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster kubernetes config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	return client, nil
+}
+
+// Identity returns the name this replica campaigns under
+func (e *KubernetesElector) Identity() string {
+	return e.identity
+}
+
+// Run campaigns for the Lease until ctx is cancelled, re-entering the
+// election whenever leadership is lost
+func (e *KubernetesElector) Run(ctx context.Context, callbacks Callbacks) error {
+	// client-go runs OnStartedLeading in its own goroutine and doesn't wait
+	// for it, and calls OnStoppedLeading even if the Lease was never
+	// acquired. The lock reports acquisition on the campaigning goroutine
+	// before OnStartedLeading is started, so the callback is tracked from
+	// there to keep the ordering promised by Callbacks.
+	var (
+		leading    bool
+		leaderDone sync.WaitGroup
+	)
+
+	lock := &acquisitionLock{
+		Interface: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      e.cfg.LockName,
+				Namespace: e.cfg.Namespace,
+			},
+			Client: e.client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: e.identity,
+			},
+		},
+		acquired: func() {
+			leading = true
+			leaderDone.Add(1)
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   e.cfg.LeaseDuration,
+		RenewDeadline:   e.cfg.RenewDeadline,
+		RetryPeriod:     e.cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.cfg.LockName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer leaderDone.Done()
+				e.logger.WithField("identity", e.identity).Info("Acquired leadership lease")
+				if callbacks.OnStartedLeading != nil {
+					callbacks.OnStartedLeading(leaderCtx)
+				}
+			},
+			OnStoppedLeading: func() {
+				if !leading {
+					return
+				}
+				leading = false
+				leaderDone.Wait()
+				e.logger.WithField("identity", e.identity).Info("Released leadership lease")
+				if callbacks.OnStoppedLeading != nil {
+					callbacks.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				e.logger.WithField("leader", identity).Debug("Observed leader")
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	// elector.Run returns each time leadership is lost; campaign again
+	// until shutdown so a transient API outage doesn't end the election
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+
+	return nil
+}
+
+// acquisitionLock calls acquired when client-go takes the Lease. client-go
+// records the "became leader" event on the campaigning goroutine right after
+// acquiring, before it starts OnStartedLeading.
+type acquisitionLock struct {
+	resourcelock.Interface
+	acquired func()
+}
+
+// RecordEvent reports acquisition and passes the event on
+func (l *acquisitionLock) RecordEvent(event string) {
+	if event == "became leader" {
+		l.acquired()
+	}
+	l.Interface.RecordEvent(event)
+}
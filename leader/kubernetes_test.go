@@ -0,0 +1,133 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesElectorSingleLeader(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cfg := config.LeadershipConfig{
+		Enabled:       true,
+		Backend:       "kubernetes",
+		Namespace:     "openfga-system",
+		LockName:      "openfga-sync-leader",
+		LeaseDuration: 1 * time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+
+	leaders := make(chan string, 2)
+	newCallbacks := func(identity string) Callbacks {
+		return Callbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				leaders <- identity
+				<-ctx.Done()
+			},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := NewKubernetesElector(client, cfg, "replica-1", newTestLogger())
+	second := NewKubernetesElector(client, cfg, "replica-2", newTestLogger())
+	go first.Run(ctx, newCallbacks("replica-1"))
+	go second.Run(ctx, newCallbacks("replica-2"))
+
+	var leader string
+	select {
+	case leader = <-leaders:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a leader to be elected")
+	}
+
+	// The other replica must not lead while the lease is held
+	select {
+	case other := <-leaders:
+		t.Fatalf("Both %s and %s became leader", leader, other)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	lease, err := client.CoordinationV1().Leases(cfg.Namespace).Get(context.Background(), cfg.LockName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != leader {
+		t.Errorf("Expected lease holder %s, got %v", leader, lease.Spec.HolderIdentity)
+	}
+}
+
+func TestKubernetesElectorCallbackOrder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cfg := config.LeadershipConfig{
+		Enabled:       true,
+		Backend:       "kubernetes",
+		Namespace:     "openfga-system",
+		LockName:      "openfga-sync-leader",
+		LeaseDuration: 1 * time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	var returned atomic.Bool
+	leaderStopped := make(chan bool, 1)
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		NewKubernetesElector(client, cfg, "replica-1", newTestLogger()).Run(ctx, Callbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				close(started)
+				<-ctx.Done()
+				// Still shutting down after leadership is lost
+				time.Sleep(100 * time.Millisecond)
+				returned.Store(true)
+			},
+			OnStoppedLeading: func() {
+				leaderStopped <- returned.Load()
+			},
+		})
+	}()
+	waitFor(t, started, "replica-1 to lead")
+
+	// A replica that never acquires the Lease never stops leading
+	var followerStopped atomic.Bool
+	followerDone := make(chan struct{})
+	go func() {
+		defer close(followerDone)
+		NewKubernetesElector(client, cfg, "replica-2", newTestLogger()).Run(ctx, Callbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				t.Error("replica-2 became leader while replica-1 held the lease")
+			},
+			OnStoppedLeading: func() {
+				followerStopped.Store(true)
+			},
+		})
+	}()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+
+	waitFor(t, leaderDone, "replica-1 to stop")
+	waitFor(t, followerDone, "replica-2 to stop")
+	select {
+	case returned := <-leaderStopped:
+		if !returned {
+			t.Error("OnStoppedLeading ran before OnStartedLeading returned")
+		}
+	default:
+		t.Error("OnStoppedLeading was not called after leading")
+	}
+	if followerStopped.Load() {
+		t.Error("OnStoppedLeading was called on a replica that never led")
+	}
+}
@@ -0,0 +1,192 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Callbacks are invoked on leadership transitions
+type Callbacks struct {
+	// OnStartedLeading is called when leadership is acquired. Its context is
+	// cancelled as soon as leadership is lost or the elector stops.
+	OnStartedLeading func(ctx context.Context)
+
+	// OnStoppedLeading is called after OnStartedLeading has returned once
+	// leadership has been lost or released
+	OnStoppedLeading func()
+}
+
+// Elector participates in a leader election
+type Elector interface {
+	// Run takes part in the election until ctx is cancelled
+	Run(ctx context.Context, callbacks Callbacks) error
+
+	// Identity returns the name this replica campaigns under
+	Identity() string
+}
+
+// Lock is a distributed mutual-exclusion primitive used by lock-based electors
+type Lock interface {
+	// TryAcquire attempts to take the lock without blocking
+	TryAcquire(ctx context.Context) (bool, error)
+
+	// Renew confirms the lock is still held, returning an error if it was lost
+	Renew(ctx context.Context) error
+
+	// Release gives up the lock if held
+	Release(ctx context.Context) error
+
+	// Close releases any resources held by the lock
+	Close() error
+}
+
+// NewElector creates an elector for the configured leadership backend
+func NewElector(cfg *config.Config, logger *logrus.Logger) (Elector, error) {
+	identity := cfg.Leadership.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine leader identity: %w", err)
+		}
+		identity = hostname
+	}
+
+	switch cfg.Leadership.Backend {
+	case "kubernetes":
+		client, err := newKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewKubernetesElector(client, cfg.Leadership, identity, logger), nil
+	case "postgres":
+		dsn := cfg.Leadership.DSN
+		if dsn == "" && cfg.Backend.Type == "postgres" {
+			dsn = cfg.Backend.DSN
+		}
+		lock, err := NewPostgresLock(dsn, cfg.Leadership.Namespace+"/"+cfg.Leadership.LockName)
+		if err != nil {
+			return nil, err
+		}
+		return NewLockElector(lock, identity, cfg.Leadership.RetryPeriod, logger), nil
+	case "file":
+		path := cfg.Leadership.DSN
+		if path == "" && cfg.Backend.Type == "sqlite" {
+			path = sqliteLockPath(cfg.Backend.DSN)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("file leadership backend requires a lock file path")
+		}
+		return NewLockElector(NewFileLock(path, identity), identity, cfg.Leadership.RetryPeriod, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported leadership backend: %s", cfg.Leadership.Backend)
+	}
+}
+
+// sqliteLockPath derives a lock file next to a SQLite database
+func sqliteLockPath(dsn string) string {
+	path := strings.TrimPrefix(dsn, "file:")
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path + ".lock"
+}
+
+// LockElector elects a leader by polling a Lock every retry period
+type LockElector struct {
+	lock        Lock
+	identity    string
+	retryPeriod time.Duration
+	logger      *logrus.Logger
+}
+
+// NewLockElector creates an elector backed by a Lock
+func NewLockElector(lock Lock, identity string, retryPeriod time.Duration, logger *logrus.Logger) *LockElector {
+	return &LockElector{
+		lock:        lock,
+		identity:    identity,
+		retryPeriod: retryPeriod,
+		logger:      logger,
+	}
+}
+
+// Identity returns the name this replica campaigns under
+func (e *LockElector) Identity() string {
+	return e.identity
+}
+
+// Run polls the lock until ctx is cancelled, starting and stopping the
+// leader callbacks as the lock is acquired and lost
+func (e *LockElector) Run(ctx context.Context, callbacks Callbacks) error {
+	defer e.lock.Close()
+
+	var (
+		cancelLeader context.CancelFunc
+		leaderDone   sync.WaitGroup
+	)
+
+	startLeading := func() {
+		var leaderCtx context.Context
+		leaderCtx, cancelLeader = context.WithCancel(ctx)
+		leaderDone.Add(1)
+		go func() {
+			defer leaderDone.Done()
+			if callbacks.OnStartedLeading != nil {
+				callbacks.OnStartedLeading(leaderCtx)
+			}
+		}()
+	}
+
+	stopLeading := func() {
+		cancelLeader()
+		cancelLeader = nil
+		leaderDone.Wait()
+		if callbacks.OnStoppedLeading != nil {
+			callbacks.OnStoppedLeading()
+		}
+	}
+
+	ticker := time.NewTicker(e.retryPeriod)
+	defer ticker.Stop()
+
+	for {
+		if cancelLeader != nil {
+			if err := e.lock.Renew(ctx); err != nil {
+				e.logger.WithError(err).WithField("identity", e.identity).Warn("Lost leadership lock")
+				stopLeading()
+			}
+		} else {
+			acquired, err := e.lock.TryAcquire(ctx)
+			if err != nil {
+				e.logger.WithError(err).WithField("identity", e.identity).Warn("Failed to acquire leadership lock")
+			}
+			if acquired {
+				e.logger.WithField("identity", e.identity).Info("Acquired leadership lock")
+				startLeading()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if cancelLeader != nil {
+				stopLeading()
+			}
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := e.lock.Release(releaseCtx); err != nil {
+				e.logger.WithError(err).Warn("Failed to release leadership lock")
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeLock is an in-memory Lock whose availability is controlled by the test
+type fakeLock struct {
+	mu        sync.Mutex
+	available bool
+	held      bool
+	lost      bool
+	released  int
+	closed    bool
+}
+
+func (f *fakeLock) TryAcquire(ctx context.Context) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.available {
+		return false, nil
+	}
+	f.held = true
+	f.lost = false
+	return true, nil
+}
+
+func (f *fakeLock) Renew(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lost {
+		f.held = false
+		return errors.New("lock lost")
+	}
+	return nil
+}
+
+func (f *fakeLock) Release(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.held {
+		f.released++
+	}
+	f.held = false
+	return nil
+}
+
+func (f *fakeLock) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeLock) set(fn func(*fakeLock)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	return logger
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+}
+
+func TestLockElectorTransitions(t *testing.T) {
+	lock := &fakeLock{}
+	elector := NewLockElector(lock, "replica-1", 10*time.Millisecond, newTestLogger())
+
+	started := make(chan struct{}, 2)
+	stopped := make(chan struct{}, 2)
+	callbacks := Callbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+		},
+		OnStoppedLeading: func() {
+			stopped <- struct{}{}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- elector.Run(ctx, callbacks)
+	}()
+
+	// Nobody leads while the lock is unavailable
+	select {
+	case <-started:
+		t.Fatal("Started leading while lock was unavailable")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lock.set(func(l *fakeLock) { l.available = true })
+	waitFor(t, started, "leadership to be acquired")
+
+	// Losing the lock stops the leader
+	lock.set(func(l *fakeLock) {
+		l.available = false
+		l.lost = true
+	})
+	waitFor(t, stopped, "leadership to be lost")
+
+	// Leadership is regained once the lock becomes available again
+	lock.set(func(l *fakeLock) { l.available = true })
+	waitFor(t, started, "leadership to be reacquired")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	waitFor(t, stopped, "leadership to stop on shutdown")
+
+	lock.set(func(l *fakeLock) {
+		if l.released != 1 {
+			t.Errorf("Expected lock to be released once on shutdown, got %d", l.released)
+		}
+		if !l.closed {
+			t.Error("Expected lock to be closed")
+		}
+	})
+}
+
+func TestFileLockMutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sync.lock")
+
+	first := NewFileLock(path, "replica-1")
+	second := NewFileLock(path, "replica-2")
+	defer first.Close()
+	defer second.Close()
+
+	acquired, err := first.TryAcquire(ctx)
+	if err != nil || !acquired {
+		t.Fatalf("Expected first lock to be acquired, got %v (%v)", acquired, err)
+	}
+
+	acquired, err = second.TryAcquire(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring held lock: %v", err)
+	}
+	if acquired {
+		t.Fatal("Expected second lock to be refused while first holds it")
+	}
+
+	if err := first.Renew(ctx); err != nil {
+		t.Errorf("Expected renew to succeed, got %v", err)
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+
+	acquired, err = second.TryAcquire(ctx)
+	if err != nil || !acquired {
+		t.Fatalf("Expected second lock to be acquired after release, got %v (%v)", acquired, err)
+	}
+}
+
+func TestSqliteLockPath(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		expected string
+	}{
+		{"/data/sync.db", "/data/sync.db.lock"},
+		{"file:/data/sync.db?cache=shared", "/data/sync.db.lock"},
+		{":memory:", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sqliteLockPath(tt.dsn); got != tt.expected {
+			t.Errorf("sqliteLockPath(%q) = %q, expected %q", tt.dsn, got, tt.expected)
+		}
+	}
+}
+
+func TestAdvisoryLockKeyIsStable(t *testing.T) {
+	if advisoryLockKey("default/openfga-sync-leader") != advisoryLockKey("default/openfga-sync-leader") {
+		t.Error("Expected advisory lock key to be deterministic")
+	}
+	if advisoryLockKey("a/lock") == advisoryLockKey("b/lock") {
+		t.Error("Expected different lock names to map to different keys")
+	}
+}
+
+func TestNewElectorFileBackend(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Backend.Type = "sqlite"
+	cfg.Backend.DSN = filepath.Join(t.TempDir(), "sync.db")
+	cfg.Leadership.Enabled = true
+	cfg.Leadership.Backend = "file"
+	cfg.Leadership.Identity = "replica-1"
+
+	elector, err := NewElector(cfg, newTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create elector: %v", err)
+	}
+	if elector.Identity() != "replica-1" {
+		t.Errorf("Expected identity replica-1, got %s", elector.Identity())
+	}
+	if _, ok := elector.(*LockElector); !ok {
+		t.Errorf("Expected *LockElector, got %T", elector)
+	}
+
+	cfg.Leadership.Backend = "zookeeper"
+	if _, err := NewElector(cfg, newTestLogger()); err == nil {
+		t.Error("Expected error for unsupported backend")
+	}
+}
@@ -0,0 +1,117 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresLock implements Lock with a session-level Postgres advisory lock.
+// The lock is held on a dedicated connection, so it is released automatically
+// if the process dies or the connection drops.
+type PostgresLock struct {
+	db   *sql.DB
+	key  int64
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// NewPostgresLock creates an advisory lock keyed by name
+func NewPostgresLock(dsn, name string) (*PostgresLock, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("postgres leadership backend requires a DSN")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leadership database connection: %w", err)
+	}
+
+	return &PostgresLock{
+		db:  db,
+		key: advisoryLockKey(name),
+	}, nil
+}
+
+// advisoryLockKey maps a lock name onto the bigint advisory lock keyspace
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// TryAcquire attempts to take the advisory lock without blocking
+func (l *PostgresLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		conn, err := l.db.Conn(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get leadership connection: %w", err)
+		}
+		l.conn = conn
+	}
+
+	var acquired bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// Renew verifies that the session holding the advisory lock is still alive
+func (l *PostgresLock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return fmt.Errorf("advisory lock is not held")
+	}
+
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return fmt.Errorf("leadership connection lost: %w", err)
+	}
+
+	return nil
+}
+
+// Release unlocks the advisory lock and returns the connection to the pool
+func (l *PostgresLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	l.conn.Close()
+	l.conn = nil
+	if err != nil {
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying database handle
+func (l *PostgresLock) Close() error {
+	l.mu.Lock()
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+	l.mu.Unlock()
+
+	return l.db.Close()
+}
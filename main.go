@@ -11,6 +11,7 @@ import (
 
 	"github.com/aaguiarz/openfga-sync/config"
 	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/leader"
 	"github.com/aaguiarz/openfga-sync/metrics"
 	"github.com/aaguiarz/openfga-sync/server"
 	"github.com/aaguiarz/openfga-sync/storage"
@@ -22,8 +23,14 @@ import (
 )
 
 func main() {
+	// "openfga-sync migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	startTime := flag.String("start-time", "", "Where a fresh deployment starts syncing: \"now\" or an RFC3339 timestamp (overrides service.start_time)")
 	flag.Parse()
 
 	// Load configuration
@@ -32,6 +39,24 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	if *startTime != "" {
+		if cfg.Service.Bootstrap {
+			fmt.Fprintf(os.Stderr, "Failed to load config: -start-time cannot be combined with service.bootstrap\n")
+			os.Exit(1)
+		}
+		cfg.Service.StartTime = *startTime
+	}
+
+	// Pin "now" to the process start so every store and type begins at the
+	// same point
+	if cfg.Service.StartTime != "" {
+		resolved, err := config.ParseStartTime(cfg.Service.StartTime, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Service.StartTime = resolved.UTC().Format(time.RFC3339Nano)
+	}
 
 	// Setup logger
 	logger := logrus.New()
@@ -50,9 +75,13 @@ func main() {
 		"version":          "1.0.0",
 		"openfga_endpoint": cfg.OpenFGA.Endpoint,
 		"openfga_store":    cfg.OpenFGA.StoreID,
+		"openfga_stores":   cfg.OpenFGA.Stores(),
+		"all_stores":       cfg.OpenFGA.AllStores,
 		"backend_type":     cfg.Backend.Type,
 		"storage_mode":     cfg.Backend.Mode,
+		"backend_sinks":    cfg.SinkNames(),
 		"poll_interval":    cfg.Service.PollInterval,
+		"start_time":       cfg.Service.StartTime,
 		"server_port":      cfg.Server.Port,
 		"metrics_enabled":  cfg.Observability.Metrics.Enabled,
 	}).Info("Starting OpenFGA sync service")
@@ -73,59 +102,19 @@ func main() {
 	// Initialize HTTP server
 	httpServer := server.New(cfg, logger, metricsCollector)
 
-	// Initialize storage adapter
-	storageAdapter, err := storage.NewStorageAdapter(cfg, logger)
+	// Initialize storage adapters, one per sink when backend is a list
+	sinks, err := newSinks(cfg, logger, metricsCollector)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize storage adapter")
 	}
 
-	// Initialize OpenFGA fetcher with enhanced options
-	fetchOptions := fetcher.FetchOptions{
-		PageSize:   cfg.Service.BatchSize,
-		MaxChanges: cfg.Service.MaxChanges,
-		Timeout:    cfg.Service.RequestTimeout,
-		RetryConfig: fetcher.RetryConfig{
-			MaxRetries:    cfg.Service.MaxRetries,
-			InitialDelay:  cfg.Service.RetryDelay,
-			MaxDelay:      cfg.Service.MaxRetryDelay,
-			BackoffFactor: cfg.Service.BackoffFactor,
-		},
-		RateLimitDelay:   cfg.Service.RateLimitDelay,
-		EnableValidation: cfg.Service.EnableValidation,
+	// Initialize OpenFGA fetcher with enhanced options. In all-stores mode it
+	// has no store and is only used to discover stores.
+	var primaryStoreID string
+	if stores := cfg.OpenFGA.Stores(); len(stores) > 0 {
+		primaryStoreID = stores[0]
 	}
-
-	var fgaFetcher *fetcher.OpenFGAFetcher
-
-	// Check if OIDC configuration is provided
-	if cfg.OpenFGA.OIDC.ClientID != "" && cfg.OpenFGA.OIDC.ClientSecret != "" {
-		// Use OIDC authentication
-		oidcConfig := fetcher.OIDCConfig{
-			Issuer:       cfg.OpenFGA.OIDC.Issuer,
-			Audience:     cfg.OpenFGA.OIDC.Audience,
-			ClientID:     cfg.OpenFGA.OIDC.ClientID,
-			ClientSecret: cfg.OpenFGA.OIDC.ClientSecret,
-			Scopes:       cfg.OpenFGA.OIDC.Scopes,
-			TokenIssuer:  cfg.OpenFGA.OIDC.TokenIssuer,
-		}
-
-		fgaFetcher, err = fetcher.NewOpenFGAFetcherWithOIDCAndOptions(
-			cfg.OpenFGA.Endpoint,
-			cfg.OpenFGA.StoreID,
-			oidcConfig,
-			logger,
-			fetchOptions,
-		)
-	} else {
-		// Use API token authentication
-		fgaFetcher, err = fetcher.NewOpenFGAFetcherWithOptions(
-			cfg.OpenFGA.Endpoint,
-			cfg.OpenFGA.StoreID,
-			cfg.OpenFGA.Token,
-			logger,
-			fetchOptions,
-		)
-	}
-
+	fgaFetcher, err := newFetcher(cfg, primaryStoreID, "", logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize OpenFGA fetcher")
 	}
@@ -184,14 +173,19 @@ func main() {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				// Check storage connection status
-				if stats, err := storageAdapter.GetStats(ctx); err == nil {
-					if status, ok := stats["connection_status"].(string); ok {
-						metricsCollector.UpdateStorageConnectionStatus(status == "healthy" || status == "connected")
+				// Check storage connection status; with several sinks every one must be connected
+				connected := true
+				for _, s := range sinks {
+					stats, err := s.adapter.GetStats(ctx)
+					if err != nil {
+						connected = false
+						continue
+					}
+					if status, ok := stats["connection_status"].(string); ok && status != "healthy" && status != "connected" {
+						connected = false
 					}
-				} else {
-					metricsCollector.UpdateStorageConnectionStatus(false)
 				}
+				metricsCollector.UpdateStorageConnectionStatus(connected)
 			}
 		}
 	}()
@@ -199,8 +193,13 @@ func main() {
 	// Start the sync process
 	logger.Info("OpenFGA sync service started successfully")
 
-	// Run the sync loop until shutdown
-	syncErr := runSyncLoop(ctx, fgaFetcher, storageAdapter, cfg, logger, metricsCollector)
+	// Run the sync loop until shutdown, only while leading when leader election is enabled
+	var syncErr error
+	if cfg.Leadership.Enabled {
+		syncErr = runAsLeader(ctx, fgaFetcher, sinks, cfg, logger, metricsCollector, httpServer)
+	} else {
+		syncErr = runSync(ctx, fgaFetcher, sinks, cfg, logger, metricsCollector)
+	}
 
 	// Begin graceful shutdown
 	logger.Info("Beginning graceful shutdown...")
@@ -218,12 +217,8 @@ func main() {
 	}
 	shutdownCancel()
 
-	// Close storage adapter
-	if err := storageAdapter.Close(); err != nil {
-		logger.WithError(err).Error("Failed to close storage adapter gracefully")
-	} else {
-		logger.Debug("Storage adapter closed gracefully")
-	}
+	// Close storage adapters
+	closeSinks(sinks, logger)
 
 	// Close OpenFGA fetcher
 	fgaFetcher.Close()
@@ -246,35 +241,322 @@ func main() {
 	logger.Info("OpenFGA sync service stopped gracefully")
 }
 
+// runAsLeader takes part in leader election and runs the sync loop only while
+// this replica holds leadership
+func runAsLeader(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, sinks []sink, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics, httpServer *server.Server) error {
+	elector, err := leader.NewElector(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize leader election: %w", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"identity":  elector.Identity(),
+		"backend":   cfg.Leadership.Backend,
+		"lock_name": cfg.Leadership.LockName,
+	}).Info("Joining leader election")
+
+	return elector.Run(ctx, leader.Callbacks{
+		OnStartedLeading: func(leaderCtx context.Context) {
+			metrics.RecordLeadershipChange(true)
+			httpServer.SetLeader(true)
+			runLeaderSync(leaderCtx, fgaFetcher, sinks, cfg, logger, metrics)
+		},
+		OnStoppedLeading: func() {
+			metrics.RecordLeadershipChange(false)
+			httpServer.SetLeader(false)
+		},
+	})
+}
+
+// runLeaderSync runs the sync loop until leaderCtx is cancelled. The elector
+// keeps leadership while this runs, so a sync loop that fails, for example
+// because the database is briefly unreachable, is restarted with backoff
+// instead of leaving the leader idle and the followers locked out.
+func runLeaderSync(leaderCtx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, sinks []sink, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) {
+	delay := cfg.Service.RetryDelay
+	if delay <= 0 {
+		delay = cfg.Service.PollInterval
+	}
+
+	for {
+		err := runSync(leaderCtx, fgaFetcher, sinks, cfg, logger, metrics)
+		if leaderCtx.Err() != nil {
+			return
+		}
+
+		logger.WithError(err).WithField("retry_in", delay).Error("Sync loop terminated with error, restarting")
+		if !sleepContext(leaderCtx, delay) {
+			return
+		}
+		delay = time.Duration(float64(delay) * cfg.Service.BackoffFactor)
+		if cfg.Service.MaxRetryDelay > 0 && delay > cfg.Service.MaxRetryDelay {
+			delay = cfg.Service.MaxRetryDelay
+		}
+	}
+}
+
+// newFetcher creates an OpenFGA fetcher for storeID using the configured
+// authentication, fetch options and filters. A non-empty objectType restricts
+// ReadChanges to that type.
+func newFetcher(cfg *config.Config, storeID, objectType string, logger *logrus.Logger) (*fetcher.OpenFGAFetcher, error) {
+	fetchOptions := fetcher.FetchOptions{
+		PageSize:   cfg.Service.BatchSize,
+		MaxChanges: cfg.Service.MaxChanges,
+		Timeout:    cfg.Service.RequestTimeout,
+		RetryConfig: fetcher.RetryConfig{
+			MaxRetries:    cfg.Service.MaxRetries,
+			InitialDelay:  cfg.Service.RetryDelay,
+			MaxDelay:      cfg.Service.MaxRetryDelay,
+			BackoffFactor: cfg.Service.BackoffFactor,
+		},
+		RateLimitDelay:   cfg.Service.RateLimitDelay,
+		EnableValidation: cfg.Service.EnableValidation,
+		ObjectType:       objectType,
+		Filter: fetcher.ChangeFilter{
+			IncludeRelations: cfg.Service.Filter.IncludeRelations,
+			ExcludeRelations: cfg.Service.Filter.ExcludeRelations,
+			IncludeUserTypes: cfg.Service.Filter.IncludeUserTypes,
+			ExcludeUserTypes: cfg.Service.Filter.ExcludeUserTypes,
+		},
+	}
+
+	// Check if OIDC configuration is provided
+	if cfg.OpenFGA.OIDC.ClientID != "" && cfg.OpenFGA.OIDC.ClientSecret != "" {
+		// Use OIDC authentication
+		oidcConfig := fetcher.OIDCConfig{
+			Issuer:       cfg.OpenFGA.OIDC.Issuer,
+			Audience:     cfg.OpenFGA.OIDC.Audience,
+			ClientID:     cfg.OpenFGA.OIDC.ClientID,
+			ClientSecret: cfg.OpenFGA.OIDC.ClientSecret,
+			Scopes:       cfg.OpenFGA.OIDC.Scopes,
+			TokenIssuer:  cfg.OpenFGA.OIDC.TokenIssuer,
+		}
+
+		return fetcher.NewOpenFGAFetcherWithOIDCAndOptions(cfg.OpenFGA.Endpoint, storeID, oidcConfig, logger, fetchOptions)
+	}
+
+	// Use API token authentication
+	return fetcher.NewOpenFGAFetcherWithOptions(cfg.OpenFGA.Endpoint, storeID, cfg.OpenFGA.Token, logger, fetchOptions)
+}
+
 // runSyncLoop runs the main synchronization loop
 func runSyncLoop(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) error {
-	// Get the last continuation token
-	continuationToken, err := storageAdapter.GetLastContinuationToken(ctx)
+	continuationToken, err := resolveStartToken(ctx, fgaFetcher, storageAdapter, cfg, logger, metrics)
 	if err != nil {
-		return fmt.Errorf("failed to get last continuation token: %w", err)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
 	}
 
-	logger.WithField("continuation_token", continuationToken).Info("Starting sync from continuation token")
+	logger.WithFields(logrus.Fields{
+		"store_id":           fgaFetcher.StoreID(),
+		"object_type":        fgaFetcher.ObjectType(),
+		"continuation_token": continuationToken,
+	}).Info("Starting sync from continuation token")
 
-	ticker := time.NewTicker(cfg.Service.PollInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(cfg.Service.PollInterval)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			if err := syncChanges(ctx, fgaFetcher, storageAdapter, cfg, &continuationToken, logger, metrics); err != nil {
+		case <-timer.C:
+			behind, err := drainChanges(ctx, fgaFetcher, storageAdapter, cfg, &continuationToken, logger, metrics)
+			if err != nil {
 				logger.WithError(err).Error("Failed to sync changes")
 				metrics.RecordChangesError()
 				// Continue running despite errors
 			}
+
+			// Only wait for the poll interval once caught up
+			if behind && err == nil {
+				timer.Reset(0)
+			} else {
+				timer.Reset(cfg.Service.PollInterval)
+			}
+		}
+	}
+}
+
+// resolveStartToken returns the continuation token to sync from: the saved
+// checkpoint, else the service.start_time position, bootstrapping the state
+// first when needed
+func resolveStartToken(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) (string, error) {
+	// Get the last continuation token
+	continuationToken, err := storageAdapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get last continuation token: %w", err)
+	}
+
+	// Start a fresh deployment at service.start_time instead of the beginning
+	// of the history, and checkpoint it so a restart doesn't move it
+	if continuationToken == "" && cfg.Service.StartTime != "" {
+		startTime, err := time.Parse(time.RFC3339Nano, cfg.Service.StartTime)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse start time: %w", err)
+		}
+		continuationToken = fetcher.StartTimeToken(startTime)
+		if err := storageAdapter.SaveContinuationToken(ctx, continuationToken); err != nil {
+			return "", fmt.Errorf("failed to save start position: %w", err)
+		}
+		logger.WithField("start_time", startTime).Info("No checkpoint found, starting sync from start time")
+	}
+
+	// Snapshot current state instead of replaying the full change history
+	if needsBootstrap(cfg, continuationToken) {
+		continuationToken, err = runBootstrap(ctx, fgaFetcher, storageAdapter, cfg, continuationToken, logger, metrics)
+		if err != nil {
+			return "", fmt.Errorf("failed to bootstrap state: %w", err)
 		}
 	}
+
+	return continuationToken, nil
 }
 
-// syncChanges fetches and stores changes from OpenFGA
-func syncChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, continuationToken *string, logger *logrus.Logger, metrics *metrics.Metrics) error {
+// drainChanges runs one sync cycle, consuming pages back-to-back while OpenFGA
+// reports more changes, up to cfg.Service.MaxChanges per cycle (0 = unlimited).
+// With a positive cfg.Service.PipelineDepth the next pages are prefetched while
+// the current one is written. It returns true if the cycle stopped on its
+// budget with changes still pending.
+func drainChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, continuationToken *string, logger *logrus.Logger, metrics *metrics.Metrics) (bool, error) {
+	var batches <-chan prefetchedBatch
+	if cfg.Service.PipelineDepth > 0 {
+		pipelineCtx, cancel := context.WithCancel(ctx)
+		pipeline := prefetchChanges(pipelineCtx, fgaFetcher, cfg, *continuationToken, metrics)
+		batches = pipeline
+		defer func() {
+			// Stop the prefetcher and wait for it so it never outlives the cycle
+			cancel()
+			for range pipeline {
+			}
+			metrics.UpdatePipelineInFlight(0)
+		}()
+	}
+
+	cycleChanges := 0
+	pages := 0
+
+	for {
+		var prefetched *fetcher.FetchResult
+		if batches != nil {
+			batch, ok := <-batches
+			if !ok {
+				// The prefetcher stops early only when the cycle was cancelled
+				metrics.UpdateCatchUp(false, cycleChanges)
+				return false, nil
+			}
+			metrics.UpdatePipelineInFlight(len(batches))
+			if batch.err != nil {
+				metrics.UpdateCatchUp(false, cycleChanges)
+				return false, batch.err
+			}
+			prefetched = batch.result
+		}
+
+		processed, hasMore, err := syncChanges(ctx, fgaFetcher, storageAdapter, cfg, continuationToken, prefetched, logger, metrics)
+		if err != nil {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			return false, err
+		}
+		cycleChanges += processed
+		pages++
+
+		// OpenFGA keeps returning a token at the head of the stream, so an
+		// empty page also means we've caught up
+		if !hasMore || processed == 0 || ctx.Err() != nil {
+			metrics.UpdateCatchUp(false, cycleChanges)
+			if pages > 1 {
+				logger.WithFields(logrus.Fields{
+					"pages":   pages,
+					"changes": cycleChanges,
+				}).Info("Caught up with change backlog")
+			}
+			return false, nil
+		}
+
+		metrics.UpdateCatchUp(true, cycleChanges)
+
+		if cfg.Service.MaxChanges > 0 && cycleChanges >= cfg.Service.MaxChanges {
+			logger.WithFields(logrus.Fields{
+				"pages":       pages,
+				"changes":     cycleChanges,
+				"max_changes": cfg.Service.MaxChanges,
+			}).Info("Reached per-cycle change limit, continuing backlog in next cycle")
+			return true, nil
+		}
+	}
+}
+
+// prefetchedBatch is a page fetched ahead of the writer, or the error that ended prefetching
+type prefetchedBatch struct {
+	result *fetcher.FetchResult
+	err    error
+}
+
+// prefetchChanges fetches pages from startToken in a background goroutine,
+// following each page's continuation token. At most cfg.Service.PipelineDepth
+// pages are held ahead of the writer; once that many are waiting the fetcher
+// blocks, so a slow sink applies backpressure. The channel is closed after the
+// last page of the cycle, an error, or cancellation of ctx.
+func prefetchChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, cfg *config.Config, startToken string, metrics *metrics.Metrics) <-chan prefetchedBatch {
+	// The goroutine holds one page while blocked on send, so buffer one fewer
+	batches := make(chan prefetchedBatch, cfg.Service.PipelineDepth-1)
+
+	go func() {
+		defer close(batches)
+
+		token := startToken
+		fetched := 0
+		for {
+			result, err := fetchChanges(ctx, fgaFetcher, cfg, token, metrics)
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case batches <- prefetchedBatch{result: result, err: err}:
+				metrics.UpdatePipelineInFlight(len(batches))
+			case <-ctx.Done():
+				return
+			}
+
+			// Mirror drainChanges' stop conditions so we don't fetch past the cycle
+			if err != nil || !result.HasMore || result.TotalFetched == 0 {
+				return
+			}
+			fetched += result.TotalFetched
+			if cfg.Service.MaxChanges > 0 && fetched >= cfg.Service.MaxChanges {
+				return
+			}
+			token = result.ContinuationToken
+		}
+	}()
+
+	return batches
+}
+
+// fetchChanges fetches one page of changes with retry logic and records request metrics
+func fetchChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, cfg *config.Config, continuationToken string, metrics *metrics.Metrics) (*fetcher.FetchResult, error) {
+	fetchStart := time.Now()
+	result, err := fgaFetcher.FetchChangesWithRetry(ctx, continuationToken, cfg.Service.BatchSize)
+	fetchDuration := time.Since(fetchStart)
+
+	if err != nil {
+		metrics.RecordOpenFGARequest("error", fetchDuration, "changes")
+		return nil, fmt.Errorf("failed to fetch changes: %w", err)
+	}
+
+	metrics.RecordOpenFGARequest("success", fetchDuration, "changes")
+	return result, nil
+}
+
+// syncChanges fetches and stores one page of changes from OpenFGA, returning
+// how many changes were consumed from the stream (including any dropped by
+// filters) and whether more are available. A page already fetched from
+// continuationToken can be passed as prefetched.
+func syncChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, continuationToken *string, prefetched *fetcher.FetchResult, logger *logrus.Logger, metrics *metrics.Metrics) (int, bool, error) {
 	// Start OpenTelemetry span for the entire sync operation
 	tracer := otel.Tracer("openfga-sync/main")
 	ctx, span := tracer.Start(ctx, "sync.changes",
@@ -292,29 +574,35 @@ func syncChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storag
 		metrics.RecordSyncDuration(time.Since(syncStart))
 	}()
 
-	// Use enhanced fetch with retry logic
-	fetchStart := time.Now()
-	result, err := fgaFetcher.FetchChangesWithRetry(ctx, *continuationToken, cfg.Service.BatchSize)
-	fetchDuration := time.Since(fetchStart)
-
-	if err != nil {
-		span.RecordError(err)
-		span.SetAttributes(attribute.String("error.type", "fetch_error"))
-		metrics.RecordOpenFGARequest("error", fetchDuration, "changes")
-		return fmt.Errorf("failed to fetch changes: %w", err)
+	// Use enhanced fetch with retry logic unless the pipeline already fetched the page
+	result := prefetched
+	span.SetAttributes(attribute.Bool("sync.prefetched", prefetched != nil))
+	if result == nil {
+		var err error
+		result, err = fetchChanges(ctx, fgaFetcher, cfg, *continuationToken, metrics)
+		if err != nil {
+			span.RecordError(err)
+			span.SetAttributes(attribute.String("error.type", "fetch_error"))
+			return 0, false, err
+		}
 	}
 
-	metrics.RecordOpenFGARequest("success", fetchDuration, "changes")
-
-	if len(result.Changes) == 0 {
+	if result.TotalFetched == 0 {
 		span.SetAttributes(attribute.Int("sync.changes_found", 0))
 		logger.Debug("No new changes found")
-		return nil
+		return 0, false, nil
+	}
+
+	// Changes dropped by filters still move the continuation token forward
+	filtered := result.TotalFetched - len(result.Changes)
+	if filtered > 0 {
+		metrics.RecordChangesFiltered(filtered)
 	}
 
 	// Add span attributes for the fetched data
 	span.SetAttributes(
 		attribute.Int("sync.changes_found", len(result.Changes)),
+		attribute.Int("sync.changes_filtered", filtered),
 		attribute.String("sync.next_token", result.ContinuationToken),
 		attribute.Bool("sync.has_more", result.HasMore),
 	)
@@ -328,47 +616,87 @@ func syncChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storag
 		"average_latency":  fmt.Sprintf("%.2fms", stats.AverageLatency),
 	}).Debug("Fetcher statistics")
 
+	// Commit the batch together with its continuation token when the adapter
+	// supports it, so a crash between the two can't replay the batch
+	txAdapter, transactional := storageAdapter.(storage.TransactionalAdapter)
+	atomicCommit := transactional && result.ContinuationToken != ""
+	span.SetAttributes(attribute.Bool("sync.atomic_commit", atomicCommit))
+
 	// Apply changes based on storage mode
 	storageStart := time.Now()
 	var storageErr error
 
 	if cfg.IsChangelogMode() {
-		storageErr = storageAdapter.WriteChanges(ctx, result.Changes)
+		if atomicCommit {
+			storageErr = txAdapter.WriteChangesWithToken(ctx, result.Changes, result.ContinuationToken)
+		} else {
+			storageErr = storageAdapter.WriteChanges(ctx, result.Changes)
+		}
 		if storageErr != nil {
 			span.RecordError(storageErr)
 			span.SetAttributes(attribute.String("error.type", "storage_write_error"))
 			metrics.RecordStorageOperation("write", "error", time.Since(storageStart))
-			return fmt.Errorf("failed to write changes: %w", storageErr)
+			return 0, false, fmt.Errorf("failed to write changes: %w", storageErr)
 		}
 		metrics.RecordStorageOperation("write", "success", time.Since(storageStart))
 		span.SetAttributes(attribute.String("sync.storage_operation", "write"))
-	} else if cfg.IsStatefulMode() {
-		storageErr = storageAdapter.ApplyChanges(ctx, result.Changes)
+	} else if cfg.IsStatefulMode() || cfg.IsHistoryMode() {
+		// History mode applies changes as validity intervals
+		if atomicCommit {
+			storageErr = txAdapter.ApplyChangesWithToken(ctx, result.Changes, result.ContinuationToken)
+		} else {
+			storageErr = storageAdapter.ApplyChanges(ctx, result.Changes)
+		}
 		if storageErr != nil {
 			span.RecordError(storageErr)
 			span.SetAttributes(attribute.String("error.type", "storage_apply_error"))
 			metrics.RecordStorageOperation("apply", "error", time.Since(storageStart))
-			return fmt.Errorf("failed to apply changes: %w", storageErr)
+			return 0, false, fmt.Errorf("failed to apply changes: %w", storageErr)
 		}
 		metrics.RecordStorageOperation("apply", "success", time.Since(storageStart))
 		span.SetAttributes(attribute.String("sync.storage_operation", "apply"))
+	} else if cfg.IsHybridMode() {
+		// Hybrid adapters append to the changelog and update the state table
+		// in the same transaction
+		hybridAdapter, ok := storageAdapter.(storage.HybridAdapter)
+		if !ok {
+			err := fmt.Errorf("backend %s does not support hybrid mode", cfg.Backend.Type)
+			span.RecordError(err)
+			span.SetAttributes(attribute.String("error.type", "invalid_storage_mode"))
+			return 0, false, err
+		}
+		if atomicCommit {
+			storageErr = hybridAdapter.WriteAndApplyChangesWithToken(ctx, result.Changes, result.ContinuationToken)
+		} else {
+			storageErr = hybridAdapter.WriteAndApplyChanges(ctx, result.Changes)
+		}
+		if storageErr != nil {
+			span.RecordError(storageErr)
+			span.SetAttributes(attribute.String("error.type", "storage_write_apply_error"))
+			metrics.RecordStorageOperation("write_apply", "error", time.Since(storageStart))
+			return 0, false, fmt.Errorf("failed to write and apply changes: %w", storageErr)
+		}
+		metrics.RecordStorageOperation("write_apply", "success", time.Since(storageStart))
+		span.SetAttributes(attribute.String("sync.storage_operation", "write_apply"))
 	} else {
 		err := fmt.Errorf("unsupported storage mode: %s", cfg.Backend.Mode)
 		span.RecordError(err)
 		span.SetAttributes(attribute.String("error.type", "invalid_storage_mode"))
-		return err
+		return 0, false, err
 	}
 
 	// Record successful change processing
 	metrics.RecordChangesProcessed(len(result.Changes))
 
-	if result.ContinuationToken != "" {
+	if atomicCommit {
+		*continuationToken = result.ContinuationToken
+	} else if result.ContinuationToken != "" {
 		tokenStart := time.Now()
 		if err := storageAdapter.SaveContinuationToken(ctx, result.ContinuationToken); err != nil {
 			span.RecordError(err)
 			span.SetAttributes(attribute.String("error.type", "token_save_error"))
 			metrics.RecordStorageOperation("save_token", "error", time.Since(tokenStart))
-			return fmt.Errorf("failed to save continuation token: %w", err)
+			return 0, false, fmt.Errorf("failed to save continuation token: %w", err)
 		}
 		metrics.RecordStorageOperation("save_token", "success", time.Since(tokenStart))
 		*continuationToken = result.ContinuationToken
@@ -406,5 +734,5 @@ func syncChanges(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storag
 		"sync_duration_ms":  time.Since(syncStart).Milliseconds(),
 	}).Info("Successfully processed changes batch")
 
-	return nil
+	return result.TotalFetched, result.HasMore, nil
 }
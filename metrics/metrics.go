@@ -12,12 +12,22 @@ import (
 type Metrics struct {
 	// Change processing metrics
 	ChangesProcessedTotal prometheus.Counter
+	ChangesFilteredTotal  prometheus.Counter
 	ChangesErrorsTotal    prometheus.Counter
 	ChangesLagSeconds     prometheus.Gauge
 
 	// Sync processing metrics
 	SyncDurationSeconds prometheus.Histogram
 	SyncLastTimestamp   prometheus.Gauge
+	SyncCatchingUp      prometheus.Gauge
+	SyncCycleChanges    prometheus.Gauge
+	SyncPipelineBatches prometheus.Gauge
+	SyncStores          prometheus.Gauge
+
+	// Fan-out sink metrics
+	SinkPagesBehind prometheus.GaugeVec
+	SinkDetached    prometheus.GaugeVec
+	SinkErrorsTotal prometheus.CounterVec
 
 	// OpenFGA API metrics
 	OpenFGARequestsTotal       prometheus.CounterVec
@@ -29,10 +39,19 @@ type Metrics struct {
 	StorageOperationDuration prometheus.HistogramVec
 	StorageConnectionStatus  prometheus.Gauge
 
+	// Storage maintenance metrics
+	ChangelogPrunedRowsTotal   prometheus.CounterVec
+	StorageReclaimedBytesTotal prometheus.CounterVec
+	StorageDatabaseBytes       prometheus.GaugeVec
+
 	// Service health metrics
 	ServiceUptime         prometheus.Counter
 	ServiceStartTimestamp prometheus.Gauge
 
+	// Leader election metrics
+	LeaderStatus               prometheus.Gauge
+	LeadershipTransitionsTotal prometheus.CounterVec
+
 	mu sync.RWMutex
 }
 
@@ -44,6 +63,10 @@ func New() *Metrics {
 			Name: "openfga_sync_changes_processed_total",
 			Help: "Total number of changes processed successfully",
 		}),
+		ChangesFilteredTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "openfga_sync_changes_filtered_total",
+			Help: "Total number of fetched changes dropped by client-side filters",
+		}),
 		ChangesErrorsTotal: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "openfga_sync_changes_errors_total",
 			Help: "Total number of change processing errors",
@@ -63,6 +86,36 @@ func New() *Metrics {
 			Name: "openfga_sync_last_timestamp",
 			Help: "Unix timestamp of the last successful sync",
 		}),
+		SyncCatchingUp: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "openfga_sync_catching_up",
+			Help: "Whether the sync loop is draining a change backlog (1 = catching up, 0 = caught up)",
+		}),
+		SyncCycleChanges: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "openfga_sync_cycle_changes",
+			Help: "Number of changes processed so far in the current or last sync cycle",
+		}),
+		SyncPipelineBatches: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "openfga_sync_pipeline_batches_in_flight",
+			Help: "Number of prefetched batches waiting to be written",
+		}),
+		SyncStores: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "openfga_sync_stores",
+			Help: "Number of OpenFGA stores being synced by this process",
+		}),
+
+		// Fan-out sink metrics
+		SinkPagesBehind: *promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openfga_sync_sink_pages_behind",
+			Help: "Number of fetched pages a fan-out sink has not written yet",
+		}, []string{"sink"}),
+		SinkDetached: *promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openfga_sync_sink_detached",
+			Help: "Whether a fan-out sink fell out of the shared page buffer and fetches its own pages (1 = detached)",
+		}, []string{"sink"}),
+		SinkErrorsTotal: *promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "openfga_sync_sink_errors_total",
+			Help: "Total number of failed writes by fan-out sink",
+		}, []string{"sink"}),
 
 		// OpenFGA API metrics
 		OpenFGARequestsTotal: *promauto.NewCounterVec(prometheus.CounterOpts{
@@ -94,6 +147,20 @@ func New() *Metrics {
 			Help: "Storage connection status (1 = connected, 0 = disconnected)",
 		}),
 
+		// Storage maintenance metrics
+		ChangelogPrunedRowsTotal: *promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "openfga_sync_changelog_pruned_rows_total",
+			Help: "Total number of changelog rows removed by retention, by sink and reason (max_age, max_rows, compaction)",
+		}, []string{"sink", "reason"}),
+		StorageReclaimedBytesTotal: *promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "openfga_sync_storage_reclaimed_bytes_total",
+			Help: "Total disk space returned by VACUUM and WAL checkpoints, by sink",
+		}, []string{"sink"}),
+		StorageDatabaseBytes: *promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openfga_sync_storage_database_bytes",
+			Help: "Database size after the last maintenance pass, by sink",
+		}, []string{"sink"}),
+
 		// Service health metrics
 		ServiceUptime: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "openfga_sync_service_uptime_seconds_total",
@@ -103,6 +170,16 @@ func New() *Metrics {
 			Name: "openfga_sync_service_start_timestamp",
 			Help: "Unix timestamp when the service started",
 		}),
+
+		// Leader election metrics
+		LeaderStatus: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "openfga_sync_leader_status",
+			Help: "Leader election status (1 = leader, 0 = follower)",
+		}),
+		LeadershipTransitionsTotal: *promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "openfga_sync_leadership_transitions_total",
+			Help: "Total number of leadership transitions by type (acquired, lost)",
+		}, []string{"transition"}),
 	}
 }
 
@@ -113,6 +190,13 @@ func (m *Metrics) RecordChangesProcessed(count int) {
 	m.ChangesProcessedTotal.Add(float64(count))
 }
 
+// RecordChangesFiltered increments the filtered changes counter
+func (m *Metrics) RecordChangesFiltered(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ChangesFilteredTotal.Add(float64(count))
+}
+
 // RecordChangesError increments the changes error counter
 func (m *Metrics) RecordChangesError() {
 	m.mu.Lock()
@@ -135,6 +219,51 @@ func (m *Metrics) RecordSyncDuration(duration time.Duration) {
 	m.SyncLastTimestamp.Set(float64(time.Now().Unix()))
 }
 
+// UpdateCatchUp updates the backlog catch-up progress of the current sync cycle
+func (m *Metrics) UpdateCatchUp(catchingUp bool, cycleChanges int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if catchingUp {
+		m.SyncCatchingUp.Set(1)
+	} else {
+		m.SyncCatchingUp.Set(0)
+	}
+	m.SyncCycleChanges.Set(float64(cycleChanges))
+}
+
+// UpdatePipelineInFlight updates the number of prefetched batches waiting to be written
+func (m *Metrics) UpdatePipelineInFlight(batches int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SyncPipelineBatches.Set(float64(batches))
+}
+
+// UpdateSyncedStores updates the number of stores with a running sync loop
+func (m *Metrics) UpdateSyncedStores(stores int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SyncStores.Set(float64(stores))
+}
+
+// UpdateSinkPosition updates how far a fan-out sink is behind the fetch loop
+func (m *Metrics) UpdateSinkPosition(sink string, pagesBehind int, detached bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SinkPagesBehind.WithLabelValues(sink).Set(float64(pagesBehind))
+	if detached {
+		m.SinkDetached.WithLabelValues(sink).Set(1)
+	} else {
+		m.SinkDetached.WithLabelValues(sink).Set(0)
+	}
+}
+
+// RecordSinkError increments the failed write counter of a fan-out sink
+func (m *Metrics) RecordSinkError(sink string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SinkErrorsTotal.WithLabelValues(sink).Inc()
+}
+
 // RecordOpenFGARequest records OpenFGA API request metrics
 func (m *Metrics) RecordOpenFGARequest(status string, duration time.Duration, endpoint string) {
 	m.mu.Lock()
@@ -166,6 +295,18 @@ func (m *Metrics) UpdateStorageConnectionStatus(connected bool) {
 	}
 }
 
+// RecordStorageMaintenance records one maintenance pass of a sink. pruned maps
+// the reason rows were removed to their count.
+func (m *Metrics) RecordStorageMaintenance(sink string, pruned map[string]int64, reclaimedBytes, databaseBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for reason, rows := range pruned {
+		m.ChangelogPrunedRowsTotal.WithLabelValues(sink, reason).Add(float64(rows))
+	}
+	m.StorageReclaimedBytesTotal.WithLabelValues(sink).Add(float64(reclaimedBytes))
+	m.StorageDatabaseBytes.WithLabelValues(sink).Set(float64(databaseBytes))
+}
+
 // RecordServiceStart records when the service started
 func (m *Metrics) RecordServiceStart() {
 	m.mu.Lock()
@@ -179,3 +320,16 @@ func (m *Metrics) IncrementUptime() {
 	defer m.mu.Unlock()
 	m.ServiceUptime.Inc()
 }
+
+// RecordLeadershipChange updates the leader status and counts the transition
+func (m *Metrics) RecordLeadershipChange(leading bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if leading {
+		m.LeaderStatus.Set(1)
+		m.LeadershipTransitionsTotal.WithLabelValues("acquired").Inc()
+	} else {
+		m.LeaderStatus.Set(0)
+		m.LeadershipTransitionsTotal.WithLabelValues("lost").Inc()
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = `Usage: openfga-sync migrate [flags] up|down|status
+
+Manages the schema of the postgres, sqlite, mysql and mariadb backends. The
+service migrates to the latest version on startup; use this command to
+migrate ahead of a deployment, roll back, or inspect the applied versions.
+
+  up      apply every pending migration
+  down    roll back the most recent migrations (see -steps)
+  status  list migrations and when each was applied
+
+Flags:
+`
+
+// runMigrateCommand runs "openfga-sync migrate" against every SQL sink in the
+// configuration and returns the process exit code
+func runMigrateCommand(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configPath := flags.String("config", "config.yaml", "Path to configuration file")
+	steps := flags.Int("steps", 1, "Number of migrations to roll back with down")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	command := flags.Arg(0)
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintf(os.Stderr, "Unknown migrate command: %s\n", command)
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	logger := logrus.New()
+	if level, err := logrus.ParseLevel(cfg.Logging.Level); err == nil {
+		logger.SetLevel(level)
+	}
+	if cfg.Logging.Format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	ctx := context.Background()
+	migrated := 0
+	for _, sink := range cfg.Sinks() {
+		if !storage.HasMigrations(sink.Type) {
+			logger.WithField("sink", sink.SinkName()).Debug("Backend has no schema migrations, skipping")
+			continue
+		}
+		if err := migrateSink(ctx, sink, command, *steps, os.Stdout, logger); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to migrate %s: %v\n", sink.SinkName(), err)
+			return 1
+		}
+		migrated++
+	}
+
+	if migrated == 0 {
+		fmt.Fprintln(os.Stderr, "No postgres, sqlite, mysql or mariadb backend configured")
+		return 1
+	}
+	return 0
+}
+
+// migrateSink runs one migrate command against the database of sink
+func migrateSink(ctx context.Context, sink config.BackendConfig, command string, steps int, out io.Writer, logger *logrus.Logger) error {
+	migrator, db, err := storage.NewMigrator(sink, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s: applied %d migration(s)\n", sink.SinkName(), applied)
+
+	case "down":
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s: rolled back %d migration(s)\n", sink.SinkName(), rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s:\n", sink.SinkName())
+		table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "  VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			} else if status.Skipped {
+				appliedAt = "skipped (" + status.Feature + " disabled)"
+			}
+			fmt.Fprintf(table, "  %04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		table.Flush()
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/aaguiarz/openfga-sync/config"
@@ -23,6 +25,7 @@ type Server struct {
 	// Service state
 	startTime time.Time
 	ready     bool
+	leader    atomic.Bool // Set from the leader election goroutine
 }
 
 // HealthResponse represents the health check response
@@ -119,6 +122,17 @@ func (s *Server) SetReady(ready bool) {
 	}
 }
 
+// SetLeader records whether this replica currently holds leadership. When
+// leader election is enabled, followers report not ready.
+func (s *Server) SetLeader(leader bool) {
+	s.leader.Store(leader)
+	if leader {
+		s.logger.Info("Service is now the leader")
+	} else {
+		s.logger.Info("Service is now a follower")
+	}
+}
+
 // healthHandler handles the /healthz endpoint
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(s.startTime)
@@ -131,6 +145,7 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 		Details: map[string]string{
 			"backend_type":  s.config.Backend.Type,
 			"storage_mode":  string(s.config.Backend.Mode),
+			"backend_sinks": strings.Join(s.config.SinkNames(), ","),
 			"poll_interval": s.config.Service.PollInterval.String(),
 		},
 	}
@@ -167,6 +182,18 @@ func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
 		dependencies["service_ready"] = "NOT_READY"
 	}
 
+	// Only the leader syncs, so followers must not receive traffic
+	leader := s.leader.Load()
+	if s.config.Leadership.Enabled {
+		if leader {
+			dependencies["leadership"] = "LEADER"
+		} else {
+			status = "NOT_READY"
+			statusCode = http.StatusServiceUnavailable
+			dependencies["leadership"] = "FOLLOWER"
+		}
+	}
+
 	// Additional dependency checks could be added here
 	// For example, checking OpenFGA connectivity, database health, etc.
 
@@ -189,6 +216,7 @@ func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
 		"endpoint": "/readyz",
 		"status":   response.Status,
 		"ready":    s.ready,
+		"leader":   leader,
 	}).Debug("Readiness check requested")
 }
 
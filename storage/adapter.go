@@ -30,12 +30,76 @@ type StorageAdapter interface {
 	Close() error
 }
 
+// TransactionalAdapter is implemented by adapters that can persist a batch of
+// changes and its continuation token atomically. A crash can then never leave
+// a committed batch behind an old token, which would replay it on restart.
+type TransactionalAdapter interface {
+	// WriteChangesWithToken writes changes and saves the token in one transaction (changelog mode)
+	WriteChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error
+
+	// ApplyChangesWithToken applies changes and saves the token in one transaction (stateful mode)
+	ApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error
+}
+
+// HybridAdapter is implemented by adapters that can keep the changelog and
+// the current state in the same database (hybrid mode)
+type HybridAdapter interface {
+	// WriteAndApplyChanges appends changes to the changelog and applies them to
+	// the state table in one transaction
+	WriteAndApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error
+
+	// WriteAndApplyChangesWithToken does the same and saves the token in that transaction
+	WriteAndApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error
+}
+
+// HistoryAdapter is implemented by adapters that keep a validity interval for
+// every tuple (history mode)
+type HistoryAdapter interface {
+	// TuplesAsOf returns the tuples that existed at query.At
+	TuplesAsOf(ctx context.Context, query AsOfQuery) ([]HistoryTuple, error)
+}
+
+// MaintainedAdapter is implemented by adapters that prune and compact their
+// storage in a background task
+type MaintainedAdapter interface {
+	// OnMaintenance registers fn to receive the report of every completed
+	// maintenance pass
+	OnMaintenance(fn func(MaintenanceReport))
+}
+
+// MaintenanceReport describes one background maintenance pass
+type MaintenanceReport struct {
+	ExpiredRows    int64 // Changes deleted for being older than the maximum age
+	OverflowRows   int64 // Oldest changes deleted beyond the maximum row count
+	CompactedRows  int64 // Changes removed as write/delete pairs of one tuple
+	ReclaimedBytes int64 // Disk space returned by VACUUM and WAL checkpoints
+	DatabaseBytes  int64 // Database size after the pass
+}
+
+// MultiStoreAdapter is implemented by adapters that can hold several OpenFGA
+// stores in one database, partitioned by a store_id column
+type MultiStoreAdapter interface {
+	// ForStore returns an adapter scoped to storeID that shares this adapter's
+	// connection. Closing the returned adapter does not close the connection.
+	ForStore(storeID string) StorageAdapter
+}
+
+// CursorAdapter is implemented by adapters that can keep one continuation
+// token per object type, for changes fetched with the ReadChanges type filter
+type CursorAdapter interface {
+	// ForObjectType returns a view of the adapter whose continuation token is
+	// tracked separately for objectType. Changes are stored as usual.
+	ForObjectType(objectType string) StorageAdapter
+}
+
 // StorageMode represents the storage operation mode
 type StorageMode string
 
 const (
 	StorageModeChangelog StorageMode = "changelog"
 	StorageModeStateful  StorageMode = "stateful"
+	StorageModeHybrid    StorageMode = "hybrid"
+	StorageModeHistory   StorageMode = "history"
 )
 
 // NewStorageAdapter creates a storage adapter based on configuration
@@ -44,13 +108,20 @@ func NewStorageAdapter(cfg *config.Config, logger interface{}) (StorageAdapter,
 	case "postgres":
 		// Convert logger to the expected type
 		if l, ok := logger.(*logrus.Logger); ok {
-			return NewPostgresAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+			return NewPostgresAdapterWithOptions(cfg.Backend.DSN, cfg.Backend.Mode, PostgresOptions{
+				Naming:       tableNaming(cfg.Backend),
+				Partitioning: cfg.Backend.Partitioning,
+				BulkSize:     cfg.Backend.BulkSize,
+			}, l)
 		}
 		return nil, fmt.Errorf("invalid logger type for postgres adapter")
 	case "sqlite":
 		// Convert logger to the expected type
 		if l, ok := logger.(*logrus.Logger); ok {
-			return NewSQLiteAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+			return NewSQLiteAdapterWithOptions(cfg.Backend.DSN, cfg.Backend.Mode, SQLiteOptions{
+				Naming:    tableNaming(cfg.Backend),
+				Retention: cfg.Backend.Retention,
+			}, l)
 		}
 		return nil, fmt.Errorf("invalid logger type for sqlite adapter")
 	case "openfga":
@@ -59,9 +130,61 @@ func NewStorageAdapter(cfg *config.Config, logger interface{}) (StorageAdapter,
 			return NewOpenFGAAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
 		}
 		return nil, fmt.Errorf("invalid logger type for openfga adapter")
-	// TODO: Add other adapters as needed
-	// case "mysql":
-	//     return NewMySQLAdapter(cfg.Backend.DSN, cfg.Backend.Mode, logger)
+	case "mysql", "mariadb":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewMySQLAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for mysql adapter")
+	case "clickhouse":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewClickHouseAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for clickhouse adapter")
+	case "kafka":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewKafkaAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for kafka adapter")
+	case "nats":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewNATSAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for nats adapter")
+	case "redis":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewRedisAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for redis adapter")
+	case "file":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewFileAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for file adapter")
+	case "parquet":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			return NewParquetAdapter(cfg.Backend.DSN, cfg.Backend.Mode, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for parquet adapter")
+	case "webhook":
+		// Convert logger to the expected type
+		if l, ok := logger.(*logrus.Logger); ok {
+			// Deliveries back off like OpenFGA API calls
+			retry := fetcher.RetryConfig{
+				MaxRetries:    cfg.Service.MaxRetries,
+				InitialDelay:  cfg.Service.RetryDelay,
+				MaxDelay:      cfg.Service.MaxRetryDelay,
+				BackoffFactor: cfg.Service.BackoffFactor,
+			}
+			return NewWebhookAdapter(cfg.Backend.DSN, cfg.Backend.Mode, retry, l)
+		}
+		return nil, fmt.Errorf("invalid logger type for webhook adapter")
 	default:
 		return nil, fmt.Errorf("unsupported backend type: %s", cfg.Backend.Type)
 	}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CheckpointStore persists continuation tokens for adapters whose sink
+// cannot store them alongside the data (e.g. another OpenFGA store)
+type CheckpointStore interface {
+	// Load returns the last saved continuation token, or "" if none exists
+	Load(ctx context.Context) (string, error)
+
+	// Save durably records the continuation token
+	Save(ctx context.Context, token string) error
+
+	// Type returns the checkpoint backend name for stats and logging
+	Type() string
+
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// CheckpointConfig selects and configures a checkpoint store
+type CheckpointConfig struct {
+	Type string `json:"type"`          // memory, file, sqlite or postgres
+	DSN  string `json:"dsn,omitempty"` // file path for file, DSN for sqlite/postgres
+	Key  string `json:"key,omitempty"` // row key for SQL stores, defaults to the adapter's key
+}
+
+// NewCheckpointStore creates a checkpoint store from configuration.
+// defaultKey identifies the replication stream when cfg.Key is empty.
+func NewCheckpointStore(cfg CheckpointConfig, defaultKey string, logger *logrus.Logger) (CheckpointStore, error) {
+	key := cfg.Key
+	if key == "" {
+		key = defaultKey
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case "", "memory":
+		return &memoryCheckpointStore{}, nil
+	case "file":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("file checkpoint requires a 'dsn' path")
+		}
+		return newFileCheckpointStore(cfg.DSN)
+	case "sqlite":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("sqlite checkpoint requires a 'dsn'")
+		}
+		dsn := cfg.DSN
+		if !strings.HasPrefix(dsn, "file:") && dsn != ":memory:" {
+			dsn = "file:" + dsn
+		}
+		return newSQLCheckpointStore("sqlite3", dsn, key, logger)
+	case "postgres":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("postgres checkpoint requires a 'dsn'")
+		}
+		return newSQLCheckpointStore("postgres", cfg.DSN, key, logger)
+	default:
+		return nil, fmt.Errorf("unsupported checkpoint type: %s", cfg.Type)
+	}
+}
+
+// memoryCheckpointStore keeps the token in memory (not persistent across restarts)
+type memoryCheckpointStore struct {
+	mu    sync.RWMutex
+	token string
+}
+
+func (m *memoryCheckpointStore) Load(ctx context.Context) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.token, nil
+}
+
+func (m *memoryCheckpointStore) Save(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = token
+	return nil
+}
+
+func (m *memoryCheckpointStore) Type() string { return "memory" }
+
+func (m *memoryCheckpointStore) Close() error { return nil }
+
+// fileCheckpointStore keeps the token in a local file, replaced atomically on save
+type fileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileCheckpointStore(path string) (*fileCheckpointStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &fileCheckpointStore{path: path}, nil
+}
+
+func (f *fileCheckpointStore) Load(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save writes to a temporary file, fsyncs it and renames it over the
+// checkpoint so a crash never leaves a truncated token behind. The directory
+// is fsynced too, otherwise the rename itself can be lost in a crash.
+func (f *fileCheckpointStore) Save(ctx context.Context, token string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+
+	dir, err := os.Open(filepath.Dir(f.path))
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint directory: %w", err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync checkpoint directory: %w", err)
+	}
+	return nil
+}
+
+func (f *fileCheckpointStore) Type() string { return "file" }
+
+func (f *fileCheckpointStore) Close() error { return nil }
+
+// sqlCheckpointStore keeps tokens in a fga_checkpoints table keyed by stream
+type sqlCheckpointStore struct {
+	db     *sql.DB
+	driver string
+	key    string
+}
+
+func newSQLCheckpointStore(driver, dsn, key string, logger *logrus.Logger) (*sqlCheckpointStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping checkpoint database: %w", err)
+	}
+
+	timestampType := "TIMESTAMP WITH TIME ZONE DEFAULT NOW()"
+	if driver == "sqlite3" {
+		timestampType = "DATETIME DEFAULT CURRENT_TIMESTAMP"
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS fga_checkpoints (
+		checkpoint_key TEXT PRIMARY KEY,
+		continuation_token TEXT NOT NULL,
+		updated_at ` + timestampType + `
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create checkpoint table: %w", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"driver": driver,
+		"key":    key,
+	}).Debug("Initialized SQL checkpoint store")
+
+	return &sqlCheckpointStore{db: db, driver: driver, key: key}, nil
+}
+
+func (s *sqlCheckpointStore) Load(ctx context.Context) (string, error) {
+	query := "SELECT continuation_token FROM fga_checkpoints WHERE checkpoint_key = $1"
+	if s.driver == "sqlite3" {
+		query = "SELECT continuation_token FROM fga_checkpoints WHERE checkpoint_key = ?"
+	}
+
+	var token string
+	err := s.db.QueryRowContext(ctx, query, s.key).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return token, nil
+}
+
+func (s *sqlCheckpointStore) Save(ctx context.Context, token string) error {
+	query := `INSERT INTO fga_checkpoints (checkpoint_key, continuation_token, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (checkpoint_key) DO UPDATE SET continuation_token = EXCLUDED.continuation_token, updated_at = NOW()`
+	if s.driver == "sqlite3" {
+		query = `INSERT INTO fga_checkpoints (checkpoint_key, continuation_token, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (checkpoint_key) DO UPDATE SET continuation_token = excluded.continuation_token, updated_at = CURRENT_TIMESTAMP`
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, s.key, token); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlCheckpointStore) Type() string {
+	if s.driver == "sqlite3" {
+		return "sqlite"
+	}
+	return s.driver
+}
+
+func (s *sqlCheckpointStore) Close() error {
+	return s.db.Close()
+}
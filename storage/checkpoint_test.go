@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewCheckpointStore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		cfg      CheckpointConfig
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "default is memory",
+			cfg:      CheckpointConfig{},
+			wantType: "memory",
+		},
+		{
+			name:     "file",
+			cfg:      CheckpointConfig{Type: "file", DSN: filepath.Join(dir, "nested", "token")},
+			wantType: "file",
+		},
+		{
+			name:     "sqlite",
+			cfg:      CheckpointConfig{Type: "sqlite", DSN: filepath.Join(dir, "checkpoints.db")},
+			wantType: "sqlite",
+		},
+		{
+			name:    "file without path",
+			cfg:     CheckpointConfig{Type: "file"},
+			wantErr: true,
+		},
+		{
+			name:    "postgres without dsn",
+			cfg:     CheckpointConfig{Type: "postgres"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			cfg:     CheckpointConfig{Type: "etcd", DSN: "localhost:2379"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewCheckpointStore(tt.cfg, "openfga:store123", logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCheckpointStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer store.Close()
+
+			if store.Type() != tt.wantType {
+				t.Errorf("Type() = %s, want %s", store.Type(), tt.wantType)
+			}
+		})
+	}
+}
+
+func TestCheckpointStore_Persistence(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	dir := t.TempDir()
+
+	configs := map[string]CheckpointConfig{
+		"file":   {Type: "file", DSN: filepath.Join(dir, "token")},
+		"sqlite": {Type: "sqlite", DSN: filepath.Join(dir, "checkpoints.db")},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			store, err := NewCheckpointStore(cfg, "openfga:store123", logger)
+			if err != nil {
+				t.Fatalf("NewCheckpointStore() error = %v", err)
+			}
+
+			token, err := store.Load(ctx)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if token != "" {
+				t.Errorf("Expected empty token initially, got %s", token)
+			}
+
+			if err := store.Save(ctx, "token-1"); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := store.Save(ctx, "token-2"); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			store.Close()
+
+			// A new store over the same location simulates a restart
+			reopened, err := NewCheckpointStore(cfg, "openfga:store123", logger)
+			if err != nil {
+				t.Fatalf("NewCheckpointStore() reopen error = %v", err)
+			}
+			defer reopened.Close()
+
+			token, err = reopened.Load(ctx)
+			if err != nil {
+				t.Fatalf("Load() after reopen error = %v", err)
+			}
+			if token != "token-2" {
+				t.Errorf("Expected token-2 after reopen, got %s", token)
+			}
+		})
+	}
+}
+
+func TestSQLCheckpointStore_KeysAreIndependent(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "checkpoints.db")
+
+	first, err := NewCheckpointStore(CheckpointConfig{Type: "sqlite", DSN: dsn}, "openfga:store-a", logger)
+	if err != nil {
+		t.Fatalf("NewCheckpointStore() error = %v", err)
+	}
+	defer first.Close()
+
+	second, err := NewCheckpointStore(CheckpointConfig{Type: "sqlite", DSN: dsn}, "openfga:store-b", logger)
+	if err != nil {
+		t.Fatalf("NewCheckpointStore() error = %v", err)
+	}
+	defer second.Close()
+
+	if err := first.Save(ctx, "token-a"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	token, err := second.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if token != "" {
+		t.Errorf("Expected store-b checkpoint to be empty, got %s", token)
+	}
+}
+
+func TestFileCheckpointStore_NoTemporaryFilesLeft(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newFileCheckpointStore(filepath.Join(dir, "token"))
+	if err != nil {
+		t.Fatalf("newFileCheckpointStore() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.Save(context.Background(), "token"); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the checkpoint file, found %d entries", len(entries))
+	}
+}
+
+func TestOpenFGAAdapter_CheckpointSurvivesRestart(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	ctx := context.Background()
+	cfg := CheckpointConfig{Type: "file", DSN: filepath.Join(t.TempDir(), "token")}
+
+	checkpoint, err := NewCheckpointStore(cfg, "openfga:target", logger)
+	if err != nil {
+		t.Fatalf("NewCheckpointStore() error = %v", err)
+	}
+	adapter := &OpenFGAAdapter{logger: logger, checkpoint: checkpoint}
+
+	if err := adapter.SaveContinuationToken(ctx, "resume-here"); err != nil {
+		t.Fatalf("SaveContinuationToken() error = %v", err)
+	}
+	adapter.Close()
+
+	checkpoint, err = NewCheckpointStore(cfg, "openfga:target", logger)
+	if err != nil {
+		t.Fatalf("NewCheckpointStore() error = %v", err)
+	}
+	restarted := &OpenFGAAdapter{logger: logger, checkpoint: checkpoint}
+	defer restarted.Close()
+
+	token, err := restarted.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "resume-here" {
+		t.Errorf("Expected token resume-here after restart, got %s", token)
+	}
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+// skipIfNoClickHouse skips the test if ClickHouse is not available
+func skipIfNoClickHouse(t *testing.T) string {
+	// Check if ClickHouse environment variables are set
+	dbHost := os.Getenv("CLICKHOUSE_HOST")
+	dbPort := os.Getenv("CLICKHOUSE_PORT")
+	dbUser := os.Getenv("CLICKHOUSE_USER")
+	dbPassword := os.Getenv("CLICKHOUSE_PASSWORD")
+	dbName := os.Getenv("CLICKHOUSE_DB")
+
+	// Use defaults if not set
+	if dbHost == "" {
+		dbHost = "localhost"
+	}
+	if dbPort == "" {
+		dbPort = "9000"
+	}
+	if dbUser == "" {
+		dbUser = "default"
+	}
+	if dbName == "" {
+		dbName = "openfga_sync_test"
+	}
+
+	// Try to connect to ClickHouse
+	options, err := clickhouse.ParseDSN(fmt.Sprintf("clickhouse://%s:%s@%s:%s/default?dial_timeout=2s", dbUser, dbPassword, dbHost, dbPort))
+	if err != nil {
+		t.Skipf("ClickHouse not available: %v", err)
+	}
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		t.Skipf("ClickHouse not available: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if err := conn.Ping(ctx); err != nil {
+		t.Skipf("ClickHouse not available: %v", err)
+	}
+
+	// Start every test from an empty database
+	if err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+dbName); err != nil {
+		t.Fatalf("Failed to drop test database: %v", err)
+	}
+	if err := conn.Exec(ctx, "CREATE DATABASE "+dbName); err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	return fmt.Sprintf("clickhouse://%s:%s@%s:%s/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
+}
+
+func TestClickHouseAdapter_WriteChanges(t *testing.T) {
+	dsn := skipIfNoClickHouse(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewClickHouseAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+			Condition:  `{"name":"in_office_hours"}`,
+		},
+		{
+			Operation:  "TUPLE_OPERATION_DELETE",
+			ObjectType: "folder",
+			ObjectID:   "eng",
+			Relation:   "editor",
+			UserType:   "user",
+			UserID:     "bob",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("WriteChanges() error = %v", err)
+	}
+	if err := adapter.ApplyChanges(ctx, changes); err == nil {
+		t.Error("Expected ApplyChanges to fail in changelog mode")
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["changelog_entries"] != int64(2) {
+		t.Errorf("Expected 2 changelog entries, got %v", stats["changelog_entries"])
+	}
+
+	var condition *string
+	err = adapter.conn.QueryRow(ctx, "SELECT condition FROM fga_changelog WHERE object_type = 'document'").Scan(&condition)
+	if err != nil {
+		t.Fatalf("Failed to query condition: %v", err)
+	}
+	if condition == nil || *condition != `{"name":"in_office_hours"}` {
+		t.Errorf("Expected the condition to be stored, got %v", condition)
+	}
+}
+
+func TestClickHouseAdapter_ApplyChanges(t *testing.T) {
+	dsn := skipIfNoClickHouse(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewClickHouseAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	changes := []fetcher.ChangeEvent{
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "doc123", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "doc123", Relation: "editor", UserType: "user", UserID: "bob", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_DELETE", ObjectType: "document", ObjectID: "doc123", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now.Add(time.Millisecond)},
+	}
+
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+
+	// Replaying the batch, as after a crash before the token was saved, must
+	// not resurrect the deleted tuple
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("ApplyChanges() replay error = %v", err)
+	}
+
+	var userID, relation string
+	err = adapter.conn.QueryRow(ctx, "SELECT user_id, relation FROM fga_tuples FINAL WHERE is_deleted = 0").Scan(&userID, &relation)
+	if err != nil {
+		t.Fatalf("Failed to query tuple details: %v", err)
+	}
+	if userID != "bob" || relation != "editor" {
+		t.Errorf("Expected bob as editor, got %s as %s", userID, relation)
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["current_tuples"] != int64(1) {
+		t.Errorf("Expected 1 current tuple, got %v", stats["current_tuples"])
+	}
+}
+
+func TestClickHouseAdapter_ContinuationToken(t *testing.T) {
+	dsn := skipIfNoClickHouse(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewClickHouseAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "" {
+		t.Errorf("Expected empty token, got %s", token)
+	}
+
+	for _, token := range []string{"token-1", "token-2"} {
+		if err := adapter.SaveContinuationToken(ctx, token); err != nil {
+			t.Fatalf("SaveContinuationToken() error = %v", err)
+		}
+	}
+
+	// Store and object type cursors are tracked separately
+	view := adapter.ForStore("store-a").(*ClickHouseAdapter).ForObjectType("document")
+	if err := view.SaveContinuationToken(ctx, "document-token"); err != nil {
+		t.Fatalf("SaveContinuationToken() error = %v", err)
+	}
+
+	if token, _ := adapter.GetLastContinuationToken(ctx); token != "token-2" {
+		t.Errorf("Expected token-2, got %s", token)
+	}
+	if token, _ := view.GetLastContinuationToken(ctx); token != "document-token" {
+		t.Errorf("Expected document-token, got %s", token)
+	}
+}
@@ -0,0 +1,432 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFilePrefix    = "changes"
+	defaultFileMaxSizeMB = 100
+)
+
+// FileAdapter implements StorageAdapter by appending change events to JSON
+// Lines files (one JSON-encoded change per line) in a directory. Files are
+// rotated by size or age at batch boundaries and optionally gzipped once
+// closed. Every batch is fsynced before the continuation token is saved to a
+// sidecar checkpoint file, so a crash can only repeat a batch, never lose one.
+type FileAdapter struct {
+	mu         sync.Mutex
+	dir        string
+	prefix     string
+	maxSize    int64
+	maxAge     time.Duration
+	compress   bool
+	file       *os.File
+	writer     *bufio.Writer
+	size       int64
+	opened     time.Time
+	rotations  int
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	lastToken  string
+	checkpoint CheckpointStore
+}
+
+// FileConfig represents the configuration for the file adapter
+type FileConfig struct {
+	Dir        string `json:"dir"`
+	Prefix     string `json:"prefix,omitempty"`      // File name prefix, defaults to "changes"
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"` // Rotate once a file reaches this size, defaults to 100
+	MaxAge     string `json:"max_age,omitempty"`     // Rotate once a file is this old, e.g. "1h"; no age limit if empty
+	Gzip       bool   `json:"gzip,omitempty"`        // Compress files once rotated
+	Checkpoint string `json:"checkpoint,omitempty"`  // Sidecar token file, defaults to <dir>/<prefix>.checkpoint
+}
+
+// NewFileAdapter creates a new JSON Lines file storage adapter
+func NewFileAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*FileAdapter, error) {
+	cfg, err := parseFileDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file DSN: %w", err)
+	}
+
+	var maxAge time.Duration
+	if cfg.MaxAge != "" {
+		maxAge, err = time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age format: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", cfg.Dir, err)
+	}
+
+	checkpoint, err := newFileCheckpointStore(cfg.Checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	adapter := &FileAdapter{
+		dir:        cfg.Dir,
+		prefix:     cfg.Prefix,
+		maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		maxAge:     maxAge,
+		compress:   cfg.Gzip,
+		logger:     logger,
+		mode:       mode,
+		checkpoint: checkpoint,
+	}
+
+	// Files left open by a previous run are complete up to their last fsync
+	if err := adapter.recoverFiles(); err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"dir":          cfg.Dir,
+		"prefix":       cfg.Prefix,
+		"max_size_mb":  cfg.MaxSizeMB,
+		"max_age":      maxAge,
+		"gzip":         cfg.Gzip,
+		"checkpoint":   cfg.Checkpoint,
+		"storage_mode": mode,
+	}).Info("Successfully created file storage adapter")
+
+	return adapter, nil
+}
+
+// parseFileDSN parses the file DSN configuration string
+// Supports two formats:
+// 1. Simple: the output directory (e.g., "/var/log/openfga-sync")
+// 2. JSON: {"dir":"/var/log/openfga-sync","max_size_mb":100,"max_age":"24h","gzip":true}
+func parseFileDSN(dsn string) (*FileConfig, error) {
+	var cfg FileConfig
+
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+	} else {
+		cfg.Dir = strings.TrimPrefix(dsn, "file://")
+	}
+
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("DSN must contain a non-empty directory")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultFilePrefix
+	}
+	if strings.ContainsAny(cfg.Prefix, `/\`) {
+		return nil, fmt.Errorf("prefix must not contain path separators")
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultFileMaxSizeMB
+	}
+	if cfg.Checkpoint == "" {
+		cfg.Checkpoint = filepath.Join(cfg.Dir, cfg.Prefix+".checkpoint")
+	}
+
+	return &cfg, nil
+}
+
+// WriteChanges appends a batch of change events (changelog mode)
+func (f *FileAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return f.appendChanges(changes)
+}
+
+// ApplyChanges appends a batch of change events (stateful mode). The files
+// are an audit log, so state is rebuilt from the same events.
+func (f *FileAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return f.appendChanges(changes)
+}
+
+// appendChanges writes one line per change and fsyncs the file
+func (f *FileAdapter) appendChanges(changes []fetcher.ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Rotate at batch boundaries so a batch is never split across files
+	if f.file != nil && (f.size >= f.maxSize || (f.maxAge > 0 && time.Since(f.opened) >= f.maxAge)) {
+		if err := f.closeFile(); err != nil {
+			return err
+		}
+		f.rotations++
+	}
+	if f.file == nil {
+		if err := f.openFile(); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		line, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change event: %w", err)
+		}
+		line = append(line, '\n')
+		if _, err := f.writer.Write(line); err != nil {
+			return fmt.Errorf("failed to write change to %s: %w", f.file.Name(), err)
+		}
+		f.size += int64(len(line))
+	}
+
+	if err := f.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush %s: %w", f.file.Name(), err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", f.file.Name(), err)
+	}
+
+	f.logger.WithFields(logrus.Fields{
+		"file":    f.file.Name(),
+		"changes": len(changes),
+	}).Debug("Appended changes to file")
+
+	return nil
+}
+
+// openFile starts a new file named after the current time
+func (f *FileAdapter) openFile() error {
+	now := time.Now().UTC()
+	for {
+		path := filepath.Join(f.dir, f.prefix+"-"+now.Format("20060102T150405.000000000Z")+".jsonl")
+
+		// Never reuse the name of an earlier file, compressed or not
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			if _, err = os.Stat(path + ".gz"); err == nil {
+				file.Close()
+				os.Remove(path)
+				err = os.ErrExist
+			} else {
+				err = nil
+			}
+		}
+		if errors.Is(err, os.ErrExist) {
+			now = now.Add(time.Nanosecond)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+
+		f.file = file
+		break
+	}
+
+	f.writer = bufio.NewWriter(f.file)
+	f.size = 0
+	f.opened = now
+	return nil
+}
+
+// closeFile closes the current file and compresses it when gzip is enabled
+func (f *FileAdapter) closeFile() error {
+	path := f.file.Name()
+	flushErr := f.writer.Flush()
+	closeErr := f.file.Close()
+	f.file, f.writer = nil, nil
+
+	if flushErr != nil {
+		return fmt.Errorf("failed to flush %s: %w", path, flushErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close %s: %w", path, closeErr)
+	}
+
+	if f.compress {
+		return gzipFile(path)
+	}
+	return nil
+}
+
+// recoverFiles trims a torn last line from files of a previous run and
+// compresses them when gzip is enabled
+func (f *FileAdapter) recoverFiles() error {
+	paths, err := filepath.Glob(filepath.Join(f.dir, f.prefix+"-*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := trimPartialLine(path); err != nil {
+			return err
+		}
+		if f.compress {
+			if err := gzipFile(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trimPartialLine truncates a file after its last newline, dropping a line
+// that was only partially written before a crash. Its batch was never
+// checkpointed and is written again.
+func trimPartialLine(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	// Scan backwards for the last newline
+	end := info.Size()
+	buf := make([]byte, 64*1024)
+	for offset := end; offset > 0; {
+		n := int64(len(buf))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := file.ReadAt(buf[:n], offset); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = offset + int64(i) + 1
+			break
+		}
+		end = offset
+	}
+
+	if end == info.Size() {
+		return nil
+	}
+	if err := file.Truncate(end); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", path, err)
+	}
+	return file.Sync()
+}
+
+// gzipFile replaces path with path.gz
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	tmpPath := path + ".gz.tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+	defer os.Remove(tmpPath)
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to sync %s: %w", tmpPath, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmpPath, err)
+	}
+	return os.Remove(path)
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (f *FileAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := f.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+
+	f.mu.Lock()
+	f.lastToken = token
+	f.mu.Unlock()
+	return token, nil
+}
+
+// SaveContinuationToken saves the continuation token to the sidecar file.
+// Batches are fsynced when appended, so the token never gets ahead of them.
+func (f *FileAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if err := f.checkpoint.Save(ctx, token); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+
+	f.mu.Lock()
+	f.lastToken = token
+	f.mu.Unlock()
+	f.logger.WithField("token", token).Debug("Saved continuation token")
+	return nil
+}
+
+// Close closes (and compresses) the current file and the checkpoint store
+func (f *FileAdapter) Close() error {
+	f.logger.Info("Closing file adapter")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var fileErr error
+	if f.file != nil {
+		fileErr = f.closeFile()
+	}
+	checkpointErr := f.checkpoint.Close()
+	if fileErr != nil {
+		return fileErr
+	}
+	return checkpointErr
+}
+
+// GetStats returns statistics about the file adapter
+func (f *FileAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"adapter_type":    "file",
+		"dir":             f.dir,
+		"storage_mode":    string(f.mode),
+		"last_token":      f.lastToken,
+		"checkpoint_type": f.checkpoint.Type(),
+		"rotations":       f.rotations,
+		"gzip":            f.compress,
+	}
+	if f.file != nil {
+		stats["current_file"] = f.file.Name()
+		stats["current_size"] = f.size
+	}
+	return stats, nil
+}
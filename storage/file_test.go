@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+// readJSONLines returns the change events in a plain or gzipped JSON Lines file
+func readJSONLines(t *testing.T, path string) []fetcher.ChangeEvent {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var scanner *bufio.Scanner
+	if filepath.Ext(path) == ".gz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Failed to read gzip %s: %v", path, err)
+		}
+		scanner = bufio.NewScanner(gz)
+	} else {
+		scanner = bufio.NewScanner(file)
+	}
+
+	var changes []fetcher.ChangeEvent
+	for scanner.Scan() {
+		var change fetcher.ChangeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &change); err != nil {
+			t.Fatalf("Invalid line in %s: %v", path, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func TestParseFileDSN(t *testing.T) {
+	cfg, err := parseFileDSN("/var/log/openfga-sync")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if cfg.Prefix != "changes" || cfg.MaxSizeMB != 100 || cfg.Checkpoint != "/var/log/openfga-sync/changes.checkpoint" {
+		t.Errorf("Unexpected simple DSN config: %+v", cfg)
+	}
+
+	cfg, err = parseFileDSN(`{"dir":"/data","prefix":"audit","max_age":"1h","gzip":true,"checkpoint":"/state/token"}`)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON DSN: %v", err)
+	}
+	if cfg.Prefix != "audit" || !cfg.Gzip || cfg.Checkpoint != "/state/token" {
+		t.Errorf("Unexpected JSON DSN config: %+v", cfg)
+	}
+
+	for _, dsn := range []string{"", `{"gzip":true}`, `{"dir":"/data","prefix":"a/b"}`} {
+		if _, err := parseFileDSN(dsn); err == nil {
+			t.Errorf("Expected error for DSN %q", dsn)
+		}
+	}
+}
+
+func TestFileAdapter_RotatesAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// Every batch after the first rotates the file
+	dsn := fmt.Sprintf(`{"dir":%q,"max_age":"1ns","gzip":true}`, dir)
+	adapter, err := NewFileAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create file adapter: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		changes := []fetcher.ChangeEvent{
+			{ObjectType: "document", ObjectID: fmt.Sprintf("doc-%d", i), Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_write"},
+			{ObjectType: "document", ObjectID: fmt.Sprintf("doc-%d", i), Relation: "editor", UserType: "user", UserID: "bob", ChangeType: "tuple_write"},
+		}
+		if err := adapter.WriteChanges(ctx, changes); err != nil {
+			t.Fatalf("Failed to write batch %d: %v", i, err)
+		}
+		if err := adapter.SaveContinuationToken(ctx, fmt.Sprintf("token-%d", i)); err != nil {
+			t.Fatalf("Failed to save continuation token: %v", err)
+		}
+	}
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Failed to close adapter: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "changes-*.jsonl.gz"))
+	sort.Strings(files)
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 gzipped files, got %v", files)
+	}
+	for i, file := range files {
+		changes := readJSONLines(t, file)
+		if len(changes) != 2 || changes[0].ObjectID != fmt.Sprintf("doc-%d", i) {
+			t.Errorf("Unexpected changes in %s: %+v", file, changes)
+		}
+	}
+	if plain, _ := filepath.Glob(filepath.Join(dir, "*.jsonl")); len(plain) != 0 {
+		t.Errorf("Expected no uncompressed files, got %v", plain)
+	}
+
+	token, err := os.ReadFile(filepath.Join(dir, "changes.checkpoint"))
+	if err != nil || string(token) != "token-2\n" {
+		t.Errorf("Expected token-2 in the sidecar checkpoint, got %q (%v)", token, err)
+	}
+}
+
+func TestFileAdapter_RecoversTornLine(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// A previous run crashed halfway through a line
+	leftover := filepath.Join(dir, "changes-20250101T000000.000000000Z.jsonl")
+	content := `{"object_type":"document","object_id":"roadmap"}` + "\n" + `{"object_type":"docu`
+	if err := os.WriteFile(leftover, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write leftover file: %v", err)
+	}
+
+	adapter, err := NewFileAdapter(dir, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create file adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	changes := readJSONLines(t, leftover)
+	if len(changes) != 1 || changes[0].ObjectID != "roadmap" {
+		t.Errorf("Expected the torn line to be dropped, got %+v", changes)
+	}
+
+	if err := adapter.ApplyChanges(context.Background(), []fetcher.ChangeEvent{{ObjectType: "document", ObjectID: "budget"}}); err != nil {
+		t.Fatalf("Failed to apply changes: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "changes-*.jsonl"))
+	if len(files) != 2 {
+		t.Errorf("Expected a new file next to the leftover one, got %v", files)
+	}
+}
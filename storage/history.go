@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryTuple is a tuple together with the interval during which it existed.
+// ValidTo is nil while the tuple still exists.
+type HistoryTuple struct {
+	ObjectType string     `json:"object_type"`
+	ObjectID   string     `json:"object_id"`
+	Relation   string     `json:"relation"`
+	UserType   string     `json:"user_type"`
+	UserID     string     `json:"user_id"`
+	Condition  string     `json:"condition,omitempty"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to,omitempty"`
+}
+
+// AsOfQuery selects the tuples that existed at a point in time. Empty filter
+// fields match any value.
+type AsOfQuery struct {
+	At         time.Time
+	ObjectType string
+	ObjectID   string
+	Relation   string
+	UserType   string
+	UserID     string
+}
+
+// TuplesAsOf reconstructs the tuple set at query.At from an adapter running in
+// history mode, e.g. to answer "who had access to document:roadmap on June 1"
+func TuplesAsOf(ctx context.Context, adapter StorageAdapter, query AsOfQuery) ([]HistoryTuple, error) {
+	historyAdapter, ok := adapter.(HistoryAdapter)
+	if !ok {
+		return nil, fmt.Errorf("storage adapter does not support as-of queries")
+	}
+	if query.At.IsZero() {
+		return nil, fmt.Errorf("as-of query requires a point in time")
+	}
+	return historyAdapter.TuplesAsOf(ctx, query)
+}
+
+// historyAsOfSQL builds the as-of query over the history table. placeholder
+// renders the n-th bind parameter in the driver's syntax, condition is the
+// quoted condition column and at is the query time as the driver expects it.
+// An interval covers valid_from inclusive to valid_to exclusive.
+func historyAsOfSQL(table, storeID string, query AsOfQuery, at interface{}, placeholder func(int) string, condition string) (string, []interface{}) {
+	args := []interface{}{storeID, at, at}
+	where := []string{
+		"store_id = " + placeholder(1),
+		"valid_from <= " + placeholder(2),
+		"(valid_to IS NULL OR valid_to > " + placeholder(3) + ")",
+	}
+
+	for _, filter := range []struct{ column, value string }{
+		{"object_type", query.ObjectType},
+		{"object_id", query.ObjectID},
+		{"relation", query.Relation},
+		{"user_type", query.UserType},
+		{"user_id", query.UserID},
+	} {
+		if filter.value != "" {
+			args = append(args, filter.value)
+			where = append(where, filter.column+" = "+placeholder(len(args)))
+		}
+	}
+
+	return fmt.Sprintf(`SELECT object_type, object_id, relation, user_type, user_id, %s, valid_from, valid_to
+		FROM %s
+		WHERE %s
+		ORDER BY object_type, object_id, relation, user_type, user_id`, condition, table, strings.Join(where, " AND ")), args
+}
+
+// scanHistoryTuples reads the rows of a historyAsOfSQL query
+func scanHistoryTuples(rows *sql.Rows) ([]HistoryTuple, error) {
+	var tuples []HistoryTuple
+	for rows.Next() {
+		var tuple HistoryTuple
+		var condition sql.NullString
+		var validTo sql.NullTime
+		if err := rows.Scan(
+			&tuple.ObjectType,
+			&tuple.ObjectID,
+			&tuple.Relation,
+			&tuple.UserType,
+			&tuple.UserID,
+			&condition,
+			&tuple.ValidFrom,
+			&validTo,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan history tuple: %w", err)
+		}
+		tuple.Condition = condition.String
+		if validTo.Valid {
+			tuple.ValidTo = &validTo.Time
+		}
+		tuples = append(tuples, tuple)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history tuples: %w", err)
+	}
+	return tuples, nil
+}
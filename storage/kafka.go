@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/sirupsen/logrus"
+)
+
+// KafkaAdapter implements StorageAdapter by publishing change events to a
+// Kafka topic. Each event is keyed by its object, so all changes to an object
+// land on the same partition in order. The continuation token lives in a
+// checkpoint store, which the configuration requires to be persistent, and is
+// saved only after the batch has been acknowledged (at-least-once delivery).
+type KafkaAdapter struct {
+	writer     *kafka.Writer
+	topic      string
+	brokers    []string
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	lastToken  string
+	checkpoint CheckpointStore
+}
+
+// KafkaConfig represents the configuration for the Kafka adapter
+type KafkaConfig struct {
+	Brokers      []string         `json:"brokers"`
+	Topic        string           `json:"topic"`
+	ClientID     string           `json:"client_id,omitempty"`
+	BatchSize    int              `json:"batch_size,omitempty"`
+	BatchTimeout string           `json:"batch_timeout,omitempty"` // String format like "10ms"
+	WriteTimeout string           `json:"write_timeout,omitempty"` // String format like "10s"
+	TLS          bool             `json:"tls,omitempty"`
+	SASL         KafkaSASLConfig  `json:"sasl,omitempty"`
+	Checkpoint   CheckpointConfig `json:"checkpoint,omitempty"`
+}
+
+// KafkaSASLConfig contains SASL/PLAIN credentials for the Kafka adapter
+type KafkaSASLConfig struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// NewKafkaAdapter creates a new Kafka storage adapter
+func NewKafkaAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*KafkaAdapter, error) {
+	cfg, err := parseKafkaDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Kafka DSN: %w", err)
+	}
+
+	batchSize := 100
+	if cfg.BatchSize > 0 {
+		batchSize = cfg.BatchSize
+	}
+
+	// Writes are synchronous, so a partially filled batch only waits this long
+	batchTimeout := 10 * time.Millisecond
+	if cfg.BatchTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.BatchTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch_timeout format: %w", err)
+		}
+		batchTimeout = parsed
+	}
+
+	writeTimeout := 10 * time.Second
+	if cfg.WriteTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.WriteTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid write_timeout format: %w", err)
+		}
+		writeTimeout = parsed
+	}
+
+	transport := &kafka.Transport{ClientID: "openfga-sync"}
+	if cfg.ClientID != "" {
+		transport.ClientID = cfg.ClientID
+	}
+	if cfg.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if cfg.SASL.Username != "" {
+		transport.SASL = plain.Mechanism{Username: cfg.SASL.Username, Password: cfg.SASL.Password}
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    batchSize,
+		BatchTimeout: batchTimeout,
+		WriteTimeout: writeTimeout,
+		Transport:    transport,
+	}
+
+	checkpoint, err := NewCheckpointStore(cfg.Checkpoint, "kafka:"+cfg.Topic, logger)
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	adapter := &KafkaAdapter{
+		writer:     writer,
+		topic:      cfg.Topic,
+		brokers:    cfg.Brokers,
+		logger:     logger,
+		mode:       mode,
+		checkpoint: checkpoint,
+	}
+
+	logger.WithFields(logrus.Fields{
+		"brokers":         cfg.Brokers,
+		"topic":           cfg.Topic,
+		"storage_mode":    mode,
+		"batch_size":      batchSize,
+		"checkpoint_type": checkpoint.Type(),
+	}).Info("Successfully created Kafka storage adapter")
+
+	return adapter, nil
+}
+
+// parseKafkaDSN parses the Kafka DSN configuration string
+// Supports two formats:
+// 1. Simple: "brokers/topic" (e.g., "kafka-1:9092,kafka-2:9092/openfga-changes")
+// 2. JSON: {"brokers":["kafka-1:9092"],"topic":"openfga-changes","checkpoint":{"type":"file","dsn":"/var/lib/openfga-sync/token"}}
+func parseKafkaDSN(dsn string) (*KafkaConfig, error) {
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		var cfg KafkaConfig
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+
+		// Validate required fields
+		if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+			return nil, fmt.Errorf("JSON DSN must contain non-empty 'brokers' and 'topic' fields")
+		}
+
+		return &cfg, nil
+	}
+
+	// Simple format: brokers/topic
+	dsn = strings.TrimPrefix(dsn, "kafka://")
+	lastSlashIndex := strings.LastIndex(dsn, "/")
+	if lastSlashIndex == -1 || lastSlashIndex == len(dsn)-1 {
+		return nil, fmt.Errorf("invalid DSN format, expected brokers/topic")
+	}
+
+	var brokers []string
+	for _, broker := range strings.Split(dsn[:lastSlashIndex], ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("invalid DSN format, at least one broker is required")
+	}
+
+	return &KafkaConfig{
+		Brokers: brokers,
+		Topic:   dsn[lastSlashIndex+1:],
+	}, nil
+}
+
+// WriteChanges publishes a batch of change events (changelog mode)
+func (k *KafkaAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return k.publish(ctx, changes)
+}
+
+// ApplyChanges publishes a batch of change events (stateful mode). A topic is
+// an event stream, so consumers rebuild state from the same events.
+func (k *KafkaAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return k.publish(ctx, changes)
+}
+
+// publish writes one message per change and returns once every message has
+// been acknowledged by all in-sync replicas
+func (k *KafkaAdapter) publish(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, 0, len(changes))
+	for _, change := range changes {
+		value, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change event: %w", err)
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(kafkaMessageKey(change)),
+			Value: value,
+			Time:  change.Timestamp,
+			Headers: []kafka.Header{
+				{Key: "change_type", Value: []byte(change.ChangeType)},
+			},
+		})
+	}
+
+	if err := k.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to publish changes to topic %s: %w", k.topic, err)
+	}
+
+	k.logger.WithFields(logrus.Fields{
+		"topic":    k.topic,
+		"messages": len(messages),
+	}).Debug("Published changes to Kafka")
+
+	return nil
+}
+
+// kafkaMessageKey returns the partitioning key of a change: its object
+func kafkaMessageKey(change fetcher.ChangeEvent) string {
+	return change.ObjectType + ":" + change.ObjectID
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (k *KafkaAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := k.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+	k.lastToken = token
+	return token, nil
+}
+
+// SaveContinuationToken saves the continuation token for resuming processing
+func (k *KafkaAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if err := k.checkpoint.Save(ctx, token); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	k.lastToken = token
+	k.logger.WithField("token", token).Debug("Saved continuation token")
+	return nil
+}
+
+// Close flushes pending messages and closes the writer and checkpoint store
+func (k *KafkaAdapter) Close() error {
+	k.logger.Info("Closing Kafka adapter")
+	writerErr := k.writer.Close()
+	checkpointErr := k.checkpoint.Close()
+	if writerErr != nil {
+		return fmt.Errorf("failed to close Kafka writer: %w", writerErr)
+	}
+	return checkpointErr
+}
+
+// GetStats returns statistics about the Kafka adapter
+func (k *KafkaAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	writerStats := k.writer.Stats()
+	return map[string]interface{}{
+		"adapter_type":    "kafka",
+		"topic":           k.topic,
+		"brokers":         k.brokers,
+		"storage_mode":    string(k.mode),
+		"last_token":      k.lastToken,
+		"checkpoint_type": k.checkpoint.Type(),
+		"messages":        writerStats.Messages,
+		"writes":          writerStats.Writes,
+		"errors":          writerStats.Errors,
+	}, nil
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeKafkaRecord is a record accepted by fakeKafkaBroker
+type fakeKafkaRecord struct {
+	partition int32
+	key       string
+	value     []byte
+	headers   map[string]string
+}
+
+// fakeKafkaBroker is an in-process, single-node Kafka that speaks just enough
+// of the wire protocol (ApiVersions, Metadata, Produce) for a producer
+type fakeKafkaBroker struct {
+	t          *testing.T
+	listener   net.Listener
+	topic      string
+	partitions int32
+
+	mu      sync.Mutex
+	records []fakeKafkaRecord
+}
+
+func newFakeKafkaBroker(t *testing.T, topic string, partitions int32) *fakeKafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	broker := &fakeKafkaBroker{t: t, listener: listener, topic: topic, partitions: partitions}
+	go broker.serve()
+	t.Cleanup(func() { listener.Close() })
+	return broker
+}
+
+func (b *fakeKafkaBroker) addr() string {
+	return b.listener.Addr().String()
+}
+
+func (b *fakeKafkaBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeKafkaBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		apiVersion, correlationID, _, msg, err := protocol.ReadRequest(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				b.t.Logf("fake kafka: failed to read request: %v", err)
+			}
+			return
+		}
+
+		var response protocol.Message
+		switch req := msg.(type) {
+		case *apiversions.Request:
+			response = &apiversions.Response{ApiKeys: []apiversions.ApiKeyResponse{
+				{ApiKey: int16(protocol.Produce), MinVersion: 0, MaxVersion: 7},
+				{ApiKey: int16(protocol.Metadata), MinVersion: 0, MaxVersion: 8},
+				{ApiKey: int16(protocol.ApiVersions), MinVersion: 0, MaxVersion: 2},
+			}}
+		case *metadata.Request:
+			response = b.metadata()
+		case *produce.Request:
+			response = b.produce(req)
+		default:
+			b.t.Logf("fake kafka: unsupported request %T", msg)
+			return
+		}
+
+		if err := protocol.WriteResponse(conn, apiVersion, correlationID, response); err != nil {
+			b.t.Logf("fake kafka: failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+func (b *fakeKafkaBroker) metadata() *metadata.Response {
+	host, portStr, _ := net.SplitHostPort(b.addr())
+	port, _ := strconv.Atoi(portStr)
+
+	partitions := make([]metadata.ResponsePartition, b.partitions)
+	for i := range partitions {
+		partitions[i] = metadata.ResponsePartition{
+			PartitionIndex: int32(i),
+			LeaderID:       1,
+			ReplicaNodes:   []int32{1},
+			IsrNodes:       []int32{1},
+		}
+	}
+
+	return &metadata.Response{
+		Brokers:      []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: int32(port)}},
+		ControllerID: 1,
+		Topics:       []metadata.ResponseTopic{{Name: b.topic, Partitions: partitions}},
+	}
+}
+
+func (b *fakeKafkaBroker) produce(req *produce.Request) *produce.Response {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	response := &produce.Response{}
+	for _, topic := range req.Topics {
+		responseTopic := produce.ResponseTopic{Topic: topic.Topic}
+		for _, partition := range topic.Partitions {
+			baseOffset := int64(len(b.records))
+			for {
+				record, err := partition.RecordSet.Records.ReadRecord()
+				if err != nil {
+					break
+				}
+				key, _ := protocol.ReadAll(record.Key)
+				value, _ := protocol.ReadAll(record.Value)
+				headers := make(map[string]string)
+				for _, header := range record.Headers {
+					headers[header.Key] = string(header.Value)
+				}
+				b.records = append(b.records, fakeKafkaRecord{
+					partition: partition.Partition,
+					key:       string(key),
+					value:     value,
+					headers:   headers,
+				})
+			}
+			responseTopic.Partitions = append(responseTopic.Partitions, produce.ResponsePartition{
+				Partition:  partition.Partition,
+				BaseOffset: baseOffset,
+			})
+		}
+		response.Topics = append(response.Topics, responseTopic)
+	}
+	return response
+}
+
+func (b *fakeKafkaBroker) published() []fakeKafkaRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]fakeKafkaRecord(nil), b.records...)
+}
+
+func TestParseKafkaDSN(t *testing.T) {
+	cfg, err := parseKafkaDSN("kafka://kafka-1:9092, kafka-2:9092/openfga-changes")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if len(cfg.Brokers) != 2 || cfg.Brokers[1] != "kafka-2:9092" || cfg.Topic != "openfga-changes" {
+		t.Errorf("Unexpected simple DSN config: %+v", cfg)
+	}
+
+	cfg, err = parseKafkaDSN(`{"brokers":["kafka-1:9092"],"topic":"openfga-changes","checkpoint":{"type":"file","dsn":"/tmp/token"}}`)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON DSN: %v", err)
+	}
+	if cfg.Checkpoint.Type != "file" || cfg.Topic != "openfga-changes" {
+		t.Errorf("Unexpected JSON DSN config: %+v", cfg)
+	}
+
+	for _, dsn := range []string{"kafka-1:9092", "kafka-1:9092/", "/openfga-changes", `{"brokers":[],"topic":"t"}`} {
+		if _, err := parseKafkaDSN(dsn); err == nil {
+			t.Errorf("Expected error for DSN %q", dsn)
+		}
+	}
+}
+
+func TestKafkaAdapter_PublishesKeyedChanges(t *testing.T) {
+	broker := newFakeKafkaBroker(t, "openfga-changes", 3)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dsn, _ := json.Marshal(KafkaConfig{
+		Brokers:    []string{broker.addr()},
+		Topic:      "openfga-changes",
+		Checkpoint: CheckpointConfig{Type: "file", DSN: filepath.Join(t.TempDir(), "token")},
+	})
+	adapter, err := NewKafkaAdapter(string(dsn), config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create Kafka adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	changes := []fetcher.ChangeEvent{
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_write", Timestamp: now},
+		{ObjectType: "document", ObjectID: "budget", Relation: "viewer", UserType: "user", UserID: "bob", ChangeType: "tuple_write", Timestamp: now},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "editor", UserType: "user", UserID: "anne", ChangeType: "tuple_write", Timestamp: now},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_delete", Timestamp: now},
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to publish changes: %v", err)
+	}
+	if err := adapter.SaveContinuationToken(ctx, "token-1"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Failed to close adapter: %v", err)
+	}
+
+	records := broker.published()
+	if len(records) != len(changes) {
+		t.Fatalf("Expected %d records, got %d", len(changes), len(records))
+	}
+
+	// Every change to an object lands on one partition, in order
+	var roadmap []fetcher.ChangeEvent
+	roadmapPartition := int32(-1)
+	for _, record := range records {
+		var change fetcher.ChangeEvent
+		if err := json.Unmarshal(record.value, &change); err != nil {
+			t.Fatalf("Record value is not a change event: %v", err)
+		}
+		if record.key != change.ObjectType+":"+change.ObjectID {
+			t.Errorf("Expected key %s:%s, got %q", change.ObjectType, change.ObjectID, record.key)
+		}
+		if record.headers["change_type"] != change.ChangeType {
+			t.Errorf("Expected change_type header %q, got %q", change.ChangeType, record.headers["change_type"])
+		}
+		if record.key != "document:roadmap" {
+			continue
+		}
+		if roadmapPartition == -1 {
+			roadmapPartition = record.partition
+		} else if record.partition != roadmapPartition {
+			t.Errorf("Expected document:roadmap on partition %d, got %d", roadmapPartition, record.partition)
+		}
+		roadmap = append(roadmap, change)
+	}
+	if len(roadmap) != 3 || roadmap[1].Relation != "editor" || roadmap[2].ChangeType != "tuple_delete" {
+		t.Errorf("Expected document:roadmap changes in order, got %+v", roadmap)
+	}
+
+	// The checkpoint survives a restart
+	reopened, err := NewKafkaAdapter(string(dsn), config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen Kafka adapter: %v", err)
+	}
+	defer reopened.Close()
+
+	token, err := reopened.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load continuation token: %v", err)
+	}
+	if token != "token-1" {
+		t.Errorf("Expected token-1, got %q", token)
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
+	"github.com/sirupsen/logrus"
+)
+
+// sqlTables holds the table names the postgres and sqlite adapters query,
+// placed by the backend's schema and table prefix
+type sqlTables struct {
+	syncState string
+	changelog string
+	tuples    string
+	history   string
+}
+
+// newSQLTables returns the table names placed by naming
+func newSQLTables(naming migrations.Naming) sqlTables {
+	return sqlTables{
+		syncState: naming.Table("sync_state"),
+		changelog: naming.Table("fga_changelog"),
+		tuples:    naming.Table("fga_tuples"),
+		history:   naming.Table("fga_tuple_history"),
+	}
+}
+
+// tableNaming returns where backend places its tables
+func tableNaming(backend config.BackendConfig) migrations.Naming {
+	return migrations.Naming{Schema: backend.Schema, Prefix: backend.TablePrefix}
+}
+
+// NewMigrator opens the database of an SQL backend and returns its schema
+// migrator, for managing the schema without starting an adapter. The caller
+// closes the returned database.
+func NewMigrator(backend config.BackendConfig, logger *logrus.Logger) (*migrations.Migrator, *sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch backend.Type {
+	case "postgres":
+		db, err = openPostgres(backend.DSN)
+	case "sqlite":
+		db, err = openSQLite(backend.DSN)
+	case "mysql", "mariadb":
+		db, err = openMySQL(backend.DSN)
+	default:
+		return nil, nil, fmt.Errorf("backend type %s has no schema migrations", backend.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var migrator *migrations.Migrator
+	switch backend.Type {
+	case "postgres":
+		partitioned := backend.Partitioning.Enabled && backend.Mode.HasChangelog()
+		migrator, err = newPostgresMigrator(db, tableNaming(backend), partitioned, logger)
+	case "sqlite":
+		migrator, err = newSQLiteMigrator(db, tableNaming(backend), logger)
+	default:
+		migrator, err = newMySQLMigrator(db, logger)
+	}
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return migrator, db, nil
+}
+
+// HasMigrations reports whether a backend type keeps its schema in versioned
+// migrations
+func HasMigrations(backendType string) bool {
+	switch backendType {
+	case "postgres", "sqlite", "mysql", "mariadb":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,668 @@
+// Package migrations versions the schema of the SQL storage adapters.
+//
+// Each dialect has numbered migration files embedded from its directory,
+// named NNNN_description.up.sql and NNNN_description.down.sql. Applied
+// versions are recorded in a schema_migrations table, and a database lock
+// keeps replicas that start together from applying the same migration twice.
+//
+// Migration files are text/template documents rendered with a Naming, so one
+// database can hold the tables of several pipelines. They refer to tables as
+// {{table "fga_tuples"}}, to indexes and constraints as {{name "idx_..."}},
+// to a schema-qualified index as {{qualify (name "idx_...")}}, and to the
+// schema in catalog lookups as {{schema}}.
+//
+// Some migrations are optional and only run on migrators that enable their
+// feature, like the conversion of the PostgreSQL changelog into a partitioned
+// table. They stay pending otherwise, so enabling the feature later applies
+// them.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed postgres/*.sql mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// Dialect selects the migration files and locking strategy for a database
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+// Optional migration features
+const (
+	FeaturePartitioning = "partitioning" // Range-partitioned PostgreSQL changelog
+)
+
+// optional maps the optional migrations of each dialect to their feature
+var optional = map[Dialect]map[int]string{
+	Postgres: {3: FeaturePartitioning},
+}
+
+// lockPrefix namespaces the migration lock on databases shared with other
+// applications
+const lockPrefix = "openfga-sync/"
+
+// lockTimeout bounds how long a replica waits for another one to finish
+// migrating before giving up
+const lockTimeout = 5 * time.Minute
+
+// Naming places the tables of a migration set. The zero value uses the
+// unprefixed names in the default schema.
+type Naming struct {
+	Schema string // Postgres schema; "" uses the search path
+	Prefix string // Prepended to every table, index and constraint name
+}
+
+// Name returns name with the table prefix
+func (n Naming) Name(name string) string {
+	return n.Prefix + name
+}
+
+// Qualify returns name qualified with the schema, if any
+func (n Naming) Qualify(name string) string {
+	if n.Schema == "" {
+		return name
+	}
+	return n.Schema + "." + name
+}
+
+// Table returns the prefixed, schema-qualified name of table
+func (n Naming) Table(table string) string {
+	return n.Qualify(n.Name(table))
+}
+
+// schemaExpr returns the schema as a SQL expression for catalog lookups
+func (n Naming) schemaExpr() string {
+	if n.Schema == "" {
+		return "current_schema()"
+	}
+	return "'" + n.Schema + "'"
+}
+
+// render expands the naming functions in a migration file
+func (n Naming) render(fileName, content string) (string, error) {
+	tmpl, err := template.New(fileName).Funcs(template.FuncMap{
+		"name":    n.Name,
+		"qualify": n.Qualify,
+		"table":   n.Table,
+		"schema":  n.schemaExpr,
+	}).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse migration %s: %w", fileName, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return "", fmt.Errorf("failed to render migration %s: %w", fileName, err)
+	}
+	return rendered.String(), nil
+}
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	Feature string // Set on optional migrations
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Skipped   bool // Pending optional migration whose feature is not enabled
+}
+
+// Conn runs statements on the connection holding the migration lock. Both
+// *sql.Conn and *sql.Tx satisfy it.
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Migrator applies and rolls back the migrations of one dialect
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	naming     Naming
+	logger     *logrus.Logger
+	migrations []Migration
+
+	// Baseline upgrades databases created before schema_migrations existed.
+	// It runs under the migration lock when no migration has been applied yet.
+	Baseline func(ctx context.Context, conn Conn) error
+
+	// Features enables optional migrations; Up skips the others
+	Features []string
+}
+
+// New creates a migrator for the tables placed by naming in db. MySQL
+// connections must set parseTime=true.
+func New(db *sql.DB, dialect Dialect, naming Naming, logger *logrus.Logger) (*Migrator, error) {
+	migrations, err := Load(dialect, naming)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		naming:     naming,
+		logger:     logger,
+		migrations: migrations,
+	}, nil
+}
+
+// Load returns the embedded migrations of dialect, rendered with naming and
+// ordered by version
+func Load(dialect Dialect, naming Naming) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, string(dialect))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported migration dialect: %s", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := cutDirection(fileName)
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", fileName)
+		}
+
+		content, err := files.ReadFile(path.Join(string(dialect), fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, err)
+		}
+		script, err := naming.render(fileName, string(content))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name, Feature: optional[dialect][version]}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = script
+		} else {
+			migration.Down = script
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// cutDirection splits "0001_name.up.sql" into "0001_name" and "up"
+func cutDirection(fileName string) (string, string, bool) {
+	for _, direction := range []string{"up", "down"} {
+		if base, ok := strings.CutSuffix(fileName, "."+direction+".sql"); ok {
+			return base, direction, true
+		}
+	}
+	return "", "", false
+}
+
+// Up applies every pending migration, except optional ones whose feature is
+// not enabled, and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if len(versions) == 0 && m.Baseline != nil {
+			if err := m.Baseline(ctx, conn); err != nil {
+				return fmt.Errorf("failed to upgrade unversioned schema: %w", err)
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if !m.enabled(migration) {
+				m.logger.WithFields(logrus.Fields{
+					"version": migration.Version,
+					"name":    migration.Name,
+					"feature": migration.Feature,
+				}).Debug("Skipping optional schema migration")
+				continue
+			}
+			if err := m.run(ctx, conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+			applied++
+		}
+
+		// A newer release may have migrated the database already
+		for version := range versions {
+			if m.find(version) == nil {
+				m.logger.WithField("version", version).Warn("Database has a schema migration unknown to this build")
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations and returns how many were
+// rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	rolledBack := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		ordered := make([]int, 0, len(versions))
+		for version := range versions {
+			ordered = append(ordered, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(ordered)))
+
+		for _, version := range ordered {
+			if rolledBack == steps {
+				break
+			}
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("migration %d is not known to this build", version)
+			}
+			if err := m.run(ctx, conn, *migration, migration.Down, false); err != nil {
+				return err
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, applied := versions[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   applied,
+			AppliedAt: appliedAt,
+			Skipped:   !applied && !m.enabled(migration),
+		})
+	}
+	return statuses, nil
+}
+
+// enabled reports whether Up applies migration
+func (m *Migrator) enabled(migration Migration) bool {
+	if migration.Feature == "" {
+		return true
+	}
+	for _, feature := range m.Features {
+		if feature == migration.Feature {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the migration with version, or nil
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a dedicated connection while holding the migration
+// lock. PostgreSQL and MySQL use a named advisory lock; SQLite wraps the
+// whole run in one write transaction.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	switch m.dialect {
+	case Postgres:
+		lockCtx, cancel := context.WithTimeout(ctx, lockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", advisoryLockKey(m.lockName())); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey(m.lockName())); err != nil {
+				m.logger.WithError(err).Warn("Failed to release migration lock")
+			}
+		}()
+		return fn(conn)
+
+	case MySQL:
+		var acquired sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName(), int(lockTimeout.Seconds())).Scan(&acquired)
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired.Int64 != 1 {
+			return fmt.Errorf("timed out waiting for migration lock")
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName()); err != nil {
+				m.logger.WithError(err).Warn("Failed to release migration lock")
+			}
+		}()
+		return fn(conn)
+
+	case SQLite:
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if err := fn(conn); err != nil {
+			if _, rollbackErr := conn.ExecContext(context.Background(), "ROLLBACK"); rollbackErr != nil {
+				m.logger.WithError(rollbackErr).Warn("Failed to roll back migrations")
+			}
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+			return fmt.Errorf("failed to commit migrations: %w", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported migration dialect: %s", m.dialect)
+	}
+}
+
+// lockName identifies the migration lock of this migrator's tables, so
+// pipelines sharing a database migrate independently
+func (m *Migrator) lockName() string {
+	return lockPrefix + m.naming.Table("schema_migrations")
+}
+
+// advisoryLockKey maps a lock name to a PostgreSQL advisory lock key
+func advisoryLockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
+
+// run applies one direction of migration and records it in
+// schema_migrations. PostgreSQL runs each migration in its own transaction.
+// MySQL commits DDL implicitly, so a migration that fails partway must be
+// fixed by hand before it is retried. SQLite is already inside the
+// transaction opened by withLock.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, script string, up bool) error {
+	direction := "down"
+	record := m.bind(fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.naming.Table("schema_migrations")))
+	args := []interface{}{migration.Version}
+	if up {
+		direction = "up"
+		record = m.bind(fmt.Sprintf("INSERT INTO %s (version, name) VALUES (?, ?)", m.naming.Table("schema_migrations")))
+		args = append(args, migration.Name)
+	}
+
+	logger := m.logger.WithFields(logrus.Fields{
+		"version":   migration.Version,
+		"name":      migration.Name,
+		"direction": direction,
+	})
+	logger.Info("Running schema migration")
+
+	var exec Conn = conn
+	var tx *sql.Tx
+	if m.dialect != SQLite {
+		var err error
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		exec = tx
+	}
+
+	for _, statement := range m.statements(script) {
+		if _, err := exec.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to run migration %04d_%s %s: %w", migration.Version, migration.Name, direction, err)
+		}
+	}
+	if _, err := exec.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+		}
+	}
+	return nil
+}
+
+// statements splits a migration script for drivers that run one statement
+// per call. PostgreSQL and SQLite accept the whole script at once, which
+// keeps semicolons inside PL/pgSQL blocks intact.
+func (m *Migrator) statements(script string) []string {
+	if m.dialect != MySQL {
+		return []string{script}
+	}
+	return splitStatements(script)
+}
+
+// splitStatements splits a MySQL script on the semicolons that end
+// statements, skipping those in comments and in quoted strings and
+// identifiers. Comments stay with the statement that follows them, and
+// chunks holding only comments are dropped since MySQL rejects empty queries.
+func splitStatements(script string) []string {
+	var statements []string
+	start := 0
+	hasCode := false
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '#' || isLineComment(script, i):
+			// Runs to the end of the line
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			// Executable /*! ... */ comments are run by MySQL
+			if i+2 < len(script) && script[i+2] == '!' {
+				hasCode = true
+			}
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+		case c == '\'' || c == '"' || c == '`':
+			hasCode = true
+			for i++; i < len(script) && script[i] != c; i++ {
+				// Backslash escapes apply to strings, not identifiers
+				if script[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+		case c == ';':
+			if hasCode {
+				statements = append(statements, script[start:i+1])
+			}
+			start = i + 1
+			hasCode = false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+
+	if hasCode {
+		statements = append(statements, script[start:])
+	}
+	return statements
+}
+
+// isLineComment reports whether script has a "-- " comment at i. MySQL needs
+// whitespace after the dashes, so "1--1" is arithmetic.
+func isLineComment(script string, i int) bool {
+	if !strings.HasPrefix(script[i:], "--") {
+		return false
+	}
+	return i+2 == len(script) || strings.ContainsRune(" \t\r\n", rune(script[i+2]))
+}
+
+// appliedVersions creates schema_migrations if needed and returns the applied
+// versions with the time each was applied
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	if err := m.ensureSchema(ctx, conn); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, m.tableDDL()); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s ORDER BY version", m.naming.Table("schema_migrations")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema migration: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return versions, nil
+}
+
+// ensureSchema creates the PostgreSQL schema of the tables if it is missing.
+// Existing schemas are left alone, so the role only needs CREATE on the
+// database when the schema is new.
+func (m *Migrator) ensureSchema(ctx context.Context, conn *sql.Conn) error {
+	if m.dialect != Postgres || m.naming.Schema == "" {
+		return nil
+	}
+
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", m.naming.Schema).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema %s: %w", m.naming.Schema, err)
+	}
+	if !exists {
+		if _, err := conn.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+m.naming.Schema); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", m.naming.Schema, err)
+		}
+	}
+	return nil
+}
+
+// tableDDL returns the schema_migrations definition for the dialect
+func (m *Migrator) tableDDL() string {
+	table := m.naming.Table("schema_migrations")
+	switch m.dialect {
+	case Postgres:
+		return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`
+	case MySQL:
+		return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+		) DEFAULT CHARSET=utf8mb4`
+	default:
+		return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`
+	}
+}
+
+// bind rewrites ? placeholders to $n for PostgreSQL
+func (m *Migrator) bind(query string) string {
+	if m.dialect != Postgres {
+		return query
+	}
+
+	var bound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			bound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		bound.WriteRune(r)
+	}
+	return bound.String()
+}
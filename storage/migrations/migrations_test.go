@@ -0,0 +1,348 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", "file:"+t.TempDir()+"/migrations.db")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	return logger
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to inspect table %s: %v", table, err)
+	}
+	return count > 0
+}
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range []Dialect{Postgres, MySQL, SQLite} {
+		t.Run(string(dialect), func(t *testing.T) {
+			migrations, err := Load(dialect, Naming{})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(migrations) == 0 {
+				t.Fatal("Expected at least one migration")
+			}
+			for i, migration := range migrations {
+				if migration.Version != i+1 {
+					t.Errorf("Expected version %d, got %d", i+1, migration.Version)
+				}
+				if migration.Name == "" || migration.Up == "" || migration.Down == "" {
+					t.Errorf("Migration %d is incomplete: %+v", migration.Version, migration)
+				}
+			}
+		})
+	}
+
+	if _, err := Load("oracle", Naming{}); err == nil {
+		t.Error("Expected an error for an unknown dialect")
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	migrator, err := New(db, SQLite, Naming{}, newTestLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("Expected migration %d to be pending", status.Version)
+		}
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != len(statuses) {
+		t.Errorf("Expected %d applied migrations, got %d", len(statuses), applied)
+	}
+	for _, table := range []string{"sync_state", "fga_changelog", "fga_tuples", "fga_tuple_history"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("Expected table %s to exist", table)
+		}
+	}
+
+	// Running again is a no-op
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() again error = %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected no migrations on the second run, got %d", applied)
+	}
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("Expected migration %d to be applied, got %+v", status.Version, status)
+		}
+	}
+
+	if _, err := migrator.Down(ctx, 0); err == nil {
+		t.Error("Expected Down(0) to fail")
+	}
+
+	rolledBack, err := migrator.Down(ctx, len(statuses)+1)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if rolledBack != len(statuses) {
+		t.Errorf("Expected %d rolled back migrations, got %d", len(statuses), rolledBack)
+	}
+	if tableExists(t, db, "fga_tuples") {
+		t.Error("Expected fga_tuples to be dropped")
+	}
+
+	// Rolled back migrations can be applied again
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() after Down() error = %v", err)
+	}
+	if !tableExists(t, db, "fga_tuples") {
+		t.Error("Expected fga_tuples to be recreated")
+	}
+}
+
+func TestMigrator_OptionalMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	migrator := &Migrator{
+		db:      db,
+		dialect: SQLite,
+		logger:  newTestLogger(),
+		migrations: []Migration{
+			{Version: 1, Name: "base", Up: "CREATE TABLE base (id INTEGER)", Down: "DROP TABLE base"},
+			{Version: 2, Name: "extra", Up: "CREATE TABLE extra (id INTEGER)", Down: "DROP TABLE extra", Feature: "extra"},
+		},
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 1 || tableExists(t, db, "extra") {
+		t.Errorf("Expected only the required migration to be applied, got %d", applied)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if !statuses[1].Skipped || statuses[1].Applied {
+		t.Errorf("Expected the optional migration to be skipped, got %+v", statuses[1])
+	}
+
+	// Enabling the feature later applies it
+	migrator.Features = []string{"extra"}
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() with the feature error = %v", err)
+	}
+	if applied != 1 || !tableExists(t, db, "extra") {
+		t.Errorf("Expected the optional migration to be applied, got %d", applied)
+	}
+
+	postgres, err := Load(Postgres, Naming{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, migration := range postgres {
+		if migration.Name == "partition_changelog" && migration.Feature != FeaturePartitioning {
+			t.Errorf("Expected partition_changelog to require %s, got %q", FeaturePartitioning, migration.Feature)
+		}
+	}
+}
+
+func TestMigrator_Baseline(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	migrator, err := New(db, SQLite, Naming{}, newTestLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// A failed baseline rolls back the whole run
+	migrator.Baseline = func(ctx context.Context, conn Conn) error {
+		if _, err := conn.ExecContext(ctx, "CREATE TABLE legacy (id INTEGER)"); err != nil {
+			return err
+		}
+		return fmt.Errorf("upgrade failed")
+	}
+	if _, err := migrator.Up(ctx); err == nil {
+		t.Fatal("Expected Up() to fail when the baseline fails")
+	}
+	if tableExists(t, db, "legacy") || tableExists(t, db, "sync_state") {
+		t.Error("Expected the failed run to be rolled back")
+	}
+
+	calls := 0
+	migrator.Baseline = func(ctx context.Context, conn Conn) error {
+		calls++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the baseline to run once on an unversioned database, got %d", calls)
+	}
+}
+
+func TestMigrator_Statements(t *testing.T) {
+	script := "-- comment\nCREATE TABLE a (\n\tid INT\n);\nINSERT INTO a VALUES (1);\n\n"
+
+	mysql := &Migrator{dialect: MySQL}
+	statements := mysql.statements(script)
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 MySQL statements, got %d: %q", len(statements), statements)
+	}
+
+	postgres := &Migrator{dialect: Postgres}
+	if statements := postgres.statements(script); len(statements) != 1 {
+		t.Errorf("Expected PostgreSQL to run the script at once, got %d statements", len(statements))
+	}
+}
+
+func TestMigrator_StatementsSkipCommentsAndQuotes(t *testing.T) {
+	script := `-- Widen the column;
+# and backfill it;
+ALTER TABLE a MODIFY name VARCHAR(255); /* not; a statement */
+INSERT INTO a (name) VALUES ('first;
+second'), ("it\'s;"), ('x''y;');
+SELECT ` + "`odd;name`" + ` FROM a WHERE 1--1 = 2;
+-- trailing comment;
+`
+
+	mysql := &Migrator{dialect: MySQL}
+	statements := mysql.statements(script)
+
+	expected := []string{
+		"-- Widen the column;\n# and backfill it;\nALTER TABLE a MODIFY name VARCHAR(255);",
+		" /* not; a statement */\nINSERT INTO a (name) VALUES ('first;\nsecond'), (\"it\\'s;\"), ('x''y;');",
+		"\nSELECT `odd;name` FROM a WHERE 1--1 = 2;",
+	}
+	if len(statements) != len(expected) {
+		t.Fatalf("Expected %d MySQL statements, got %d: %q", len(expected), len(statements), statements)
+	}
+	for i := range expected {
+		if statements[i] != expected[i] {
+			t.Errorf("Statement %d: expected %q, got %q", i, expected[i], statements[i])
+		}
+	}
+}
+
+func TestMigrator_Bind(t *testing.T) {
+	query := "INSERT INTO schema_migrations (version, name) VALUES (?, ?)"
+
+	if got := (&Migrator{dialect: Postgres}).bind(query); got != "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)" {
+		t.Errorf("Unexpected PostgreSQL query: %s", got)
+	}
+	if got := (&Migrator{dialect: MySQL}).bind(query); got != query {
+		t.Errorf("Unexpected MySQL query: %s", got)
+	}
+}
+
+func TestNaming(t *testing.T) {
+	naming := Naming{Schema: "authz", Prefix: "eu_"}
+	if got := naming.Table("fga_tuples"); got != "authz.eu_fga_tuples" {
+		t.Errorf("Table() = %s", got)
+	}
+	if got := naming.Name("idx_fga_tuples_relation"); got != "eu_idx_fga_tuples_relation" {
+		t.Errorf("Name() = %s", got)
+	}
+	if got := (Naming{}).Table("fga_tuples"); got != "fga_tuples" {
+		t.Errorf("Table() without naming = %s", got)
+	}
+
+	migrations, err := Load(Postgres, naming)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	baseline := migrations[0].Up
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS authz.eu_fga_tuples (",
+		"CREATE INDEX IF NOT EXISTS eu_idx_fga_tuples_relation ON authz.eu_fga_tuples(relation)",
+		"DROP INDEX IF EXISTS authz.eu_idx_sync_state_store_id",
+		"table_schema = 'authz' AND table_name = 'eu_fga_tuples'",
+		"DROP CONSTRAINT eu_fga_tuples_pkey",
+	} {
+		if !strings.Contains(baseline, want) {
+			t.Errorf("Expected the baseline to contain %q", want)
+		}
+	}
+}
+
+func TestMigrator_SharedDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	// Two pipelines keep their own tables and versions in one database
+	for _, prefix := range []string{"us_", "eu_"} {
+		migrator, err := New(db, SQLite, Naming{Prefix: prefix}, newTestLogger())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up(%s) error = %v", prefix, err)
+		}
+		if applied == 0 {
+			t.Errorf("Expected migrations to be applied for %s", prefix)
+		}
+		for _, table := range []string{"schema_migrations", "sync_state", "fga_changelog", "fga_tuples", "fga_tuple_history"} {
+			if !tableExists(t, db, prefix+table) {
+				t.Errorf("Expected table %s%s to exist", prefix, table)
+			}
+		}
+	}
+	if tableExists(t, db, "fga_tuples") {
+		t.Error("Expected no unprefixed tables")
+	}
+
+	migrator, err := New(db, SQLite, Naming{Prefix: "us_"}, newTestLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if tableExists(t, db, "us_fga_tuples") || !tableExists(t, db, "eu_fga_tuples") {
+		t.Error("Expected rolling back one pipeline to leave the other alone")
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS fga_tuple_history;
+DROP TABLE IF EXISTS fga_tuples;
+DROP TABLE IF EXISTS fga_changelog;
+DROP TABLE IF EXISTS sync_state;
@@ -0,0 +1,72 @@
+-- Tables for every storage mode, so switching modes needs no migration.
+-- Databases created before multi-store support are upgraded in Go before
+-- this runs, since MySQL can't add columns conditionally.
+--
+-- MySQL has no CREATE INDEX IF NOT EXISTS, so indexes are declared inline.
+-- Identifier columns are VARCHAR(191) and store_id VARCHAR(64) to keep the
+-- composite primary key of fga_tuples under InnoDB's 3072-byte limit with
+-- utf8mb4, which is also why type and relation names stay VARCHAR(100).
+
+CREATE TABLE IF NOT EXISTS sync_state (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL DEFAULT '',
+	continuation_token TEXT,
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	UNIQUE INDEX idx_sync_state_cursor (store_id, object_type)
+) DEFAULT CHARSET=utf8mb4;
+INSERT IGNORE INTO sync_state (id, continuation_token) VALUES (1, '');
+
+CREATE TABLE IF NOT EXISTS fga_changelog (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	change_type VARCHAR(50) NOT NULL,
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(191) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(191) NOT NULL,
+	timestamp DATETIME(6) NOT NULL,
+	`condition` JSON,
+	raw_event JSON,
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	INDEX idx_fga_changelog_store_id (store_id),
+	INDEX idx_fga_changelog_timestamp (timestamp),
+	INDEX idx_fga_changelog_user_type (user_type),
+	INDEX idx_fga_changelog_object_type (object_type),
+	INDEX idx_fga_changelog_relation (relation),
+	INDEX idx_fga_changelog_change_type (change_type)
+) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS fga_tuples (
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(191) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(191) NOT NULL,
+	`condition` JSON,
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id),
+	INDEX idx_fga_tuples_user_type (user_type),
+	INDEX idx_fga_tuples_object_type (object_type),
+	INDEX idx_fga_tuples_relation (relation),
+	INDEX idx_fga_tuples_updated_at (updated_at)
+) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS fga_tuple_history (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(191) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(191) NOT NULL,
+	`condition` JSON,
+	valid_from DATETIME(6) NOT NULL,
+	valid_to DATETIME(6) NULL,
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	INDEX idx_fga_tuple_history_tuple (store_id, object_type, object_id, relation, user_type, user_id),
+	INDEX idx_fga_tuple_history_user (store_id, user_type, user_id, valid_from)
+) DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,82 @@
+-- Tables for every storage mode, so switching modes needs no migration.
+-- The ALTER and DO statements upgrade databases created before multi-store
+-- support, when the schema was managed with CREATE TABLE IF NOT EXISTS.
+
+CREATE TABLE IF NOT EXISTS {{table "sync_state"}} (
+	id SERIAL PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL DEFAULT '',
+	continuation_token TEXT,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+ALTER TABLE {{table "sync_state"}} ADD COLUMN IF NOT EXISTS store_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE {{table "sync_state"}} ADD COLUMN IF NOT EXISTS object_type VARCHAR(100) NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS {{qualify (name "idx_sync_state_store_id")}};
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx_sync_state_cursor"}} ON {{table "sync_state"}}(store_id, object_type);
+INSERT INTO {{table "sync_state"}} (continuation_token)
+SELECT '' WHERE NOT EXISTS (SELECT 1 FROM {{table "sync_state"}});
+
+CREATE TABLE IF NOT EXISTS {{table "fga_changelog"}} (
+	id BIGSERIAL PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	change_type VARCHAR(20) NOT NULL,
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(255) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(255) NOT NULL,
+	timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+	condition JSONB,
+	raw_event JSONB,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+ALTER TABLE {{table "fga_changelog"}} ADD COLUMN IF NOT EXISTS store_id VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_store_id"}} ON {{table "fga_changelog"}}(store_id);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_timestamp"}} ON {{table "fga_changelog"}}(timestamp);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_user_type"}} ON {{table "fga_changelog"}}(user_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_object_type"}} ON {{table "fga_changelog"}}(object_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_relation"}} ON {{table "fga_changelog"}}(relation);
+
+CREATE TABLE IF NOT EXISTS {{table "fga_tuples"}} (
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(255) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(255) NOT NULL,
+	condition JSONB,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+	PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id)
+);
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = {{schema}} AND table_name = '{{name "fga_tuples"}}' AND column_name = 'store_id'
+	) THEN
+		ALTER TABLE {{table "fga_tuples"}} ADD COLUMN store_id VARCHAR(64) NOT NULL DEFAULT '';
+		ALTER TABLE {{table "fga_tuples"}} DROP CONSTRAINT {{name "fga_tuples_pkey"}};
+		ALTER TABLE {{table "fga_tuples"}} ADD PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id);
+	END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_user_type"}} ON {{table "fga_tuples"}}(user_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_object_type"}} ON {{table "fga_tuples"}}(object_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_relation"}} ON {{table "fga_tuples"}}(relation);
+
+CREATE TABLE IF NOT EXISTS {{table "fga_tuple_history"}} (
+	id BIGSERIAL PRIMARY KEY,
+	store_id VARCHAR(64) NOT NULL DEFAULT '',
+	object_type VARCHAR(100) NOT NULL,
+	object_id VARCHAR(255) NOT NULL,
+	relation VARCHAR(100) NOT NULL,
+	user_type VARCHAR(100) NOT NULL,
+	user_id VARCHAR(255) NOT NULL,
+	condition JSONB,
+	valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+	valid_to TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_open"}} ON {{table "fga_tuple_history"}}(store_id, object_type, object_id, relation, user_type, user_id) WHERE valid_to IS NULL;
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_object"}} ON {{table "fga_tuple_history"}}(store_id, object_type, object_id, valid_from);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_user"}} ON {{table "fga_tuple_history"}}(store_id, user_type, user_id, valid_from);
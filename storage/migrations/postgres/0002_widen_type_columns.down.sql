@@ -0,0 +1,15 @@
+-- Fails if any stored name is longer than 100 characters
+
+ALTER TABLE {{table "fga_tuple_history"}}
+	ALTER COLUMN object_type TYPE VARCHAR(100),
+	ALTER COLUMN relation TYPE VARCHAR(100),
+	ALTER COLUMN user_type TYPE VARCHAR(100);
+ALTER TABLE {{table "fga_tuples"}}
+	ALTER COLUMN object_type TYPE VARCHAR(100),
+	ALTER COLUMN relation TYPE VARCHAR(100),
+	ALTER COLUMN user_type TYPE VARCHAR(100);
+ALTER TABLE {{table "fga_changelog"}}
+	ALTER COLUMN object_type TYPE VARCHAR(100),
+	ALTER COLUMN relation TYPE VARCHAR(100),
+	ALTER COLUMN user_type TYPE VARCHAR(100);
+ALTER TABLE {{table "sync_state"}} ALTER COLUMN object_type TYPE VARCHAR(100);
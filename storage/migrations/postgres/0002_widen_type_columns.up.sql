@@ -0,0 +1,16 @@
+-- Type and relation names may be up to 255 characters. Widening a VARCHAR
+-- only updates the catalog, so this doesn't rewrite the tables.
+
+ALTER TABLE {{table "sync_state"}} ALTER COLUMN object_type TYPE VARCHAR(255);
+ALTER TABLE {{table "fga_changelog"}}
+	ALTER COLUMN object_type TYPE VARCHAR(255),
+	ALTER COLUMN relation TYPE VARCHAR(255),
+	ALTER COLUMN user_type TYPE VARCHAR(255);
+ALTER TABLE {{table "fga_tuples"}}
+	ALTER COLUMN object_type TYPE VARCHAR(255),
+	ALTER COLUMN relation TYPE VARCHAR(255),
+	ALTER COLUMN user_type TYPE VARCHAR(255);
+ALTER TABLE {{table "fga_tuple_history"}}
+	ALTER COLUMN object_type TYPE VARCHAR(255),
+	ALTER COLUMN relation TYPE VARCHAR(255),
+	ALTER COLUMN user_type TYPE VARCHAR(255);
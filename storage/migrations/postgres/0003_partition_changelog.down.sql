@@ -0,0 +1,17 @@
+-- Copies the rows of every attached partition back into a plain table.
+-- Detached partitions are standalone tables and are left alone.
+
+ALTER TABLE {{table "fga_changelog"}} RENAME TO {{name "fga_changelog_partitioned"}};
+CREATE TABLE {{table "fga_changelog"}} (LIKE {{table "fga_changelog_partitioned"}} INCLUDING DEFAULTS);
+INSERT INTO {{table "fga_changelog"}} SELECT * FROM {{table "fga_changelog_partitioned"}};
+ALTER SEQUENCE {{qualify (name "fga_changelog_id_seq")}} OWNED BY {{table "fga_changelog"}}.id;
+
+-- Frees the primary key and index names
+DROP TABLE {{table "fga_changelog_partitioned"}};
+
+ALTER TABLE {{table "fga_changelog"}} ADD PRIMARY KEY (id);
+CREATE INDEX {{name "idx_fga_changelog_store_id"}} ON {{table "fga_changelog"}}(store_id);
+CREATE INDEX {{name "idx_fga_changelog_timestamp"}} ON {{table "fga_changelog"}}(timestamp);
+CREATE INDEX {{name "idx_fga_changelog_user_type"}} ON {{table "fga_changelog"}}(user_type);
+CREATE INDEX {{name "idx_fga_changelog_object_type"}} ON {{table "fga_changelog"}}(object_type);
+CREATE INDEX {{name "idx_fga_changelog_relation"}} ON {{table "fga_changelog"}}(relation);
@@ -0,0 +1,51 @@
+-- Range-partitions fga_changelog on timestamp. Optional: only runs with
+-- backend.partitioning enabled. The adapter creates the upcoming partitions
+-- and removes expired ones; this only converts the table.
+--
+-- The existing table becomes the archive partition, covering everything up
+-- to the end of the day of its newest row (or up to today), and hands its id
+-- sequence and index names over to the new table. The default partition
+-- catches changes outside every range, such as a backfill older than
+-- retention. The primary key becomes (id, timestamp), as a partitioned
+-- table's primary key must include the partition key.
+
+ALTER TABLE {{table "fga_changelog"}} RENAME TO {{name "fga_changelog_archive"}};
+-- A partition can't keep a primary key other than the parent's
+ALTER TABLE {{table "fga_changelog_archive"}} DROP CONSTRAINT {{name "fga_changelog_pkey"}},
+	ADD CONSTRAINT {{name "fga_changelog_archive_pkey"}} PRIMARY KEY (id, timestamp);
+ALTER INDEX {{qualify (name "idx_fga_changelog_store_id")}} RENAME TO {{name "idx_fga_changelog_archive_store_id"}};
+ALTER INDEX {{qualify (name "idx_fga_changelog_timestamp")}} RENAME TO {{name "idx_fga_changelog_archive_timestamp"}};
+ALTER INDEX {{qualify (name "idx_fga_changelog_user_type")}} RENAME TO {{name "idx_fga_changelog_archive_user_type"}};
+ALTER INDEX {{qualify (name "idx_fga_changelog_object_type")}} RENAME TO {{name "idx_fga_changelog_archive_object_type"}};
+ALTER INDEX {{qualify (name "idx_fga_changelog_relation")}} RENAME TO {{name "idx_fga_changelog_archive_relation"}};
+
+CREATE TABLE {{table "fga_changelog"}} (LIKE {{table "fga_changelog_archive"}} INCLUDING DEFAULTS) PARTITION BY RANGE (timestamp);
+ALTER TABLE {{table "fga_changelog"}} ADD PRIMARY KEY (id, timestamp);
+ALTER SEQUENCE {{qualify (name "fga_changelog_id_seq")}} OWNED BY {{table "fga_changelog"}}.id;
+
+DO $$
+DECLARE
+	boundary TIMESTAMP WITH TIME ZONE;
+BEGIN
+	SELECT GREATEST(
+		date_trunc('day', NOW() AT TIME ZONE 'UTC'),
+		date_trunc('day', MAX(timestamp) AT TIME ZONE 'UTC') + INTERVAL '1 day'
+	) AT TIME ZONE 'UTC'
+	INTO boundary
+	FROM {{table "fga_changelog_archive"}};
+
+	EXECUTE format(
+		'ALTER TABLE {{table "fga_changelog"}} ATTACH PARTITION {{table "fga_changelog_archive"}} FOR VALUES FROM (MINVALUE) TO (%L)',
+		boundary
+	);
+END
+$$;
+
+CREATE TABLE {{table "fga_changelog_default"}} PARTITION OF {{table "fga_changelog"}} DEFAULT;
+
+-- Indexes on the parent adopt the matching archive indexes
+CREATE INDEX {{name "idx_fga_changelog_store_id"}} ON {{table "fga_changelog"}}(store_id);
+CREATE INDEX {{name "idx_fga_changelog_timestamp"}} ON {{table "fga_changelog"}}(timestamp);
+CREATE INDEX {{name "idx_fga_changelog_user_type"}} ON {{table "fga_changelog"}}(user_type);
+CREATE INDEX {{name "idx_fga_changelog_object_type"}} ON {{table "fga_changelog"}}(object_type);
+CREATE INDEX {{name "idx_fga_changelog_relation"}} ON {{table "fga_changelog"}}(relation);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS {{table "fga_tuple_history"}};
+DROP TABLE IF EXISTS {{table "fga_tuples"}};
+DROP TABLE IF EXISTS {{table "fga_changelog"}};
+DROP TABLE IF EXISTS {{table "sync_state"}};
@@ -0,0 +1,69 @@
+-- Tables for every storage mode, so switching modes needs no migration.
+-- Databases created before multi-store support are upgraded in Go before
+-- this runs, since SQLite can't add columns conditionally.
+
+CREATE TABLE IF NOT EXISTS {{table "sync_state"}} (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	store_id TEXT NOT NULL DEFAULT '',
+	object_type TEXT NOT NULL DEFAULT '',
+	continuation_token TEXT,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+DROP INDEX IF EXISTS {{name "idx_sync_state_store_id"}};
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx_sync_state_cursor"}} ON {{table "sync_state"}}(store_id, object_type);
+INSERT OR IGNORE INTO {{table "sync_state"}} (id, continuation_token) VALUES (1, '');
+
+CREATE TABLE IF NOT EXISTS {{table "fga_changelog"}} (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	store_id TEXT NOT NULL DEFAULT '',
+	change_type TEXT NOT NULL,
+	object_type TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	relation TEXT NOT NULL,
+	user_type TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	timestamp DATETIME NOT NULL,
+	condition TEXT,
+	raw_event TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_store_id"}} ON {{table "fga_changelog"}}(store_id);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_timestamp"}} ON {{table "fga_changelog"}}(timestamp);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_user_type"}} ON {{table "fga_changelog"}}(user_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_object_type"}} ON {{table "fga_changelog"}}(object_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_relation"}} ON {{table "fga_changelog"}}(relation);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_changelog_change_type"}} ON {{table "fga_changelog"}}(change_type);
+
+CREATE TABLE IF NOT EXISTS {{table "fga_tuples"}} (
+	store_id TEXT NOT NULL DEFAULT '',
+	object_type TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	relation TEXT NOT NULL,
+	user_type TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	condition TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id)
+);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_user_type"}} ON {{table "fga_tuples"}}(user_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_object_type"}} ON {{table "fga_tuples"}}(object_type);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_relation"}} ON {{table "fga_tuples"}}(relation);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuples_updated_at"}} ON {{table "fga_tuples"}}(updated_at);
+
+CREATE TABLE IF NOT EXISTS {{table "fga_tuple_history"}} (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	store_id TEXT NOT NULL DEFAULT '',
+	object_type TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	relation TEXT NOT NULL,
+	user_type TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	condition TEXT,
+	valid_from DATETIME NOT NULL,
+	valid_to DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_open"}} ON {{table "fga_tuple_history"}}(store_id, object_type, object_id, relation, user_type, user_id) WHERE valid_to IS NULL;
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_object"}} ON {{table "fga_tuple_history"}}(store_id, object_type, object_id, valid_from);
+CREATE INDEX IF NOT EXISTS {{name "idx_fga_tuple_history_user"}} ON {{table "fga_tuple_history"}}(store_id, user_type, user_id, valid_from);
@@ -0,0 +1,623 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MySQLAdapter implements StorageAdapter for MySQL and MariaDB
+type MySQLAdapter struct {
+	db         *sql.DB
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	storeID    string // Rows are scoped to this store; "" in single-store mode
+	objectType string // Continuation token cursor; "" when fetching all types
+	shared     bool   // Views don't own the connection
+}
+
+// NewMySQLAdapter creates a new MySQL/MariaDB storage adapter
+func NewMySQLAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*MySQLAdapter, error) {
+	db, err := openMySQL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &MySQLAdapter{
+		db:     db,
+		logger: logger,
+		mode:   mode,
+	}
+
+	// Initialize database schema
+	if err := adapter.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+	}
+
+	return adapter, nil
+}
+
+// openMySQL opens a MySQL or MariaDB database
+func openMySQL(dsn string) (*sql.DB, error) {
+	// MySQL DSN format: user:password@tcp(host:port)/database?parseTime=true
+	mysqlConfig, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DSN: %w", err)
+	}
+
+	// History intervals and migration times are scanned into time.Time,
+	// which needs parseTime
+	mysqlConfig.ParseTime = true
+
+	db, err := sql.Open("mysql", mysqlConfig.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// ForStore returns a view of the adapter scoped to storeID
+func (m *MySQLAdapter) ForStore(storeID string) StorageAdapter {
+	view := *m
+	view.storeID = storeID
+	view.shared = true
+	return &view
+}
+
+// ForObjectType returns a view of the adapter with its own cursor for objectType
+func (m *MySQLAdapter) ForObjectType(objectType string) StorageAdapter {
+	view := *m
+	view.objectType = objectType
+	view.shared = true
+	return &view
+}
+
+// initSchema brings the database schema up to the latest migration
+func (m *MySQLAdapter) initSchema() error {
+	migrator, err := newMySQLMigrator(m.db, m.logger)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return nil
+}
+
+// newMySQLMigrator returns the schema migrator for a MySQL or MariaDB database
+func newMySQLMigrator(db *sql.DB, logger *logrus.Logger) (*migrations.Migrator, error) {
+	migrator, err := migrations.New(db, migrations.MySQL, migrations.Naming{}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	migrator.Baseline = mysqlBaseline
+	return migrator, nil
+}
+
+// mysqlBaseline adds the store_id and object_type columns to tables created
+// before multi-store and per-type cursor support, ahead of the baseline
+// migration. Existing rows belong to the single-store partition ("") and the
+// all-types cursor ("").
+func mysqlBaseline(ctx context.Context, conn migrations.Conn) error {
+	upgrades := []struct {
+		table, column, query string
+	}{
+		{"sync_state", "store_id", `ALTER TABLE sync_state
+			ADD COLUMN store_id VARCHAR(64) NOT NULL DEFAULT '' AFTER id`},
+		{"sync_state", "object_type", `ALTER TABLE sync_state
+			ADD COLUMN object_type VARCHAR(100) NOT NULL DEFAULT '' AFTER store_id,
+			ADD UNIQUE INDEX idx_sync_state_cursor (store_id, object_type)`},
+		{"fga_changelog", "store_id", `ALTER TABLE fga_changelog
+			ADD COLUMN store_id VARCHAR(64) NOT NULL DEFAULT '' AFTER id,
+			ADD INDEX idx_fga_changelog_store_id (store_id)`},
+		{"fga_tuples", "store_id", `ALTER TABLE fga_tuples
+			ADD COLUMN store_id VARCHAR(64) NOT NULL DEFAULT '' FIRST,
+			DROP PRIMARY KEY,
+			ADD PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id)`},
+	}
+
+	for _, upgrade := range upgrades {
+		var tables, columns int
+		err := conn.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?),
+			(SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?)`,
+			upgrade.table, upgrade.table, upgrade.column).Scan(&tables, &columns)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", upgrade.table, err)
+		}
+		if tables == 0 || columns > 0 {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, upgrade.query); err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %w", upgrade.table, upgrade.column, err)
+		}
+	}
+
+	// The per-store unique index is replaced by the per-cursor one
+	var storeIndexes int
+	err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'sync_state' AND index_name = 'idx_sync_state_store_id'`).Scan(&storeIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to inspect sync_state indexes: %w", err)
+	}
+	if storeIndexes > 0 {
+		if _, err := conn.ExecContext(ctx, `ALTER TABLE sync_state DROP INDEX idx_sync_state_store_id`); err != nil {
+			return fmt.Errorf("failed to drop idx_sync_state_store_id: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mysqlJSON prepares a condition for a MySQL JSON column. MySQL rejects
+// malformed JSON, so anything that does not parse is stored as a JSON string
+// rather than failing the whole batch.
+func mysqlJSON(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if json.Valid([]byte(value)) {
+		return value
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return string(encoded)
+}
+
+// WriteChanges writes a batch of change events to MySQL (changelog mode)
+func (m *MySQLAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return m.writeChanges(ctx, changes, nil)
+}
+
+// WriteChangesWithToken writes a batch of change events and saves the
+// continuation token in the same transaction (changelog mode)
+func (m *MySQLAdapter) WriteChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return m.writeChanges(ctx, changes, &token)
+}
+
+// writeChanges inserts changes into fga_changelog and, when token is non-nil,
+// updates sync_state before committing
+func (m *MySQLAdapter) writeChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "mysql.write_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(m.mode)),
+			attribute.String("db.system", "mysql"),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if m.mode != config.StorageModeChangelog {
+		err := fmt.Errorf("WriteChanges is only supported in changelog mode")
+		span.RecordError(err)
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fga_changelog (store_id, change_type, object_type, object_id, relation, user_type, user_id, timestamp, `+"`condition`"+`, raw_event)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		rawEventJSON, err := json.Marshal(change)
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to marshal change event to JSON")
+			rawEventJSON = []byte("{}")
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			m.storeID,
+			change.Operation,
+			change.ObjectType,
+			change.ObjectID,
+			change.Relation,
+			change.UserType,
+			change.UserID,
+			change.Timestamp.UTC(),
+			mysqlJSON(change.Condition),
+			string(rawEventJSON),
+		)
+		if err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to insert change: %w", err)
+		}
+	}
+
+	if token != nil {
+		if err := m.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.rows_affected", len(changes)),
+		attribute.String("db.operation", "insert"),
+	)
+
+	m.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
+	return nil
+}
+
+// ApplyChanges applies a batch of changes to state table (stateful mode)
+func (m *MySQLAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return m.applyChanges(ctx, changes, nil)
+}
+
+// ApplyChangesWithToken applies a batch of changes and saves the continuation
+// token in the same transaction (stateful mode)
+func (m *MySQLAdapter) ApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return m.applyChanges(ctx, changes, &token)
+}
+
+// applyChanges upserts and deletes tuples in fga_tuples and, when token is
+// non-nil, updates sync_state before committing
+func (m *MySQLAdapter) applyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "mysql.apply_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(m.mode)),
+			attribute.String("db.system", "mysql"),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if m.mode != config.StorageModeStateful && m.mode != config.StorageModeHistory {
+		err := fmt.Errorf("ApplyChanges is only supported in stateful and history modes")
+		span.RecordError(err)
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var insertCount, deleteCount int
+	if m.mode == config.StorageModeHistory {
+		insertCount, deleteCount, err = m.applyHistoryTx(ctx, tx, changes)
+	} else {
+		insertCount, deleteCount, err = m.applyTuplesTx(ctx, tx, changes)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := m.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.inserts", insertCount),
+		attribute.Int("db.deletes", deleteCount),
+		attribute.String("db.operation", "upsert"),
+	)
+
+	m.logger.WithFields(logrus.Fields{
+		"inserts": insertCount,
+		"deletes": deleteCount,
+	}).Info("Successfully applied changes to state table")
+	return nil
+}
+
+// applyTuplesTx upserts and deletes tuples in fga_tuples as part of an open
+// transaction, returning the number of writes and deletes applied
+func (m *MySQLAdapter) applyTuplesTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	insertStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fga_tuples (store_id, object_type, object_id, relation, user_type, user_id, `+"`condition`"+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE `+"`condition`"+` = VALUES(`+"`condition`"+`), updated_at = CURRENT_TIMESTAMP(6)
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer insertStmt.Close()
+
+	deleteStmt, err := tx.PrepareContext(ctx, `
+		DELETE FROM fga_tuples
+		WHERE store_id = ? AND object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare delete statement: %w", err)
+	}
+	defer deleteStmt.Close()
+
+	var insertCount, deleteCount int
+	for _, change := range changes {
+		switch strings.ToUpper(change.Operation) {
+		case "TUPLE_OPERATION_WRITE":
+			_, err = insertStmt.ExecContext(ctx,
+				m.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+				mysqlJSON(change.Condition),
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to insert/update tuple: %w", err)
+			}
+			insertCount++
+		case "TUPLE_OPERATION_DELETE":
+			_, err = deleteStmt.ExecContext(ctx,
+				m.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to delete tuple: %w", err)
+			}
+			deleteCount++
+		default:
+			m.logger.WithField("operation", change.Operation).Warn("Unknown operation type, skipping")
+		}
+	}
+
+	return insertCount, deleteCount, nil
+}
+
+// applyHistoryTx opens a validity interval in fga_tuple_history for each write
+// and closes the open one for each delete, as part of an open transaction. A
+// write is skipped when the tuple is already open or an interval starting at
+// the same time exists, so replaying a batch does not duplicate intervals.
+func (m *MySQLAdapter) applyHistoryTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	openStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fga_tuple_history (store_id, object_type, object_id, relation, user_type, user_id, `+"`condition`"+`, valid_from)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ? FROM DUAL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM fga_tuple_history
+			WHERE store_id = ? AND object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?
+			AND (valid_to IS NULL OR valid_from = ?)
+		)
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare open interval statement: %w", err)
+	}
+	defer openStmt.Close()
+
+	closeStmt, err := tx.PrepareContext(ctx, `
+		UPDATE fga_tuple_history SET valid_to = ?
+		WHERE store_id = ? AND object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?
+		AND valid_to IS NULL AND valid_from <= ?
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare close interval statement: %w", err)
+	}
+	defer closeStmt.Close()
+
+	var openCount, closeCount int
+	for _, change := range changes {
+		key := []interface{}{m.storeID, change.ObjectType, change.ObjectID, change.Relation, change.UserType, change.UserID}
+		switch strings.ToUpper(change.Operation) {
+		case "TUPLE_OPERATION_WRITE":
+			args := append(append([]interface{}{}, key...), mysqlJSON(change.Condition), change.Timestamp)
+			args = append(append(args, key...), change.Timestamp)
+			result, err := openStmt.ExecContext(ctx, args...)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to open tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				openCount++
+			}
+		case "TUPLE_OPERATION_DELETE":
+			args := append(append([]interface{}{change.Timestamp}, key...), change.Timestamp)
+			result, err := closeStmt.ExecContext(ctx, args...)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to close tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				closeCount++
+			}
+		default:
+			m.logger.WithField("operation", change.Operation).Warn("Unknown operation type, skipping")
+		}
+	}
+
+	return openCount, closeCount, nil
+}
+
+// TuplesAsOf returns the tuples that existed at query.At (history mode)
+func (m *MySQLAdapter) TuplesAsOf(ctx context.Context, query AsOfQuery) ([]HistoryTuple, error) {
+	if m.mode != config.StorageModeHistory {
+		return nil, fmt.Errorf("TuplesAsOf is only supported in history mode")
+	}
+
+	statement, args := historyAsOfSQL("fga_tuple_history", m.storeID, query, query.At, func(int) string { return "?" }, "`condition`")
+	rows, err := m.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tuple history: %w", err)
+	}
+	defer rows.Close()
+
+	return scanHistoryTuples(rows)
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (m *MySQLAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	var token sql.NullString
+	err := m.db.QueryRowContext(ctx, "SELECT continuation_token FROM sync_state WHERE store_id = ? AND object_type = ?", m.storeID, m.objectType).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get continuation token: %w", err)
+	}
+	return token.String, nil
+}
+
+// mysqlSaveTokenQuery upserts the continuation token of one store cursor
+const mysqlSaveTokenQuery = `
+	INSERT INTO sync_state (store_id, object_type, continuation_token)
+	VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE continuation_token = VALUES(continuation_token), updated_at = CURRENT_TIMESTAMP(6)
+`
+
+// SaveContinuationToken saves the continuation token for resuming processing
+func (m *MySQLAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	_, err := m.db.ExecContext(ctx, mysqlSaveTokenQuery, m.storeID, m.objectType, token)
+	if err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	return nil
+}
+
+// saveContinuationTokenTx saves the continuation token as part of an open transaction
+func (m *MySQLAdapter) saveContinuationTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
+	_, err := tx.ExecContext(ctx, mysqlSaveTokenQuery, m.storeID, m.objectType, token)
+	if err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns statistics about the MySQL adapter
+func (m *MySQLAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	stats := make(map[string]interface{})
+
+	// Basic adapter info
+	stats["adapter_type"] = "mysql"
+	stats["storage_mode"] = string(m.mode)
+	if m.storeID != "" {
+		stats["store_id"] = m.storeID
+	}
+
+	// Test database connection
+	if err := m.db.PingContext(ctx); err != nil {
+		stats["connection_status"] = "error"
+		stats["connection_error"] = err.Error()
+		return stats, nil
+	}
+	stats["connection_status"] = "healthy"
+
+	// Get database-specific stats based on mode
+	if m.mode == config.StorageModeChangelog {
+		var count int64
+		err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog WHERE store_id = ?", m.storeID).Scan(&count)
+		if err != nil {
+			stats["query_error"] = err.Error()
+		} else {
+			stats["changelog_entries"] = count
+		}
+
+		// Get count by change type
+		rows, err := m.db.QueryContext(ctx, "SELECT change_type, COUNT(*) FROM fga_changelog WHERE store_id = ? GROUP BY change_type", m.storeID)
+		if err == nil {
+			defer rows.Close()
+			changeTypeStats := make(map[string]int64)
+			for rows.Next() {
+				var changeType string
+				var count int64
+				if err := rows.Scan(&changeType, &count); err == nil {
+					changeTypeStats[changeType] = count
+				}
+			}
+			stats["by_change_type"] = changeTypeStats
+		}
+	} else if m.mode == config.StorageModeStateful {
+		var count int64
+		err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples WHERE store_id = ?", m.storeID).Scan(&count)
+		if err != nil {
+			stats["query_error"] = err.Error()
+		} else {
+			stats["current_tuples"] = count
+		}
+
+		// Get count by object type
+		rows, err := m.db.QueryContext(ctx, "SELECT object_type, COUNT(*) FROM fga_tuples WHERE store_id = ? GROUP BY object_type", m.storeID)
+		if err == nil {
+			defer rows.Close()
+			objectTypeStats := make(map[string]int64)
+			for rows.Next() {
+				var objectType string
+				var count int64
+				if err := rows.Scan(&objectType, &count); err == nil {
+					objectTypeStats[objectType] = count
+				}
+			}
+			stats["by_object_type"] = objectTypeStats
+		}
+	} else if m.mode == config.StorageModeHistory {
+		var intervals, current int64
+		err := m.db.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) - COUNT(valid_to) FROM fga_tuple_history WHERE store_id = ?", m.storeID).Scan(&intervals, &current)
+		if err != nil {
+			stats["query_error"] = err.Error()
+		} else {
+			stats["history_intervals"] = intervals
+			stats["current_tuples"] = current
+		}
+	}
+
+	return stats, nil
+}
+
+// Close closes the database connection
+func (m *MySQLAdapter) Close() error {
+	if m.db == nil || m.shared {
+		return nil
+	}
+	return m.db.Close()
+}
@@ -0,0 +1,614 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// skipIfNoMySQL skips the test if MySQL is not available
+func skipIfNoMySQL(t *testing.T) string {
+	// Check if MySQL environment variables are set
+	dbHost := os.Getenv("MYSQL_HOST")
+	dbPort := os.Getenv("MYSQL_PORT")
+	dbUser := os.Getenv("MYSQL_USER")
+	dbPassword := os.Getenv("MYSQL_PASSWORD")
+	dbName := os.Getenv("MYSQL_DATABASE")
+
+	// Use defaults if not set
+	if dbHost == "" {
+		dbHost = "localhost"
+	}
+	if dbPort == "" {
+		dbPort = "3306"
+	}
+	if dbUser == "" {
+		dbUser = "root"
+	}
+	if dbPassword == "" {
+		dbPassword = "mysql"
+	}
+	if dbName == "" {
+		dbName = "openfga_sync_test"
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		dbUser, dbPassword, dbHost, dbPort, dbName)
+
+	// Try to connect to MySQL
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Skipf("MySQL not available: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		t.Skipf("MySQL not available: %v", err)
+	}
+
+	// Start every test from empty tables
+	for _, table := range []string{"fga_changelog", "fga_tuples", "fga_tuple_history", "sync_state", "schema_migrations"} {
+		db.Exec("DROP TABLE IF EXISTS " + table)
+	}
+
+	return dsn
+}
+
+func TestMySQLJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		want      interface{}
+	}{
+		{
+			name:      "empty condition",
+			condition: "",
+			want:      nil,
+		},
+		{
+			name:      "valid JSON",
+			condition: `{"name":"ip_allowlist"}`,
+			want:      `{"name":"ip_allowlist"}`,
+		},
+		{
+			name:      "invalid JSON is wrapped as a JSON string",
+			condition: `{invalid_json: missing_quotes}`,
+			want:      `"{invalid_json: missing_quotes}"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mysqlJSON(tt.condition)
+			if got != tt.want {
+				t.Errorf("mysqlJSON() = %v, want %v", got, tt.want)
+			}
+			if s, ok := got.(string); ok && !json.Valid([]byte(s)) {
+				t.Errorf("mysqlJSON() returned invalid JSON: %s", s)
+			}
+		})
+	}
+}
+
+func TestNewMySQLAdapter(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	tests := []struct {
+		name    string
+		dsn     string
+		mode    config.StorageMode
+		wantErr bool
+	}{
+		{
+			name:    "Valid DSN - Changelog mode",
+			dsn:     dsn,
+			mode:    config.StorageModeChangelog,
+			wantErr: false,
+		},
+		{
+			name:    "Valid DSN - Stateful mode",
+			dsn:     dsn,
+			mode:    config.StorageModeStateful,
+			wantErr: false,
+		},
+		{
+			name:    "Invalid DSN",
+			dsn:     "invalid_dsn",
+			mode:    config.StorageModeChangelog,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewMySQLAdapter(tt.dsn, tt.mode, logger)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMySQLAdapter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if adapter != nil {
+				adapter.Close()
+			}
+		})
+	}
+}
+
+func TestMySQLAdapter_ContinuationToken(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	adapter, err := NewMySQLAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Errorf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "" {
+		t.Errorf("Expected empty token initially, got %s", token)
+	}
+
+	testToken := "test-token-123"
+	if err := adapter.SaveContinuationToken(ctx, testToken); err != nil {
+		t.Errorf("SaveContinuationToken() error = %v", err)
+	}
+
+	retrievedToken, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Errorf("GetLastContinuationToken() error = %v", err)
+	}
+	if retrievedToken != testToken {
+		t.Errorf("Expected token %s, got %s", testToken, retrievedToken)
+	}
+}
+
+func TestMySQLAdapter_WriteChanges(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	adapter, err := NewMySQLAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+		{
+			Operation:  "TUPLE_OPERATION_DELETE",
+			ObjectType: "document",
+			ObjectID:   "doc456",
+			Relation:   "editor",
+			UserType:   "user",
+			UserID:     "bob",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	err = adapter.WriteChanges(ctx, changes)
+	if err != nil {
+		t.Errorf("WriteChanges() error = %v", err)
+	}
+
+	// Verify data was stored
+	var count int
+	err = adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query changelog: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 records, got %d", count)
+	}
+}
+
+func TestMySQLAdapter_ApplyChanges(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	adapter, err := NewMySQLAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "editor",
+			UserType:   "user",
+			UserID:     "bob",
+			Timestamp:  time.Now(),
+		},
+		{
+			Operation:  "TUPLE_OPERATION_DELETE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	err = adapter.ApplyChanges(ctx, changes)
+	if err != nil {
+		t.Errorf("ApplyChanges() error = %v", err)
+	}
+
+	// Verify final state (should only have bob as editor)
+	var count int
+	err = adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query tuples: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected 1 record, got %d", count)
+	}
+
+	// Verify the remaining record is bob as editor
+	var userID, relation string
+	err = adapter.db.QueryRowContext(ctx, "SELECT user_id, relation FROM fga_tuples").Scan(&userID, &relation)
+	if err != nil {
+		t.Fatalf("Failed to query tuple details: %v", err)
+	}
+
+	if userID != "bob" || relation != "editor" {
+		t.Errorf("Expected bob as editor, got %s as %s", userID, relation)
+	}
+}
+
+func TestMySQLAdapter_ModeValidation(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	changelogAdapter, err := NewMySQLAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create changelog adapter: %v", err)
+	}
+	defer changelogAdapter.Close()
+
+	statefulAdapter, err := NewMySQLAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create stateful adapter: %v", err)
+	}
+	defer statefulAdapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "readme",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	if err := statefulAdapter.WriteChanges(ctx, changes); err == nil {
+		t.Error("Expected WriteChanges to fail in stateful mode")
+	}
+
+	if err := changelogAdapter.ApplyChanges(ctx, changes); err == nil {
+		t.Error("Expected ApplyChanges to fail in changelog mode")
+	}
+}
+
+func TestMySQLAdapter_ConditionSupport(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	// Test changelog mode with conditions
+	changelogAdapter, err := NewMySQLAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create changelog adapter: %v", err)
+	}
+	defer changelogAdapter.Close()
+
+	// Test stateful mode with conditions
+	statefulAdapter, err := NewMySQLAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create stateful adapter: %v", err)
+	}
+	defer statefulAdapter.Close()
+
+	ctx := context.Background()
+
+	// Test changes with conditions
+	changesWithConditions := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "sensitive_doc",
+			Relation:   "viewer",
+			UserType:   "employee",
+			UserID:     "alice",
+			Condition:  `{"name":"ip_allowlist","context":{"allowed_ips":["192.168.1.1"]}}`,
+			Timestamp:  time.Now(),
+		},
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "folder",
+			ObjectID:   "financial_reports",
+			Relation:   "editor",
+			UserType:   "employee",
+			UserID:     "bob",
+			Condition:  `{"name":"time_based","context":{"start_time":"09:00","end_time":"17:00"}}`,
+			Timestamp:  time.Now(),
+		},
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "public_doc",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "charlie",
+			Condition:  "", // No condition
+			Timestamp:  time.Now(),
+		},
+	}
+
+	// Test changelog mode with conditions
+	t.Run("changelog_mode_with_conditions", func(t *testing.T) {
+		err := changelogAdapter.WriteChanges(ctx, changesWithConditions)
+		if err != nil {
+			t.Errorf("WriteChanges() error = %v", err)
+		}
+
+		var conditionCount int
+		err = changelogAdapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog WHERE `condition` IS NOT NULL").Scan(&conditionCount)
+		if err != nil {
+			t.Fatalf("Failed to query changelog: %v", err)
+		}
+
+		if conditionCount != 2 {
+			t.Errorf("Expected 2 records with conditions, got %d", conditionCount)
+		}
+	})
+
+	// Test stateful mode with conditions
+	t.Run("stateful_mode_with_conditions", func(t *testing.T) {
+		err := statefulAdapter.ApplyChanges(ctx, changesWithConditions)
+		if err != nil {
+			t.Errorf("ApplyChanges() error = %v", err)
+		}
+
+		var condition sql.NullString
+		err = statefulAdapter.db.QueryRowContext(ctx,
+			"SELECT `condition` FROM fga_tuples WHERE object_type = ? AND object_id = ?",
+			"folder", "financial_reports").Scan(&condition)
+		if err != nil {
+			t.Fatalf("Failed to query tuples: %v", err)
+		}
+
+		if !condition.Valid || !strings.Contains(condition.String, "time_based") {
+			t.Errorf("Expected condition with time_based for financial_reports, got: %s", condition.String)
+		}
+
+		var totalCount int
+		err = statefulAdapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&totalCount)
+		if err != nil {
+			t.Fatalf("Failed to count total tuples: %v", err)
+		}
+
+		if totalCount != 3 {
+			t.Errorf("Expected 3 total tuples, got %d", totalCount)
+		}
+	})
+
+	// Test condition updates in stateful mode
+	t.Run("condition_updates_stateful", func(t *testing.T) {
+		updateChanges := []fetcher.ChangeEvent{
+			{
+				Operation:  "TUPLE_OPERATION_WRITE",
+				ObjectType: "document",
+				ObjectID:   "sensitive_doc",
+				Relation:   "viewer",
+				UserType:   "employee",
+				UserID:     "alice",
+				Condition:  `{"name":"geo_restriction","context":{"allowed_countries":["US","CA"]}}`,
+				Timestamp:  time.Now(),
+			},
+		}
+
+		err := statefulAdapter.ApplyChanges(ctx, updateChanges)
+		if err != nil {
+			t.Errorf("ApplyChanges() update error = %v", err)
+		}
+
+		var condition sql.NullString
+		err = statefulAdapter.db.QueryRowContext(ctx,
+			"SELECT `condition` FROM fga_tuples WHERE object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?",
+			"document", "sensitive_doc", "viewer", "employee", "alice").Scan(&condition)
+		if err != nil {
+			t.Fatalf("Failed to query updated condition: %v", err)
+		}
+
+		if !condition.Valid || !strings.Contains(condition.String, "geo_restriction") {
+			t.Errorf("Expected updated condition with geo_restriction, got: %s", condition.String)
+		}
+	})
+
+	// Test invalid JSON conditions (should be handled gracefully)
+	t.Run("invalid_json_conditions", func(t *testing.T) {
+		invalidChanges := []fetcher.ChangeEvent{
+			{
+				Operation:  "TUPLE_OPERATION_WRITE",
+				ObjectType: "file",
+				ObjectID:   "test_file",
+				Relation:   "viewer",
+				UserType:   "user",
+				UserID:     "eve",
+				Condition:  `{invalid_json: missing_quotes}`, // Invalid JSON
+				Timestamp:  time.Now(),
+			},
+		}
+
+		err := statefulAdapter.ApplyChanges(ctx, invalidChanges)
+		if err != nil {
+			t.Errorf("ApplyChanges() should handle invalid JSON gracefully, error = %v", err)
+		}
+
+		var condition sql.NullString
+		err = statefulAdapter.db.QueryRowContext(ctx,
+			"SELECT `condition` FROM fga_tuples WHERE object_type = ? AND object_id = ? AND user_id = ?",
+			"file", "test_file", "eve").Scan(&condition)
+		if err != nil {
+			t.Fatalf("Failed to query invalid condition: %v", err)
+		}
+
+		if !condition.Valid || !strings.Contains(condition.String, "invalid_json") {
+			t.Errorf("Expected invalid condition to be stored as a JSON string, got: %s", condition.String)
+		}
+	})
+}
+
+func TestMySQLAdapter_ChangesWithToken(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewMySQLAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	var txAdapter TransactionalAdapter = adapter
+	if err := txAdapter.ApplyChangesWithToken(ctx, changes, "atomic-token"); err != nil {
+		t.Fatalf("ApplyChangesWithToken() error = %v", err)
+	}
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "atomic-token" {
+		t.Errorf("Expected atomic-token, got %s", token)
+	}
+}
+
+func TestMySQLAdapter_HistoryMode(t *testing.T) {
+	dsn := skipIfNoMySQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewMySQLAdapter(dsn, config.StorageModeHistory, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 12, 0, 0, 0, time.UTC) }
+	change := func(operation string, userID string, ts time.Time) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{Operation: operation, ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: userID, Timestamp: ts}
+	}
+	changes := []fetcher.ChangeEvent{
+		change("TUPLE_OPERATION_WRITE", "anne", day(1)),
+		change("TUPLE_OPERATION_WRITE", "bob", day(2)),
+		change("TUPLE_OPERATION_DELETE", "anne", day(3)),
+		change("TUPLE_OPERATION_WRITE", "anne", day(5)),
+	}
+
+	if err := adapter.ApplyChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("ApplyChangesWithToken() error = %v", err)
+	}
+	// Replaying the batch must not duplicate intervals
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("ApplyChanges() replay error = %v", err)
+	}
+
+	users := func(at time.Time) []string {
+		tuples, err := TuplesAsOf(ctx, adapter, AsOfQuery{At: at, ObjectType: "document", ObjectID: "roadmap"})
+		if err != nil {
+			t.Fatalf("TuplesAsOf() error = %v", err)
+		}
+		var users []string
+		for _, tuple := range tuples {
+			users = append(users, tuple.UserID)
+		}
+		return users
+	}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{day(1).Add(-time.Hour), ""},
+		{day(1), "anne"},
+		{day(2), "anne,bob"},
+		{day(3), "bob"},
+		{day(4), "bob"},
+		{day(6), "anne,bob"},
+	} {
+		if got := strings.Join(users(tt.at), ","); got != tt.want {
+			t.Errorf("Tuples as of %s = %q, want %q", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["history_intervals"] != int64(3) || stats["current_tuples"] != int64(2) {
+		t.Errorf("Expected 3 intervals and 2 current tuples, got %v and %v", stats["history_intervals"], stats["current_tuples"])
+	}
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultNATSStream          = "OPENFGA_CHANGES"
+	defaultNATSSubjectTemplate = "fga.changes.{object_type}.{relation}"
+	defaultNATSBucket          = "openfga-sync"
+)
+
+// NATSAdapter implements StorageAdapter by publishing change events to NATS
+// JetStream. Subjects are rendered from a template of the change's fields and
+// every message carries a dedup ID derived from the change, so a batch that is
+// published again after a crash is dropped by the stream within its duplicate
+// window. The continuation token is kept in a JetStream KV bucket.
+type NATSAdapter struct {
+	conn            *nats.Conn
+	js              jetstream.JetStream
+	stream          string
+	subjectTemplate string
+	logger          *logrus.Logger
+	mode            config.StorageMode
+	lastToken       string
+	duplicates      atomic.Int64 // Read by GetStats from the monitoring goroutine
+	checkpoint      CheckpointStore
+}
+
+// NATSConfig represents the configuration for the NATS adapter
+type NATSConfig struct {
+	URL             string `json:"url"`
+	Stream          string `json:"stream,omitempty"`
+	SubjectTemplate string `json:"subject_template,omitempty"` // Placeholders: {object_type}, {relation}, {user_type}, {change_type}
+	DuplicateWindow string `json:"duplicate_window,omitempty"` // String format like "2m"
+	Bucket          string `json:"bucket,omitempty"`           // KV bucket holding the continuation token
+	Key             string `json:"key,omitempty"`              // KV key, defaults to the stream name
+	Token           string `json:"token,omitempty"`
+	User            string `json:"user,omitempty"`
+	Password        string `json:"password,omitempty"`
+	CredsFile       string `json:"creds_file,omitempty"`
+	RequestTimeout  string `json:"request_timeout,omitempty"` // String format like "10s"
+}
+
+// NewNATSAdapter creates a new NATS JetStream storage adapter. The stream and
+// KV bucket are created if they don't exist; existing ones are used as is.
+func NewNATSAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*NATSAdapter, error) {
+	cfg, err := parseNATSDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse NATS DSN: %w", err)
+	}
+
+	duplicateWindow := 2 * time.Minute
+	if cfg.DuplicateWindow != "" {
+		parsed, err := time.ParseDuration(cfg.DuplicateWindow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duplicate_window format: %w", err)
+		}
+		duplicateWindow = parsed
+	}
+
+	requestTimeout := 10 * time.Second
+	if cfg.RequestTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request_timeout format: %w", err)
+		}
+		requestTimeout = parsed
+	}
+
+	options := []nats.Option{nats.Name("openfga-sync"), nats.Timeout(requestTimeout)}
+	if cfg.Token != "" {
+		options = append(options, nats.Token(cfg.Token))
+	}
+	if cfg.User != "" {
+		options = append(options, nats.UserInfo(cfg.User, cfg.Password))
+	}
+	if cfg.CredsFile != "" {
+		options = append(options, nats.UserCredentials(cfg.CredsFile))
+	}
+
+	conn, err := nats.Connect(cfg.URL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if err := ensureNATSStream(ctx, js, cfg.Stream, natsSubjectFilter(cfg.SubjectTemplate), duplicateWindow); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	checkpoint, err := newNATSCheckpointStore(ctx, js, cfg.Bucket, cfg.Key)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	adapter := &NATSAdapter{
+		conn:            conn,
+		js:              js,
+		stream:          cfg.Stream,
+		subjectTemplate: cfg.SubjectTemplate,
+		logger:          logger,
+		mode:            mode,
+		checkpoint:      checkpoint,
+	}
+
+	logger.WithFields(logrus.Fields{
+		"url":              cfg.URL,
+		"stream":           cfg.Stream,
+		"subject_template": cfg.SubjectTemplate,
+		"duplicate_window": duplicateWindow,
+		"bucket":           cfg.Bucket,
+		"storage_mode":     mode,
+	}).Info("Successfully created NATS storage adapter")
+
+	return adapter, nil
+}
+
+// parseNATSDSN parses the NATS DSN configuration string
+// Supports two formats:
+// 1. Simple: a server URL (e.g., "nats://localhost:4222")
+// 2. JSON: {"url":"nats://localhost:4222","stream":"OPENFGA_CHANGES","subject_template":"fga.changes.{object_type}.{relation}","bucket":"openfga-sync"}
+func parseNATSDSN(dsn string) (*NATSConfig, error) {
+	var cfg NATSConfig
+
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("JSON DSN must contain a non-empty 'url' field")
+		}
+	} else {
+		cfg.URL = dsn
+	}
+
+	if cfg.Stream == "" {
+		cfg.Stream = defaultNATSStream
+	}
+	if cfg.SubjectTemplate == "" {
+		cfg.SubjectTemplate = defaultNATSSubjectTemplate
+	}
+	if cfg.Bucket == "" {
+		cfg.Bucket = defaultNATSBucket
+	}
+	if cfg.Key == "" {
+		cfg.Key = cfg.Stream
+	}
+
+	for _, token := range strings.Split(cfg.SubjectTemplate, ".") {
+		if token == "" || strings.ContainsAny(token, " *>") {
+			return nil, fmt.Errorf("invalid subject_template %q", cfg.SubjectTemplate)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// ensureNATSStream creates the stream capturing subjects unless it exists
+func ensureNATSStream(ctx context.Context, js jetstream.JetStream, name, subjects string, duplicateWindow time.Duration) error {
+	_, err := js.Stream(ctx, name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("failed to look up stream %s: %w", name, err)
+	}
+
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:       name,
+		Subjects:   []string{subjects},
+		Storage:    jetstream.FileStorage,
+		Duplicates: duplicateWindow,
+	})
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return fmt.Errorf("failed to create stream %s: %w", name, err)
+	}
+	return nil
+}
+
+// renderNATSSubject fills a subject template. Values are made safe to use as
+// a single subject token.
+func renderNATSSubject(template, objectType, relation, userType, changeType string) string {
+	return strings.NewReplacer(
+		"{object_type}", natsSubjectToken(objectType),
+		"{relation}", natsSubjectToken(relation),
+		"{user_type}", natsSubjectToken(userType),
+		"{change_type}", natsSubjectToken(changeType),
+	).Replace(template)
+}
+
+// natsSubjectFilter turns a subject template into the stream subject filter
+// matching every subject it can render
+func natsSubjectFilter(template string) string {
+	return strings.NewReplacer(
+		"{object_type}", "*",
+		"{relation}", "*",
+		"{user_type}", "*",
+		"{change_type}", "*",
+	).Replace(template)
+}
+
+// natsSubjectToken replaces characters that would split or wildcard a subject token
+func natsSubjectToken(value string) string {
+	if value == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// natsMessageID derives the JetStream dedup ID of a change from its content
+func natsMessageID(change fetcher.ChangeEvent) string {
+	hash := sha256.New()
+	for _, field := range []string{
+		change.ObjectType, change.ObjectID, change.Relation,
+		change.UserType, change.UserID, change.ChangeType,
+		change.Timestamp.UTC().Format(time.RFC3339Nano), change.Condition,
+	} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// WriteChanges publishes a batch of change events (changelog mode)
+func (n *NATSAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return n.publish(ctx, changes)
+}
+
+// ApplyChanges publishes a batch of change events (stateful mode). A stream
+// is an event log, so consumers rebuild state from the same events.
+func (n *NATSAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return n.publish(ctx, changes)
+}
+
+// publish sends every change asynchronously and returns once all of them have
+// been acknowledged by the stream
+func (n *NATSAdapter) publish(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	futures := make([]jetstream.PubAckFuture, 0, len(changes))
+	for _, change := range changes {
+		value, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change event: %w", err)
+		}
+
+		msg := nats.NewMsg(renderNATSSubject(n.subjectTemplate, change.ObjectType, change.Relation, change.UserType, change.ChangeType))
+		msg.Data = value
+		msg.Header.Set("change_type", change.ChangeType)
+
+		future, err := n.js.PublishMsgAsync(msg, jetstream.WithMsgID(natsMessageID(change)))
+		if err != nil {
+			return fmt.Errorf("failed to publish change to %s: %w", msg.Subject, err)
+		}
+		futures = append(futures, future)
+	}
+
+	duplicates := 0
+	for _, future := range futures {
+		select {
+		case ack := <-future.Ok():
+			if ack.Duplicate {
+				duplicates++
+			}
+		case err := <-future.Err():
+			return fmt.Errorf("failed to publish change to %s: %w", future.Msg().Subject, err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	n.duplicates.Add(int64(duplicates))
+
+	n.logger.WithFields(logrus.Fields{
+		"stream":     n.stream,
+		"messages":   len(futures),
+		"duplicates": duplicates,
+	}).Debug("Published changes to NATS JetStream")
+
+	return nil
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (n *NATSAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := n.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+	n.lastToken = token
+	return token, nil
+}
+
+// SaveContinuationToken saves the continuation token for resuming processing
+func (n *NATSAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if err := n.checkpoint.Save(ctx, token); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	n.lastToken = token
+	n.logger.WithField("token", token).Debug("Saved continuation token")
+	return nil
+}
+
+// Close drains pending publishes and closes the NATS connection
+func (n *NATSAdapter) Close() error {
+	n.logger.Info("Closing NATS adapter")
+	n.checkpoint.Close()
+	if err := n.conn.Drain(); err != nil {
+		n.conn.Close()
+		return fmt.Errorf("failed to drain NATS connection: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns statistics about the NATS adapter
+func (n *NATSAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	stats := map[string]interface{}{
+		"adapter_type":      "nats",
+		"stream":            n.stream,
+		"subject_template":  n.subjectTemplate,
+		"storage_mode":      string(n.mode),
+		"last_token":        n.lastToken,
+		"checkpoint_type":   n.checkpoint.Type(),
+		"duplicates":        n.duplicates.Load(),
+		"connection_status": n.conn.Status().String(),
+	}
+
+	stream, err := n.js.Stream(ctx, n.stream)
+	if err != nil {
+		stats["stream_error"] = err.Error()
+		return stats, nil
+	}
+	if info, err := stream.Info(ctx); err == nil {
+		stats["stream_messages"] = info.State.Msgs
+		stats["stream_bytes"] = info.State.Bytes
+	}
+
+	return stats, nil
+}
+
+// natsCheckpointStore keeps the continuation token in a JetStream KV bucket
+type natsCheckpointStore struct {
+	kv  jetstream.KeyValue
+	key string
+}
+
+func newNATSCheckpointStore(ctx context.Context, js jetstream.JetStream, bucket, key string) (*natsCheckpointStore, error) {
+	kv, err := js.KeyValue(ctx, bucket)
+	if errors.Is(err, jetstream.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(ctx, jetstream.KeyValueConfig{
+			Bucket:      bucket,
+			Description: "openfga-sync continuation tokens",
+			Storage:     jetstream.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open KV bucket %s: %w", bucket, err)
+	}
+	return &natsCheckpointStore{kv: kv, key: key}, nil
+}
+
+func (s *natsCheckpointStore) Load(ctx context.Context) (string, error) {
+	entry, err := s.kv.Get(ctx, s.key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read checkpoint key %s: %w", s.key, err)
+	}
+	return string(entry.Value()), nil
+}
+
+func (s *natsCheckpointStore) Save(ctx context.Context, token string) error {
+	if _, err := s.kv.PutString(ctx, s.key, token); err != nil {
+		return fmt.Errorf("failed to write checkpoint key %s: %w", s.key, err)
+	}
+	return nil
+}
+
+func (s *natsCheckpointStore) Type() string { return "nats-kv" }
+
+func (s *natsCheckpointStore) Close() error { return nil }
@@ -0,0 +1,488 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeNATSMsg is a message stored in a fakeNATSServer stream
+type fakeNATSMsg struct {
+	Subject  string    `json:"subject"`
+	Sequence uint64    `json:"seq"`
+	Data     []byte    `json:"data,omitempty"`
+	Time     time.Time `json:"time"`
+	header   nats.Header
+}
+
+// fakeNATSStream is a JetStream stream held by fakeNATSServer
+type fakeNATSStream struct {
+	config    map[string]interface{}
+	subjects  []string
+	window    time.Duration
+	msgs      []fakeNATSMsg
+	msgIDs    map[string]uint64
+	published []string // Nats-Msg-Id of every publish, stored or dropped
+}
+
+// fakeNATSSub is a client subscription on fakeNATSServer
+type fakeNATSSub struct {
+	conn    *fakeNATSConn
+	sid     string
+	subject string
+}
+
+// fakeNATSConn serializes writes to one client connection
+type fakeNATSConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *fakeNATSConn) send(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	io.WriteString(c.Conn, s)
+}
+
+// fakeNATSServer is an in-process NATS server that speaks the client protocol
+// and the slice of the JetStream API a publisher with a KV checkpoint needs:
+// stream info/create, message get, and publish acks with Nats-Msg-Id dedup.
+//
+// It stands in for an embedded nats-server, which would pull the whole server
+// and its dependencies into the module. Its dedup follows the server's rules
+// (a repeated Nats-Msg-Id within the stream's duplicate window is acked as a
+// duplicate and not stored), and it records every message ID it receives, so
+// tests assert on what the adapter sends rather than on the fake's behaviour.
+type fakeNATSServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu      sync.Mutex
+	subs    []*fakeNATSSub
+	streams map[string]*fakeNATSStream
+}
+
+func newFakeNATSServer(t *testing.T) *fakeNATSServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := &fakeNATSServer{t: t, listener: listener, streams: make(map[string]*fakeNATSStream)}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeNATSServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeNATSServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(&fakeNATSConn{Conn: conn})
+	}
+}
+
+func (s *fakeNATSServer) handle(conn *fakeNATSConn) {
+	defer conn.Close()
+	defer s.unsubscribeAll(conn)
+
+	conn.send(`INFO {"server_id":"fake","version":"2.10.0","proto":1,"headers":true,"max_payload":1048576,"jetstream":true}` + "\r\n")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT", "PONG":
+		case "PING":
+			conn.send("PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs = append(s.subs, &fakeNATSSub{conn: conn, subject: fields[1], sid: fields[len(fields)-1]})
+			s.mu.Unlock()
+		case "UNSUB":
+			s.unsubscribe(conn, fields[1])
+		case "PUB", "HPUB":
+			headerSize := 0
+			if fields[0] == "HPUB" {
+				headerSize, _ = strconv.Atoi(fields[len(fields)-2])
+			}
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+
+			reply := ""
+			if (fields[0] == "PUB" && len(fields) == 4) || (fields[0] == "HPUB" && len(fields) == 5) {
+				reply = fields[2]
+			}
+			s.publish(fields[1], reply, payload[:headerSize], payload[headerSize:size])
+		default:
+			s.t.Logf("fake nats: unsupported operation %q", fields[0])
+			return
+		}
+	}
+}
+
+func (s *fakeNATSServer) unsubscribe(conn *fakeNATSConn, sid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sub := range s.subs {
+		if sub.conn == conn && sub.sid == sid {
+			s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *fakeNATSServer) unsubscribeAll(conn *fakeNATSConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := s.subs[:0]
+	for _, sub := range s.subs {
+		if sub.conn != conn {
+			subs = append(subs, sub)
+		}
+	}
+	s.subs = subs
+}
+
+// publish routes a client message to the JetStream API, a stream, or plain
+// subscribers
+func (s *fakeNATSServer) publish(subject, reply string, header, data []byte) {
+	if strings.HasPrefix(subject, "$JS.API.") {
+		response, _ := json.Marshal(s.api(strings.TrimPrefix(subject, "$JS.API."), data))
+		s.deliver(reply, response)
+		return
+	}
+
+	if ack, ok := s.store(subject, header, data); ok {
+		if reply != "" {
+			response, _ := json.Marshal(ack)
+			s.deliver(reply, response)
+		}
+		return
+	}
+
+	s.deliver(subject, data)
+}
+
+func (s *fakeNATSServer) deliver(subject string, data []byte) {
+	s.mu.Lock()
+	var matched []*fakeNATSSub
+	for _, sub := range s.subs {
+		if fakeNATSSubjectMatches(sub.subject, subject) {
+			matched = append(matched, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range matched {
+		sub.conn.send(fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", subject, sub.sid, len(data), data))
+	}
+}
+
+// store appends a message to the stream capturing subject, dropping it if its
+// Nats-Msg-Id was stored within the stream's duplicate window
+func (s *fakeNATSServer) store(subject string, header, data []byte) (map[string]interface{}, bool) {
+	var hdr nats.Header
+	if len(header) > 0 {
+		hdr, _ = nats.DecodeHeadersMsg(header)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, stream := range s.streams {
+		captured := false
+		for _, pattern := range stream.subjects {
+			captured = captured || fakeNATSSubjectMatches(pattern, subject)
+		}
+		if !captured {
+			continue
+		}
+
+		msgID := hdr.Get(nats.MsgIdHdr)
+		stream.published = append(stream.published, msgID)
+		if seq, ok := stream.msgIDs[msgID]; ok && msgID != "" && time.Since(stream.msgs[seq-1].Time) < stream.window {
+			return map[string]interface{}{"stream": name, "seq": seq, "duplicate": true}, true
+		}
+
+		seq := uint64(len(stream.msgs) + 1)
+		stream.msgs = append(stream.msgs, fakeNATSMsg{Subject: subject, Sequence: seq, Data: append([]byte(nil), data...), Time: time.Now().UTC(), header: hdr})
+		if msgID != "" {
+			stream.msgIDs[msgID] = seq
+		}
+		return map[string]interface{}{"stream": name, "seq": seq}, true
+	}
+	return nil, false
+}
+
+// api answers a JetStream API request
+func (s *fakeNATSServer) api(endpoint string, request []byte) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apiError := func(code, errCode int, description string) map[string]interface{} {
+		return map[string]interface{}{"error": map[string]interface{}{"code": code, "err_code": errCode, "description": description}}
+	}
+
+	switch {
+	case endpoint == "INFO":
+		return map[string]interface{}{"type": "io.nats.jetstream.api.v1.account_info_response", "streams": len(s.streams), "api": map[string]interface{}{"level": 1}}
+
+	case strings.HasPrefix(endpoint, "STREAM.INFO."):
+		stream, ok := s.streams[strings.TrimPrefix(endpoint, "STREAM.INFO.")]
+		if !ok {
+			return apiError(404, 10059, "stream not found")
+		}
+		return fakeNATSStreamInfo(stream)
+
+	case strings.HasPrefix(endpoint, "STREAM.CREATE."):
+		name := strings.TrimPrefix(endpoint, "STREAM.CREATE.")
+		if _, ok := s.streams[name]; ok {
+			return apiError(400, 10058, "stream name already in use")
+		}
+		var cfg map[string]interface{}
+		if err := json.Unmarshal(request, &cfg); err != nil {
+			return apiError(400, 10025, err.Error())
+		}
+		// Direct gets use a binary reply format; make clients use the JSON API
+		cfg["allow_direct"] = false
+		stream := &fakeNATSStream{config: cfg, msgIDs: make(map[string]uint64)}
+		if window, ok := cfg["duplicate_window"].(float64); ok {
+			stream.window = time.Duration(window)
+		}
+		for _, subject := range cfg["subjects"].([]interface{}) {
+			stream.subjects = append(stream.subjects, subject.(string))
+		}
+		s.streams[name] = stream
+		return fakeNATSStreamInfo(stream)
+
+	case strings.HasPrefix(endpoint, "STREAM.MSG.GET."):
+		stream, ok := s.streams[strings.TrimPrefix(endpoint, "STREAM.MSG.GET.")]
+		if !ok {
+			return apiError(404, 10059, "stream not found")
+		}
+		var get struct {
+			LastFor string `json:"last_by_subj"`
+		}
+		json.Unmarshal(request, &get)
+		for i := len(stream.msgs) - 1; i >= 0; i-- {
+			if stream.msgs[i].Subject == get.LastFor {
+				return map[string]interface{}{"type": "io.nats.jetstream.api.v1.stream_msg_get_response", "message": stream.msgs[i]}
+			}
+		}
+		return apiError(404, 10037, "no message found")
+	}
+
+	return apiError(400, 10003, "unsupported request "+endpoint)
+}
+
+func fakeNATSStreamInfo(stream *fakeNATSStream) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "io.nats.jetstream.api.v1.stream_info_response",
+		"config":  stream.config,
+		"created": time.Now().UTC(),
+		"state":   map[string]interface{}{"messages": len(stream.msgs), "last_seq": len(stream.msgs)},
+	}
+}
+
+func (s *fakeNATSServer) messages(stream string) []fakeNATSMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.streams[stream]; ok {
+		return append([]fakeNATSMsg(nil), st.msgs...)
+	}
+	return nil
+}
+
+// stream returns the window and every published message ID of a stream
+func (s *fakeNATSServer) stream(name string) (time.Duration, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.streams[name]; ok {
+		return st.window, append([]string(nil), st.published...)
+	}
+	return 0, nil
+}
+
+// fakeNATSSubjectMatches matches a subject against a pattern with * and > wildcards
+func fakeNATSSubjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+func TestParseNATSDSN(t *testing.T) {
+	cfg, err := parseNATSDSN("nats://localhost:4222")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if cfg.Stream != defaultNATSStream || cfg.SubjectTemplate != defaultNATSSubjectTemplate || cfg.Bucket != defaultNATSBucket || cfg.Key != defaultNATSStream {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+
+	cfg, err = parseNATSDSN(`{"url":"nats://localhost:4222","stream":"EDGE","subject_template":"edge.{change_type}.{object_type}"}`)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON DSN: %v", err)
+	}
+	if cfg.Stream != "EDGE" || cfg.Key != "EDGE" || cfg.SubjectTemplate != "edge.{change_type}.{object_type}" {
+		t.Errorf("Unexpected JSON DSN config: %+v", cfg)
+	}
+
+	for _, dsn := range []string{`{"stream":"EDGE"}`, `{"url":"nats://localhost:4222","subject_template":"fga..{relation}"}`, `{"url":"nats://localhost:4222","subject_template":"fga.>"}`} {
+		if _, err := parseNATSDSN(dsn); err == nil {
+			t.Errorf("Expected error for DSN %s", dsn)
+		}
+	}
+}
+
+func TestRenderNATSSubject(t *testing.T) {
+	subject := renderNATSSubject(defaultNATSSubjectTemplate, "document", "viewer", "user", "tuple_write")
+	if subject != "fga.changes.document.viewer" {
+		t.Errorf("Expected fga.changes.document.viewer, got %q", subject)
+	}
+
+	// Values can't split or wildcard the subject
+	subject = renderNATSSubject("fga.{object_type}.{relation}.{user_type}", "team.v2", "", "user*>", "tuple_write")
+	if subject != "fga.team_v2._.user__" {
+		t.Errorf("Expected fga.team_v2._.user__, got %q", subject)
+	}
+
+	if filter := natsSubjectFilter(defaultNATSSubjectTemplate); filter != "fga.changes.*.*" {
+		t.Errorf("Expected stream filter fga.changes.*.*, got %q", filter)
+	}
+}
+
+func TestNATSAdapter_PublishesDedupedChanges(t *testing.T) {
+	server := newFakeNATSServer(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dsn := fmt.Sprintf(`{"url":%q,"request_timeout":"5s"}`, server.url())
+	adapter, err := NewNATSAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create NATS adapter: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load continuation token: %v", err)
+	}
+	if token != "" {
+		t.Errorf("Expected no continuation token, got %q", token)
+	}
+
+	now := time.Now().UTC()
+	changes := []fetcher.ChangeEvent{
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_write", Timestamp: now},
+		{ObjectType: "folder", ObjectID: "eng", Relation: "owner", UserType: "group", UserID: "eng#member", ChangeType: "tuple_write", Timestamp: now},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_delete", Timestamp: now.Add(time.Second)},
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to publish changes: %v", err)
+	}
+
+	// A batch replayed after a crash carries the same message IDs, which the
+	// stream drops within its duplicate window
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to republish changes: %v", err)
+	}
+	window, published := server.stream(defaultNATSStream)
+	if window != 2*time.Minute {
+		t.Errorf("Expected the stream to be created with a 2m duplicate window, got %s", window)
+	}
+	if len(published) != 2*len(changes) {
+		t.Fatalf("Expected %d publishes, got %d", 2*len(changes), len(published))
+	}
+	for i := range changes {
+		if published[i] == "" || published[i] != published[len(changes)+i] {
+			t.Errorf("Expected the replay to reuse message ID %q, got %q", published[i], published[len(changes)+i])
+		}
+	}
+	if adapter.duplicates.Load() != int64(len(changes)) {
+		t.Errorf("Expected %d duplicates, got %d", len(changes), adapter.duplicates.Load())
+	}
+
+	msgs := server.messages(defaultNATSStream)
+	if len(msgs) != len(changes) {
+		t.Fatalf("Expected %d stored messages, got %d", len(changes), len(msgs))
+	}
+	expectedSubjects := []string{"fga.changes.document.viewer", "fga.changes.folder.owner", "fga.changes.document.viewer"}
+	for i, msg := range msgs {
+		if msg.Subject != expectedSubjects[i] {
+			t.Errorf("Expected subject %s, got %s", expectedSubjects[i], msg.Subject)
+		}
+		var change fetcher.ChangeEvent
+		if err := json.Unmarshal(msg.Data, &change); err != nil {
+			t.Fatalf("Message data is not a change event: %v", err)
+		}
+		if change.ChangeType != changes[i].ChangeType || msg.header.Get("change_type") != change.ChangeType {
+			t.Errorf("Expected change type %s, got %s (header %q)", changes[i].ChangeType, change.ChangeType, msg.header.Get("change_type"))
+		}
+		if msg.header.Get(nats.MsgIdHdr) != natsMessageID(changes[i]) {
+			t.Errorf("Expected dedup ID derived from the change, got %q", msg.header.Get(nats.MsgIdHdr))
+		}
+	}
+
+	if err := adapter.SaveContinuationToken(ctx, "token-1"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Failed to close adapter: %v", err)
+	}
+
+	// The checkpoint lives in the KV bucket and survives a restart
+	reopened, err := NewNATSAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen NATS adapter: %v", err)
+	}
+	defer reopened.Close()
+
+	token, err = reopened.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load continuation token: %v", err)
+	}
+	if token != "token-1" {
+		t.Errorf("Expected token-1, got %q", token)
+	}
+}
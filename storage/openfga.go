@@ -27,19 +27,21 @@ type OpenFGAAdapter struct {
 	maxRetries     int
 	retryDelay     time.Duration
 	batchSize      int
+	checkpoint     CheckpointStore
 }
 
 // OpenFGAConfig represents the configuration for OpenFGA adapter
 type OpenFGAConfig struct {
-	Endpoint             string     `json:"endpoint"`
-	StoreID              string     `json:"store_id"`
-	Token                string     `json:"token"`
-	AuthorizationModelID string     `json:"authorization_model_id,omitempty"`
-	RequestTimeout       string     `json:"request_timeout,omitempty"` // String format like "30s"
-	MaxRetries           int        `json:"max_retries,omitempty"`
-	RetryDelay           string     `json:"retry_delay,omitempty"` // String format like "1s"
-	BatchSize            int        `json:"batch_size,omitempty"`
-	OIDC                 OIDCConfig `json:"oidc,omitempty"`
+	Endpoint             string           `json:"endpoint"`
+	StoreID              string           `json:"store_id"`
+	Token                string           `json:"token"`
+	AuthorizationModelID string           `json:"authorization_model_id,omitempty"`
+	RequestTimeout       string           `json:"request_timeout,omitempty"` // String format like "30s"
+	MaxRetries           int              `json:"max_retries,omitempty"`
+	RetryDelay           string           `json:"retry_delay,omitempty"` // String format like "1s"
+	BatchSize            int              `json:"batch_size,omitempty"`
+	OIDC                 OIDCConfig       `json:"oidc,omitempty"`
+	Checkpoint           CheckpointConfig `json:"checkpoint,omitempty"`
 }
 
 // OIDCConfig contains OIDC authentication configuration
@@ -144,6 +146,17 @@ func NewOpenFGAAdapter(dsn string, mode config.StorageMode, logger *logrus.Logge
 		batchSize = cfg.BatchSize
 	}
 
+	// Continuation tokens can't be stored in the target store itself, so they
+	// live in a separate checkpoint store. It should be persistent, since the
+	// in-memory default loses the position on restart
+	checkpoint, err := NewCheckpointStore(cfg.Checkpoint, "openfga:"+cfg.StoreID, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+	if checkpoint.Type() == "memory" {
+		logger.WithField("target_store_id", cfg.StoreID).Warn("OpenFGA backend keeps its continuation token in memory, so every restart replays the whole changelog; configure a file, sqlite or postgres checkpoint in the DSN")
+	}
+
 	adapter := &OpenFGAAdapter{
 		client:         fgaClient,
 		targetStoreID:  cfg.StoreID,
@@ -153,10 +166,12 @@ func NewOpenFGAAdapter(dsn string, mode config.StorageMode, logger *logrus.Logge
 		maxRetries:     maxRetries,
 		retryDelay:     retryDelay,
 		batchSize:      batchSize,
+		checkpoint:     checkpoint,
 	}
 
 	// Test connection
 	if err := adapter.testConnection(); err != nil {
+		checkpoint.Close()
 		return nil, fmt.Errorf("failed to connect to target OpenFGA instance: %w", err)
 	}
 
@@ -165,6 +180,7 @@ func NewOpenFGAAdapter(dsn string, mode config.StorageMode, logger *logrus.Logge
 		"target_endpoint": cfg.Endpoint,
 		"storage_mode":    mode,
 		"batch_size":      batchSize,
+		"checkpoint_type": checkpoint.Type(),
 	}).Info("Successfully created OpenFGA storage adapter")
 
 	return adapter, nil
@@ -175,6 +191,7 @@ func NewOpenFGAAdapter(dsn string, mode config.StorageMode, logger *logrus.Logge
 // 1. Simple: "endpoint/store_id" (e.g., "http://localhost:8080/store123")
 // 2. JSON: {"endpoint":"http://localhost:8080","store_id":"store123","token":"token123"}
 // 3. JSON with OIDC: {"endpoint":"...","store_id":"...","oidc":{"issuer":"...","audience":"...","client_id":"...","client_secret":"..."}}
+// 4. JSON with checkpoint: {"endpoint":"...","store_id":"...","checkpoint":{"type":"file","dsn":"/var/lib/openfga-sync/token"}}
 func parseOpenFGADSN(dsn string) (*OpenFGAConfig, error) {
 	// If DSN starts with {, treat it as JSON format
 	if strings.HasPrefix(dsn, "{") {
@@ -444,22 +461,38 @@ func (o *OpenFGAAdapter) executeWrite(ctx context.Context, writes []client.Clien
 }
 
 // GetLastContinuationToken retrieves the last processed continuation token
-// Note: For OpenFGA adapter, we store this in memory (not persistent across restarts)
+// from the configured checkpoint store (in memory if none is configured)
 func (o *OpenFGAAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
-	return o.lastToken, nil
+	if o.checkpoint == nil {
+		return o.lastToken, nil
+	}
+
+	token, err := o.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+	o.lastToken = token
+	return token, nil
 }
 
 // SaveContinuationToken saves the continuation token for resuming processing
-// Note: For OpenFGA adapter, we store this in memory (not persistent across restarts)
 func (o *OpenFGAAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if o.checkpoint != nil {
+		if err := o.checkpoint.Save(ctx, token); err != nil {
+			return fmt.Errorf("failed to save continuation token: %w", err)
+		}
+	}
 	o.lastToken = token
 	o.logger.WithField("token", token).Debug("Saved continuation token")
 	return nil
 }
 
-// Close closes the OpenFGA adapter (no-op for HTTP client)
+// Close closes the OpenFGA adapter and its checkpoint store
 func (o *OpenFGAAdapter) Close() error {
 	o.logger.Info("Closing OpenFGA adapter")
+	if o.checkpoint != nil {
+		return o.checkpoint.Close()
+	}
 	return nil
 }
 
@@ -473,6 +506,10 @@ func (o *OpenFGAAdapter) GetStats(ctx context.Context) (map[string]interface{},
 		"request_timeout": o.requestTimeout.String(),
 		"max_retries":     o.maxRetries,
 		"batch_size":      o.batchSize,
+		"checkpoint_type": "memory",
+	}
+	if o.checkpoint != nil {
+		stats["checkpoint_type"] = o.checkpoint.Type()
 	}
 
 	// Try to get some basic stats from the target store if client is available
@@ -91,6 +91,16 @@ func TestParseOpenFGADSN(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "json format with checkpoint",
+			dsn:  `{"endpoint":"http://localhost:8080","store_id":"store123","checkpoint":{"type":"file","dsn":"/var/lib/openfga-sync/token"}}`,
+			want: &OpenFGAConfig{
+				Endpoint:   "http://localhost:8080",
+				StoreID:    "store123",
+				Checkpoint: CheckpointConfig{Type: "file", DSN: "/var/lib/openfga-sync/token"},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "https endpoint with store ID",
 			dsn:     "https://api.openfga.example.com/01HXXX-STORE-ID",
@@ -136,6 +146,10 @@ func TestParseOpenFGADSN(t *testing.T) {
 				if tt.want.BatchSize != 0 && got.BatchSize != tt.want.BatchSize {
 					t.Errorf("parseOpenFGADSN() batch_size = %v, want %v", got.BatchSize, tt.want.BatchSize)
 				}
+
+				if got.Checkpoint != tt.want.Checkpoint {
+					t.Errorf("parseOpenFGADSN() checkpoint = %+v, want %+v", got.Checkpoint, tt.want.Checkpoint)
+				}
 			}
 		})
 	}
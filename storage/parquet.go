@@ -0,0 +1,532 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	defaultParquetMaxRows = 10000
+	defaultParquetMaxAge  = 5 * time.Minute
+)
+
+// parquetChangeRow is one change in a Parquet file. The columns mirror the
+// fga_changelog table of the database backends.
+type parquetChangeRow struct {
+	ChangeType string  `parquet:"name=change_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	ObjectType string  `parquet:"name=object_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	ObjectID   string  `parquet:"name=object_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Relation   string  `parquet:"name=relation, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	UserType   string  `parquet:"name=user_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	UserID     string  `parquet:"name=user_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Timestamp  int64   `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Condition  *string `parquet:"name=condition, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	RawEvent   string  `parquet:"name=raw_event, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// ParquetAdapter implements StorageAdapter by buffering change events and
+// writing them as Parquet files partitioned by date=YYYY-MM-DD/object_type=T
+// under a local directory, optionally uploading each file to S3-compatible
+// object storage. The buffer is flushed once it holds max_rows rows or its
+// oldest row is max_age old. The continuation token is only saved once every
+// change before it has been written (and uploaded), so a crash repeats
+// buffered changes instead of losing them.
+type ParquetAdapter struct {
+	mu             sync.Mutex
+	dir            string
+	maxRows        int
+	maxAge         time.Duration
+	compression    parquet.CompressionCodec
+	s3             *minio.Client
+	s3Config       *ParquetS3Config
+	buffer         []parquetChangeRow
+	bufferedSince  time.Time
+	pendingUploads []string // Local files written but not yet uploaded
+	pendingToken   string   // Token to save once the buffer is flushed
+	filesWritten   int
+	rowsWritten    int
+	logger         *logrus.Logger
+	mode           config.StorageMode
+	lastToken      string
+	checkpoint     CheckpointStore
+	stop           chan struct{}
+	done           chan struct{}
+}
+
+// ParquetConfig represents the configuration for the Parquet adapter
+type ParquetConfig struct {
+	Dir         string           `json:"dir"`
+	MaxRows     int              `json:"max_rows,omitempty"`    // Flush once this many rows are buffered, defaults to 10000
+	MaxAge      string           `json:"max_age,omitempty"`     // Flush once the oldest buffered row is this old, defaults to "5m"
+	Compression string           `json:"compression,omitempty"` // snappy (default), gzip, zstd or none
+	S3          *ParquetS3Config `json:"s3,omitempty"`
+	Checkpoint  CheckpointConfig `json:"checkpoint,omitempty"` // Defaults to a file at <dir>/_checkpoint
+}
+
+// ParquetS3Config configures the upload of Parquet files to S3-compatible storage
+type ParquetS3Config struct {
+	Endpoint  string `json:"endpoint"` // host[:port], e.g. "s3.amazonaws.com" or "minio:9000"
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix,omitempty"` // Key prefix prepended to the partition path
+	Region    string `json:"region,omitempty"`
+	AccessKey string `json:"access_key,omitempty"` // Read from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY if empty
+	SecretKey string `json:"secret_key,omitempty"`
+	UseSSL    bool   `json:"use_ssl,omitempty"`
+	KeepLocal bool   `json:"keep_local,omitempty"` // Keep local files after upload
+}
+
+// NewParquetAdapter creates a new Parquet storage adapter
+func NewParquetAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*ParquetAdapter, error) {
+	cfg, err := parseParquetDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Parquet DSN: %w", err)
+	}
+
+	maxAge := defaultParquetMaxAge
+	if cfg.MaxAge != "" {
+		maxAge, err = time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age format: %w", err)
+		}
+		if maxAge <= 0 {
+			return nil, fmt.Errorf("max_age must be positive")
+		}
+	}
+
+	var compression parquet.CompressionCodec
+	switch strings.ToLower(cfg.Compression) {
+	case "", "snappy":
+		compression = parquet.CompressionCodec_SNAPPY
+	case "gzip":
+		compression = parquet.CompressionCodec_GZIP
+	case "zstd":
+		compression = parquet.CompressionCodec_ZSTD
+	case "none":
+		compression = parquet.CompressionCodec_UNCOMPRESSED
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", cfg.Compression)
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", cfg.Dir, err)
+	}
+
+	var s3Client *minio.Client
+	if cfg.S3 != nil {
+		creds := credentials.NewEnvAWS()
+		if cfg.S3.AccessKey != "" {
+			creds = credentials.NewStaticV4(cfg.S3.AccessKey, cfg.S3.SecretKey, "")
+		}
+		s3Client, err = minio.New(cfg.S3.Endpoint, &minio.Options{
+			Creds:  creds,
+			Secure: cfg.S3.UseSSL,
+			Region: cfg.S3.Region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 client: %w", err)
+		}
+	}
+
+	checkpoint, err := NewCheckpointStore(cfg.Checkpoint, "parquet:"+cfg.Dir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	adapter := &ParquetAdapter{
+		dir:         cfg.Dir,
+		maxRows:     cfg.MaxRows,
+		maxAge:      maxAge,
+		compression: compression,
+		s3:          s3Client,
+		s3Config:    cfg.S3,
+		logger:      logger,
+		mode:        mode,
+		checkpoint:  checkpoint,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go adapter.flushLoop()
+
+	fields := logrus.Fields{
+		"dir":             cfg.Dir,
+		"max_rows":        cfg.MaxRows,
+		"max_age":         maxAge,
+		"compression":     compression.String(),
+		"storage_mode":    mode,
+		"checkpoint_type": checkpoint.Type(),
+	}
+	if cfg.S3 != nil {
+		fields["s3_endpoint"] = cfg.S3.Endpoint
+		fields["s3_bucket"] = cfg.S3.Bucket
+	}
+	logger.WithFields(fields).Info("Successfully created Parquet storage adapter")
+
+	return adapter, nil
+}
+
+// parseParquetDSN parses the Parquet DSN configuration string
+// Supports two formats:
+// 1. Simple: the output directory (e.g., "/var/lib/openfga-sync/parquet")
+// 2. JSON: {"dir":"/var/lib/openfga-sync/parquet","max_rows":10000,"max_age":"5m","s3":{"endpoint":"minio:9000","bucket":"fga-lake"}}
+func parseParquetDSN(dsn string) (*ParquetConfig, error) {
+	var cfg ParquetConfig
+
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+	} else {
+		cfg.Dir = dsn
+	}
+
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("DSN must contain a non-empty directory")
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultParquetMaxRows
+	}
+	// Keep the token next to the files; Hive-style readers skip "_" files
+	if cfg.Checkpoint.Type == "" {
+		cfg.Checkpoint = CheckpointConfig{Type: "file", DSN: filepath.Join(cfg.Dir, "_checkpoint")}
+	}
+	if cfg.S3 != nil && (cfg.S3.Endpoint == "" || cfg.S3.Bucket == "") {
+		return nil, fmt.Errorf("s3 requires non-empty 'endpoint' and 'bucket' fields")
+	}
+
+	return &cfg, nil
+}
+
+// WriteChanges buffers a batch of change events (changelog mode)
+func (p *ParquetAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return p.bufferChanges(ctx, changes)
+}
+
+// ApplyChanges buffers a batch of change events (stateful mode). Parquet
+// files are an append-only history, so state is rebuilt from the same events.
+func (p *ParquetAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return p.bufferChanges(ctx, changes)
+}
+
+// bufferChanges adds changes to the buffer and flushes it when full. A full
+// buffer that can't be flushed rejects the batch, so a retried batch is never
+// buffered twice.
+func (p *ParquetAdapter) bufferChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.buffer) >= p.maxRows {
+		if err := p.flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	if len(p.buffer) == 0 {
+		p.bufferedSince = time.Now()
+	}
+	for _, change := range changes {
+		row, err := newParquetChangeRow(change)
+		if err != nil {
+			return err
+		}
+		p.buffer = append(p.buffer, row)
+	}
+
+	if len(p.buffer) >= p.maxRows {
+		if err := p.flush(ctx); err != nil {
+			p.logger.WithError(err).Warn("Failed to flush Parquet buffer, will retry")
+		}
+	}
+	return nil
+}
+
+// newParquetChangeRow converts a change event to a Parquet row
+func newParquetChangeRow(change fetcher.ChangeEvent) (parquetChangeRow, error) {
+	rawEvent, err := json.Marshal(change)
+	if err != nil {
+		return parquetChangeRow{}, fmt.Errorf("failed to marshal change event: %w", err)
+	}
+
+	row := parquetChangeRow{
+		ChangeType: change.Operation,
+		ObjectType: change.ObjectType,
+		ObjectID:   change.ObjectID,
+		Relation:   change.Relation,
+		UserType:   change.UserType,
+		UserID:     change.UserID,
+		Timestamp:  change.Timestamp.UnixMicro(),
+		RawEvent:   string(rawEvent),
+	}
+	if change.Condition != "" {
+		condition := change.Condition
+		row.Condition = &condition
+	}
+	return row, nil
+}
+
+// flushLoop flushes the buffer once its oldest row reaches max_age, even
+// when no new changes arrive
+func (p *ParquetAdapter) flushLoop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.maxAge / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			due := (len(p.buffer) > 0 && time.Since(p.bufferedSince) >= p.maxAge) || len(p.pendingUploads) > 0
+			if due {
+				if err := p.flush(context.Background()); err != nil {
+					p.logger.WithError(err).Warn("Failed to flush Parquet buffer, will retry")
+				}
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// flush writes the buffered rows to one file per partition, uploads pending
+// files and then saves the pending continuation token. Callers hold p.mu.
+func (p *ParquetAdapter) flush(ctx context.Context) error {
+	if len(p.buffer) > 0 {
+		files, err := p.writeFiles()
+		if err != nil {
+			return err
+		}
+		p.logger.WithFields(logrus.Fields{
+			"rows":  len(p.buffer),
+			"files": len(files),
+		}).Info("Flushed changes to Parquet files")
+
+		p.rowsWritten += len(p.buffer)
+		p.filesWritten += len(files)
+		p.buffer = nil
+		if p.s3 != nil {
+			p.pendingUploads = append(p.pendingUploads, files...)
+		}
+	}
+
+	for len(p.pendingUploads) > 0 {
+		if err := p.upload(ctx, p.pendingUploads[0]); err != nil {
+			return err
+		}
+		p.pendingUploads = p.pendingUploads[1:]
+	}
+
+	if p.pendingToken != "" {
+		if err := p.saveToken(ctx, p.pendingToken); err != nil {
+			return err
+		}
+		p.pendingToken = ""
+	}
+	return nil
+}
+
+// writeFiles writes the buffer to new files under
+// date=YYYY-MM-DD/object_type=T and returns their paths. Every file is
+// written before any is renamed into place, so a failed flush leaves no rows
+// behind for the retry to write again.
+func (p *ParquetAdapter) writeFiles() ([]string, error) {
+	partitions := make(map[string][]parquetChangeRow)
+	for _, row := range p.buffer {
+		date := time.UnixMicro(row.Timestamp).UTC().Format("2006-01-02")
+		partition := path.Join("date="+date, "object_type="+url.PathEscape(row.ObjectType))
+		partitions[partition] = append(partitions[partition], row)
+	}
+
+	names := make([]string, 0, len(partitions))
+	for partition := range partitions {
+		names = append(names, partition)
+	}
+	sort.Strings(names)
+
+	stamp := time.Now().UTC().Format("20060102T150405.000000000Z")
+	files := make([]string, 0, len(names))
+	defer func() {
+		// Renamed files are gone from their temporary paths
+		for _, file := range files {
+			os.Remove(file + ".tmp")
+		}
+	}()
+	for _, partition := range names {
+		dir := filepath.Join(p.dir, filepath.FromSlash(partition))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create partition directory %s: %w", dir, err)
+		}
+
+		file := filepath.Join(dir, "part-"+stamp+".parquet")
+		files = append(files, file)
+		if err := p.writeFile(file+".tmp", partitions[partition]); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, file := range files {
+		if err := os.Rename(file+".tmp", file); err != nil {
+			for _, renamed := range files[:i] {
+				os.Remove(renamed)
+			}
+			return nil, fmt.Errorf("failed to rename %s.tmp: %w", file, err)
+		}
+	}
+	return files, nil
+}
+
+// writeFile writes rows to tmpFile and fsyncs it
+func (p *ParquetAdapter) writeFile(tmpFile string, rows []parquetChangeRow) error {
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpFile, err)
+	}
+	defer out.Close()
+
+	pw, err := writer.NewParquetWriterFromWriter(out, new(parquetChangeRow), 1)
+	if err != nil {
+		return fmt.Errorf("failed to create Parquet writer: %w", err)
+	}
+	pw.CompressionType = p.compression
+
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			return fmt.Errorf("failed to write Parquet row: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file %s: %w", tmpFile, err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmpFile, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpFile, err)
+	}
+	return nil
+}
+
+// upload copies a local file to the bucket, keyed by its partition path
+func (p *ParquetAdapter) upload(ctx context.Context, file string) error {
+	rel, err := filepath.Rel(p.dir, file)
+	if err != nil {
+		return fmt.Errorf("failed to resolve object key for %s: %w", file, err)
+	}
+	key := path.Join(p.s3Config.Prefix, filepath.ToSlash(rel))
+
+	_, err = p.s3.FPutObject(ctx, p.s3Config.Bucket, key, file, minio.PutObjectOptions{
+		ContentType: "application/vnd.apache.parquet",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to s3://%s/%s: %w", file, p.s3Config.Bucket, key, err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"bucket": p.s3Config.Bucket,
+		"key":    key,
+	}).Debug("Uploaded Parquet file")
+
+	if !p.s3Config.KeepLocal {
+		if err := os.Remove(file); err != nil {
+			p.logger.WithError(err).WithField("file", file).Warn("Failed to remove uploaded Parquet file")
+		}
+	}
+	return nil
+}
+
+// GetLastContinuationToken retrieves the last flushed continuation token
+func (p *ParquetAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := p.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+
+	p.mu.Lock()
+	p.lastToken = token
+	p.mu.Unlock()
+	return token, nil
+}
+
+// SaveContinuationToken saves the token right away if nothing is buffered,
+// and otherwise holds it back until the buffer is flushed
+func (p *ParquetAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.buffer) > 0 || len(p.pendingUploads) > 0 {
+		p.pendingToken = token
+		return nil
+	}
+	return p.saveToken(ctx, token)
+}
+
+// saveToken persists token. Callers hold p.mu.
+func (p *ParquetAdapter) saveToken(ctx context.Context, token string) error {
+	if err := p.checkpoint.Save(ctx, token); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	p.lastToken = token
+	p.logger.WithField("token", token).Debug("Saved continuation token")
+	return nil
+}
+
+// Close flushes buffered changes and closes the checkpoint store
+func (p *ParquetAdapter) Close() error {
+	p.logger.Info("Closing Parquet adapter")
+	close(p.stop)
+	<-p.done
+
+	p.mu.Lock()
+	flushErr := p.flush(context.Background())
+	p.mu.Unlock()
+
+	checkpointErr := p.checkpoint.Close()
+	if flushErr != nil {
+		return fmt.Errorf("failed to flush Parquet buffer: %w", flushErr)
+	}
+	return checkpointErr
+}
+
+// GetStats returns statistics about the Parquet adapter
+func (p *ParquetAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := map[string]interface{}{
+		"adapter_type":    "parquet",
+		"dir":             p.dir,
+		"storage_mode":    string(p.mode),
+		"last_token":      p.lastToken,
+		"checkpoint_type": p.checkpoint.Type(),
+		"buffered_rows":   len(p.buffer),
+		"rows_written":    p.rowsWritten,
+		"files_written":   p.filesWritten,
+		"pending_uploads": len(p.pendingUploads),
+	}
+	if p.s3Config != nil {
+		stats["s3_bucket"] = p.s3Config.Bucket
+	}
+	return stats, nil
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// memParquetFile is a read-only source.ParquetFile over a byte slice
+type memParquetFile struct {
+	*bytes.Reader
+}
+
+func (m memParquetFile) Write(p []byte) (int, error) { return 0, io.ErrShortWrite }
+func (m memParquetFile) Close() error                { return nil }
+func (m memParquetFile) Open(name string) (source.ParquetFile, error) {
+	return memParquetFile{bytes.NewReader(readerBytes(m.Reader))}, nil
+}
+func (m memParquetFile) Create(name string) (source.ParquetFile, error) {
+	return nil, io.ErrShortWrite
+}
+
+func readerBytes(r *bytes.Reader) []byte {
+	data := make([]byte, r.Size())
+	r.ReadAt(data, 0)
+	return data
+}
+
+// readParquetRows decodes every row of a Parquet file
+func readParquetRows(t *testing.T, data []byte) []parquetChangeRow {
+	pr, err := reader.NewParquetReader(memParquetFile{bytes.NewReader(data)}, new(parquetChangeRow), 1)
+	if err != nil {
+		t.Fatalf("Failed to open Parquet file: %v", err)
+	}
+	defer pr.ReadStop()
+
+	rows := make([]parquetChangeRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatalf("Failed to read Parquet rows: %v", err)
+	}
+	return rows
+}
+
+// fakeS3 is an in-memory S3-compatible endpoint that accepts PutObject
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// A 403 is not retried by the client, which keeps the test fast
+	if r.Method != http.MethodPut || f.fail {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		body = decodeAWSChunked(body)
+	}
+	f.objects[strings.TrimPrefix(r.URL.Path, "/")] = body
+	w.Header().Set("ETag", `"etag"`)
+}
+
+// decodeAWSChunked strips the "<hex size>;chunk-signature=...\r\n" framing
+// of a streaming SigV4 upload
+func decodeAWSChunked(body []byte) []byte {
+	var data []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		sizeHex, _, _ := strings.Cut(string(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			break
+		}
+		data = append(data, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+	return data
+}
+
+func (f *fakeS3) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestParseParquetDSN(t *testing.T) {
+	cfg, err := parseParquetDSN("/data/parquet")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if cfg.MaxRows != defaultParquetMaxRows || cfg.Checkpoint.Type != "file" || cfg.Checkpoint.DSN != "/data/parquet/_checkpoint" {
+		t.Errorf("Unexpected simple DSN config: %+v", cfg)
+	}
+
+	for _, dsn := range []string{"", `{"max_rows":10}`, `{"dir":"/data","s3":{"bucket":"lake"}}`} {
+		if _, err := parseParquetDSN(dsn); err == nil {
+			t.Errorf("Expected error for DSN %q", dsn)
+		}
+	}
+}
+
+func TestParquetAdapter_PartitionsAndUploads(t *testing.T) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dsn, _ := json.Marshal(ParquetConfig{
+		Dir:     dir,
+		MaxRows: 3,
+		MaxAge:  "1h",
+		S3: &ParquetS3Config{
+			Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+			Bucket:    "fga-lake",
+			Prefix:    "changes",
+			Region:    "us-east-1",
+			AccessKey: "minio",
+			SecretKey: "minio123",
+		},
+	})
+	adapter, err := NewParquetAdapter(string(dsn), config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create Parquet adapter: %v", err)
+	}
+
+	ctx := context.Background()
+	day1 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	change := func(objectType, objectID string, ts time.Time) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{ObjectType: objectType, ObjectID: objectID, Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_WRITE", Timestamp: ts}
+	}
+
+	// Below max_rows: buffered, and the token is held back
+	if err := adapter.WriteChanges(ctx, []fetcher.ChangeEvent{change("document", "roadmap", day1), change("folder", "eng", day1)}); err != nil {
+		t.Fatalf("Failed to write changes: %v", err)
+	}
+	if err := adapter.SaveContinuationToken(ctx, "token-1"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+	if token, _ := adapter.checkpoint.Load(ctx); token != "" {
+		t.Errorf("Expected no saved token before the flush, got %q", token)
+	}
+
+	// Reaching max_rows flushes, but a failed upload still holds the token back
+	s3.setFail(true)
+	withCondition := change("document", "budget", day2)
+	withCondition.Condition = `{"name":"in_office_hours"}`
+	if err := adapter.WriteChanges(ctx, []fetcher.ChangeEvent{withCondition}); err != nil {
+		t.Fatalf("Failed to write changes: %v", err)
+	}
+	if err := adapter.SaveContinuationToken(ctx, "token-2"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+	if token, _ := adapter.checkpoint.Load(ctx); token != "" {
+		t.Errorf("Expected no saved token before the upload, got %q", token)
+	}
+
+	s3.setFail(false)
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Failed to close adapter: %v", err)
+	}
+
+	keys := s3.keys()
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 uploaded files, got %v", keys)
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "fga-lake/changes/date=") || !strings.HasSuffix(key, ".parquet") {
+			t.Errorf("Unexpected object key %q", key)
+		}
+		rows := readParquetRows(t, s3.objects[key])
+		if len(rows) != 1 {
+			t.Errorf("Expected 1 row in %s, got %d", key, len(rows))
+			continue
+		}
+		row := rows[0]
+		date := time.UnixMicro(row.Timestamp).UTC().Format("2006-01-02")
+		if !strings.Contains(key, "/date="+date+"/object_type="+row.ObjectType+"/") {
+			t.Errorf("Row %+v is in the wrong partition %s", row, key)
+		}
+		if row.ObjectID == "budget" && (row.Condition == nil || *row.Condition != `{"name":"in_office_hours"}`) {
+			t.Errorf("Expected the condition to be kept, got %v", row.Condition)
+		}
+		if row.ChangeType != "TUPLE_OPERATION_WRITE" || row.RawEvent == "" {
+			t.Errorf("Unexpected row %+v", row)
+		}
+	}
+
+	// Uploaded files are removed locally; the checkpoint is the only file left
+	var local []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			local = append(local, filepath.Base(path))
+		}
+		return nil
+	})
+	if len(local) != 1 || local[0] != "_checkpoint" {
+		t.Errorf("Expected only the checkpoint locally, got %v", local)
+	}
+
+	token, _ := os.ReadFile(filepath.Join(dir, "_checkpoint"))
+	if string(token) != "token-2\n" {
+		t.Errorf("Expected token-2 after the flush, got %q", token)
+	}
+}
+
+func TestParquetAdapter_FlushesOnAge(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dsn, _ := json.Marshal(ParquetConfig{Dir: dir, MaxAge: "50ms"})
+	adapter, err := NewParquetAdapter(string(dsn), config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create Parquet adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_WRITE", Timestamp: time.Now()}}
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to apply changes: %v", err)
+	}
+	if err := adapter.SaveContinuationToken(ctx, "token-1"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if token, _ := adapter.checkpoint.Load(ctx); token == "token-1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the buffer to be flushed by age")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "date=*", "object_type=document", "part-*.parquet"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 Parquet file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if rows := readParquetRows(t, data); len(rows) != 1 || rows[0].ObjectID != "roadmap" {
+		t.Errorf("Unexpected rows %+v", rows)
+	}
+}
+
+func TestParquetAdapter_FailedFlushWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dsn, _ := json.Marshal(ParquetConfig{Dir: dir, MaxRows: 2, MaxAge: "1h"})
+	adapter, err := NewParquetAdapter(string(dsn), config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create Parquet adapter: %v", err)
+	}
+
+	// A file in place of the second partition's directory fails the flush
+	// after the first partition has been written
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	blocker := filepath.Join(dir, "date=2025-03-01", "object_type=folder")
+	if err := os.MkdirAll(filepath.Dir(blocker), 0o755); err != nil {
+		t.Fatalf("Failed to create partition directory: %v", err)
+	}
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create blocker: %v", err)
+	}
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_WRITE", Timestamp: day},
+		{ObjectType: "folder", ObjectID: "eng", Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_WRITE", Timestamp: day},
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to write changes: %v", err)
+	}
+
+	parquetFiles := func() []string {
+		var files []string
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.Contains(info.Name(), ".parquet") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		return files
+	}
+	if files := parquetFiles(); len(files) != 0 {
+		t.Fatalf("Expected no files after the failed flush, got %v", files)
+	}
+
+	// The retry writes every row exactly once
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("Failed to remove blocker: %v", err)
+	}
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Failed to close adapter: %v", err)
+	}
+	files := parquetFiles()
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files after the retry, got %v", files)
+	}
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if rows := readParquetRows(t, data); len(rows) != 1 {
+			t.Errorf("Expected 1 row in %s, got %d", file, len(rows))
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// changelogPartitioner keeps the range partitions of a PostgreSQL
+// fga_changelog. The table is converted by the optional partition_changelog
+// migration; the partitioner creates upcoming partitions ahead of time and
+// removes partitions that have fallen out of retention.
+//
+// Rows older than the first partition are in the archive partition, which
+// covers everything before it. Changes that fit no range partition, such as
+// a backfill older than retention, go to the default partition. Maintenance
+// moves them into a new partition covering their range, and prunes those
+// older than retention when partitions are dropped.
+type changelogPartitioner struct {
+	db       *sql.DB
+	logger   *logrus.Logger
+	cfg      config.PartitionConfig
+	naming   migrations.Naming
+	table    string // Qualified fga_changelog name
+	fallback string // Qualified default partition name
+	now      func() time.Time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// changelogPartition is one attached partition and its exclusive upper bound
+type changelogPartition struct {
+	name  string
+	upper time.Time
+}
+
+// newChangelogPartitioner returns a partitioner for the changelog placed by
+// naming; cfg is defaulted
+func newChangelogPartitioner(db *sql.DB, cfg config.PartitionConfig, naming migrations.Naming, logger *logrus.Logger) *changelogPartitioner {
+	return &changelogPartitioner{
+		db:       db,
+		logger:   logger,
+		cfg:      cfg.WithDefaults(),
+		naming:   naming,
+		table:    naming.Table("fga_changelog"),
+		fallback: naming.Table("fga_changelog_default"),
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// start runs one maintenance pass and then maintains partitions every check
+// interval until close
+func (c *changelogPartitioner) start(ctx context.Context) error {
+	if _, _, err := c.maintain(ctx); err != nil {
+		return err
+	}
+	go c.maintainLoop()
+	return nil
+}
+
+// close stops background maintenance
+func (c *changelogPartitioner) close() {
+	close(c.stop)
+	<-c.done
+}
+
+// maintainLoop maintains partitions on a schedule. Failures are retried on
+// the next tick; upcoming partitions are created several periods ahead, so a
+// missed pass doesn't stop writes.
+func (c *changelogPartitioner) maintainLoop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if _, _, err := c.maintain(context.Background()); err != nil {
+				c.logger.WithError(err).Warn("Failed to maintain changelog partitions, will retry")
+			}
+		}
+	}
+}
+
+// lock serializes partition changes across replicas for the rest of tx
+func (c *changelogPartitioner) lock(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", "openfga-sync/partitions/"+c.table); err != nil {
+		return fmt.Errorf("failed to acquire partition lock: %w", err)
+	}
+	return nil
+}
+
+// maintain creates partitions through premake periods past the current one
+// and removes partitions that are entirely older than the retention period
+func (c *changelogPartitioner) maintain(ctx context.Context) (created int, removed int, err error) {
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "postgres.maintain_partitions",
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.partition_interval", c.cfg.Interval),
+		),
+	)
+	defer span.End()
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := c.lock(ctx, tx); err != nil {
+		span.RecordError(err)
+		return 0, 0, err
+	}
+
+	partitions, err := c.partitions(ctx, tx)
+	if err != nil {
+		span.RecordError(err)
+		return 0, 0, err
+	}
+
+	now := c.now()
+	covered := periodStart(now, c.cfg.Interval)
+	for _, partition := range partitions {
+		if partition.upper.After(covered) {
+			covered = partition.upper
+		}
+	}
+	end := periodStart(now, c.cfg.Interval)
+	for i := 0; i <= c.cfg.Premake; i++ {
+		end = nextPeriod(end, c.cfg.Interval)
+	}
+	cutoff := now.Add(-c.cfg.Retention)
+	var moved int64
+	for start := covered; start.Before(end); {
+		next := nextPeriod(start, c.cfg.Interval)
+		// After a long outage, skip periods that are already out of retention
+		if c.cfg.Retention > 0 && !next.After(cutoff) {
+			start = next
+			continue
+		}
+		rows, err := c.createPartition(ctx, tx, start, next)
+		if err != nil {
+			span.RecordError(err)
+			return 0, 0, err
+		}
+		moved += rows
+		created++
+		start = next
+	}
+
+	var pruned int64
+	if c.cfg.Retention > 0 {
+		for _, partition := range partitions {
+			if partition.upper.After(cutoff) {
+				continue
+			}
+			query := "DROP TABLE " + c.naming.Qualify(partition.name)
+			if c.cfg.RetentionAction == config.RetentionActionDetach {
+				query = fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", c.table, c.naming.Qualify(partition.name))
+			}
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				span.RecordError(err)
+				return 0, 0, fmt.Errorf("failed to %s partition %s: %w", c.cfg.RetentionAction, partition.name, err)
+			}
+			removed++
+		}
+
+		// Detached partitions keep expired rows, so the default partition
+		// keeps them too
+		if c.cfg.RetentionAction != config.RetentionActionDetach {
+			result, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE timestamp < $1", c.fallback), cutoff)
+			if err != nil {
+				span.RecordError(err)
+				return 0, 0, fmt.Errorf("failed to prune the default partition: %w", err)
+			}
+			if pruned, err = result.RowsAffected(); err != nil {
+				span.RecordError(err)
+				return 0, 0, fmt.Errorf("failed to prune the default partition: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	span.SetAttributes(
+		attribute.Int("db.partitions_created", created),
+		attribute.Int("db.partitions_removed", removed),
+		attribute.Int64("db.moved_rows", moved),
+		attribute.Int64("db.pruned_rows", pruned),
+	)
+	if created > 0 || removed > 0 || pruned > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"table":            c.table,
+			"created":          created,
+			"removed":          removed,
+			"moved_rows":       moved,
+			"pruned_rows":      pruned,
+			"retention_action": c.cfg.RetentionAction,
+		}).Info("Maintained changelog partitions")
+	}
+	return created, removed, nil
+}
+
+// createPartition creates the partition for [start, next) and returns the
+// rows moved into it. Postgres won't add a partition while the default
+// partition holds rows in its range, so any such rows, written while
+// maintenance was behind, are moved into the new partition before it is
+// attached.
+func (c *changelogPartitioner) createPartition(ctx context.Context, tx *sql.Tx, start, next time.Time) (int64, error) {
+	partition := c.naming.Qualify(fmt.Sprintf("%s_p%s", c.naming.Name("fga_changelog"), start.Format("20060102")))
+	bounds := fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", partitionBound(start), partitionBound(next))
+
+	var stranded bool
+	err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE timestamp >= $1 AND timestamp < $2)", c.fallback), start, next).Scan(&stranded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect the default partition: %w", err)
+	}
+	if !stranded {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s %s", partition, c.table, bounds)); err != nil {
+			return 0, fmt.Errorf("failed to create partition %s: %w", partition, err)
+		}
+		return 0, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS)", partition, c.table)); err != nil {
+		return 0, fmt.Errorf("failed to create partition %s: %w", partition, err)
+	}
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		WITH moved AS (DELETE FROM %s WHERE timestamp >= $1 AND timestamp < $2 RETURNING *)
+		INSERT INTO %s SELECT * FROM moved
+	`, c.fallback, partition), start, next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move rows into partition %s: %w", partition, err)
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to move rows into partition %s: %w", partition, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s", c.table, partition, bounds)); err != nil {
+		return 0, fmt.Errorf("failed to attach partition %s: %w", partition, err)
+	}
+	return moved, nil
+}
+
+// partitions lists the attached partitions of the changelog with their upper
+// bounds
+func (c *changelogPartitioner) partitions(ctx context.Context, tx *sql.Tx) ([]changelogPartition, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.relname,
+			(regexp_match(pg_get_expr(c.relpartbound, c.oid), 'TO \(''([^'']+)''\)'))[1]::timestamptz
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+	`, c.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []changelogPartition
+	for rows.Next() {
+		var name string
+		var upper sql.NullTime
+		if err := rows.Scan(&name, &upper); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		// Partitions without a timestamp upper bound are never removed
+		if upper.Valid {
+			partitions = append(partitions, changelogPartition{name: name, upper: upper.Time})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// periodStart returns the start of the partition period containing t, in UTC.
+// Weeks start on Monday.
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case config.PartitionIntervalDay:
+		return day
+	case config.PartitionIntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// nextPeriod returns the start of the partition period after the one
+// containing t
+func nextPeriod(t time.Time, interval string) time.Time {
+	start := periodStart(t, interval)
+	switch interval {
+	case config.PartitionIntervalDay:
+		return start.AddDate(0, 0, 1)
+	case config.PartitionIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// partitionBound formats t as a partition bound literal
+func partitionBound(t time.Time) string {
+	return "'" + t.UTC().Format(time.RFC3339) + "'"
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+)
+
+func TestPartitionPeriods(t *testing.T) {
+	// Thursday, 2026-10-15 in UTC
+	at := time.Date(2026, 10, 15, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+
+	tests := []struct {
+		interval string
+		start    time.Time
+		next     time.Time
+	}{
+		{config.PartitionIntervalDay, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{config.PartitionIntervalWeek, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{config.PartitionIntervalMonth, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			if got := periodStart(at, tt.interval); !got.Equal(tt.start) {
+				t.Errorf("periodStart() = %v, want %v", got, tt.start)
+			}
+			if got := nextPeriod(at, tt.interval); !got.Equal(tt.next) {
+				t.Errorf("nextPeriod() = %v, want %v", got, tt.next)
+			}
+			// A period start belongs to its own period
+			if got := nextPeriod(tt.start, tt.interval); !got.Equal(tt.next) {
+				t.Errorf("nextPeriod(start) = %v, want %v", got, tt.next)
+			}
+		})
+	}
+
+	if got := partitionBound(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)); got != "'2026-11-01T00:00:00Z'" {
+		t.Errorf("partitionBound() = %s", got)
+	}
+}
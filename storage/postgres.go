@@ -9,6 +9,7 @@ import (
 
 	"github.com/aaguiarz/openfga-sync/config"
 	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
@@ -18,26 +19,59 @@ import (
 
 // PostgresAdapter implements StorageAdapter for PostgreSQL
 type PostgresAdapter struct {
-	db     *sql.DB
-	logger *logrus.Logger
-	mode   config.StorageMode
+	db         *sql.DB
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	storeID    string // Rows are scoped to this store; "" in single-store mode
+	objectType string // Continuation token cursor; "" when fetching all types
+	shared     bool   // Views don't own the connection
+	naming     migrations.Naming
+	tables     sqlTables
+	partitions *changelogPartitioner // nil unless the changelog is partitioned
+	bulkSize   int                   // Batches of at least this many changes use the bulk path
+}
+
+// PostgresOptions configures where a PostgresAdapter places its tables, how
+// it partitions the changelog and when it switches to bulk writes
+type PostgresOptions struct {
+	Naming       migrations.Naming
+	Partitioning config.PartitionConfig
+	BulkSize     int // Minimum batch size for COPY and multi-row statements; 0 uses defaultBulkSize
 }
 
 // NewPostgresAdapter creates a new PostgreSQL storage adapter
 func NewPostgresAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*PostgresAdapter, error) {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
+	return NewPostgresAdapterWithNaming(dsn, mode, migrations.Naming{}, logger)
+}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+// NewPostgresAdapterWithNaming creates a PostgreSQL storage adapter whose
+// tables are placed by naming, so several pipelines can share a database
+func NewPostgresAdapterWithNaming(dsn string, mode config.StorageMode, naming migrations.Naming, logger *logrus.Logger) (*PostgresAdapter, error) {
+	return NewPostgresAdapterWithOptions(dsn, mode, PostgresOptions{Naming: naming}, logger)
+}
+
+// NewPostgresAdapterWithOptions creates a PostgreSQL storage adapter placed by
+// options.Naming. With options.Partitioning enabled in a changelog mode, the
+// changelog is range-partitioned and maintained in the background until Close.
+func NewPostgresAdapterWithOptions(dsn string, mode config.StorageMode, options PostgresOptions, logger *logrus.Logger) (*PostgresAdapter, error) {
+	db, err := openPostgres(dsn)
+	if err != nil {
+		return nil, err
 	}
 
 	adapter := &PostgresAdapter{
-		db:     db,
-		logger: logger,
-		mode:   mode,
+		db:       db,
+		logger:   logger,
+		mode:     mode,
+		naming:   options.Naming,
+		tables:   newSQLTables(options.Naming),
+		bulkSize: options.BulkSize,
+	}
+	if adapter.bulkSize <= 0 {
+		adapter.bulkSize = defaultBulkSize
+	}
+	if options.Partitioning.Enabled && mode.HasChangelog() {
+		adapter.partitions = newChangelogPartitioner(db, options.Partitioning, options.Naming, logger)
 	}
 
 	// Initialize database schema
@@ -45,77 +79,87 @@ func NewPostgresAdapter(dsn string, mode config.StorageMode, logger *logrus.Logg
 		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
 	}
 
+	if adapter.partitions != nil {
+		if err := adapter.partitions.start(context.Background()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to partition changelog: %w", err)
+		}
+	}
+
 	return adapter, nil
 }
 
-// initSchema creates the necessary database tables
-func (p *PostgresAdapter) initSchema() error {
-	var queries []string
-
-	// Common sync state table
-	queries = append(queries, []string{
-		`CREATE TABLE IF NOT EXISTS sync_state (
-			id SERIAL PRIMARY KEY,
-			continuation_token TEXT,
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)`,
-		`INSERT INTO sync_state (continuation_token) 
-		 SELECT '' WHERE NOT EXISTS (SELECT 1 FROM sync_state)`,
-	}...)
-
-	// Mode-specific tables
-	if p.mode == config.StorageModeChangelog {
-		// Changelog mode: append-only table with all change events
-		queries = append(queries, []string{
-			`CREATE TABLE IF NOT EXISTS fga_changelog (
-				id BIGSERIAL PRIMARY KEY,
-				change_type VARCHAR(20) NOT NULL,
-				object_type VARCHAR(100) NOT NULL,
-				object_id VARCHAR(255) NOT NULL,
-				relation VARCHAR(100) NOT NULL,
-				user_type VARCHAR(100) NOT NULL,
-				user_id VARCHAR(255) NOT NULL,
-				timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
-				condition JSONB,
-				raw_event JSONB,
-				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-			)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_timestamp ON fga_changelog(timestamp)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_user_type ON fga_changelog(user_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_object_type ON fga_changelog(object_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_relation ON fga_changelog(relation)`,
-		}...)
-	} else {
-		// Stateful mode: current state table
-		queries = append(queries, []string{
-			`CREATE TABLE IF NOT EXISTS fga_tuples (
-				object_type VARCHAR(100) NOT NULL,
-				object_id VARCHAR(255) NOT NULL,
-				relation VARCHAR(100) NOT NULL,
-				user_type VARCHAR(100) NOT NULL,
-				user_id VARCHAR(255) NOT NULL,
-				condition JSONB,
-				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-				updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-				PRIMARY KEY (object_type, object_id, relation, user_type, user_id)
-			)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_user_type ON fga_tuples(user_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_object_type ON fga_tuples(object_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_relation ON fga_tuples(relation)`,
-		}...)
-	}
-
-	for _, query := range queries {
-		if _, err := p.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute schema query: %w", err)
-		}
+// openPostgres opens a PostgreSQL database
+func openPostgres(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// ForStore returns a view of the adapter scoped to storeID
+func (p *PostgresAdapter) ForStore(storeID string) StorageAdapter {
+	view := *p
+	view.storeID = storeID
+	view.shared = true
+	return &view
+}
+
+// ForObjectType returns a view of the adapter with its own cursor for objectType
+func (p *PostgresAdapter) ForObjectType(objectType string) StorageAdapter {
+	view := *p
+	view.objectType = objectType
+	view.shared = true
+	return &view
+}
+
+// initSchema brings the database schema up to the latest migration,
+// partitioning the changelog if the adapter maintains partitions
+func (p *PostgresAdapter) initSchema() error {
+	migrator, err := newPostgresMigrator(p.db, p.naming, p.partitions != nil, p.logger)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
 	return nil
 }
 
+// newPostgresMigrator returns the schema migrator for a PostgreSQL database.
+// With partitioned set, it also converts the changelog into a partitioned
+// table.
+func newPostgresMigrator(db *sql.DB, naming migrations.Naming, partitioned bool, logger *logrus.Logger) (*migrations.Migrator, error) {
+	migrator, err := migrations.New(db, migrations.Postgres, naming, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	if partitioned {
+		migrator.Features = append(migrator.Features, migrations.FeaturePartitioning)
+	}
+	return migrator, nil
+}
+
 // WriteChanges writes a batch of change events to PostgreSQL (changelog mode)
 func (p *PostgresAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return p.writeChanges(ctx, changes, nil)
+}
+
+// WriteChangesWithToken writes a batch of change events and saves the
+// continuation token in the same transaction (changelog mode)
+func (p *PostgresAdapter) WriteChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return p.writeChanges(ctx, changes, &token)
+}
+
+// writeChanges inserts changes into fga_changelog and, when token is non-nil,
+// updates sync_state before committing
+func (p *PostgresAdapter) writeChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
 	// Start OpenTelemetry span
 	tracer := otel.Tracer("openfga-sync/storage")
 	ctx, span := tracer.Start(ctx, "postgres.write_changes",
@@ -123,16 +167,17 @@ func (p *PostgresAdapter) WriteChanges(ctx context.Context, changes []fetcher.Ch
 			attribute.Int("db.changes_count", len(changes)),
 			attribute.String("db.storage_mode", string(p.mode)),
 			attribute.String("db.system", "postgresql"),
+			attribute.Bool("db.bulk", len(changes) >= p.bulkSize),
 		),
 	)
 	defer span.End()
 
-	if len(changes) == 0 {
+	if len(changes) == 0 && token == nil {
 		return nil
 	}
 
-	if p.mode != config.StorageModeChangelog {
-		err := fmt.Errorf("WriteChanges is only supported in changelog mode")
+	if !p.mode.HasChangelog() {
+		err := fmt.Errorf("WriteChanges is only supported in changelog and hybrid modes")
 		span.RecordError(err)
 		return err
 	}
@@ -144,12 +189,49 @@ func (p *PostgresAdapter) WriteChanges(ctx context.Context, changes []fetcher.Ch
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO fga_changelog (change_type, object_type, object_id, relation, user_type, user_id, timestamp, condition, raw_event)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`)
-	if err != nil {
+	if err := p.insertChangelogTx(ctx, tx, changes); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := p.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		span.RecordError(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.rows_affected", len(changes)),
+		attribute.String("db.operation", "insert"),
+	)
+
+	p.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
+	return nil
+}
+
+// insertChangelogTx appends changes to fga_changelog as part of an open
+// transaction, with COPY for large batches
+func (p *PostgresAdapter) insertChangelogTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) error {
+	if len(changes) >= p.bulkSize {
+		return p.copyChangelogTx(ctx, tx, changes)
+	}
+	return p.insertChangelogRowsTx(ctx, tx, changes)
+}
+
+// insertChangelogRowsTx inserts changes into fga_changelog one row at a time
+func (p *PostgresAdapter) insertChangelogRowsTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) error {
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (store_id, change_type, object_type, object_id, relation, user_type, user_id, timestamp, condition, raw_event)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, p.tables.changelog))
+	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
@@ -168,6 +250,7 @@ func (p *PostgresAdapter) WriteChanges(ctx context.Context, changes []fetcher.Ch
 		}
 
 		_, err = stmt.ExecContext(ctx,
+			p.storeID,
 			change.Operation,
 			change.ObjectType,
 			change.ObjectID,
@@ -179,34 +262,33 @@ func (p *PostgresAdapter) WriteChanges(ctx context.Context, changes []fetcher.Ch
 			string(rawEventJSON),
 		)
 		if err != nil {
-			span.RecordError(err)
 			return fmt.Errorf("failed to insert change: %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	// Add success attributes to span
-	span.SetAttributes(
-		attribute.Int("db.rows_affected", len(changes)),
-		attribute.String("db.operation", "insert"),
-	)
-
-	p.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
 	return nil
 }
 
 // ApplyChanges applies a batch of changes to state table (stateful mode)
 func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
-	if len(changes) == 0 {
+	return p.applyChanges(ctx, changes, nil)
+}
+
+// ApplyChangesWithToken applies a batch of changes and saves the continuation
+// token in the same transaction (stateful mode)
+func (p *PostgresAdapter) ApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return p.applyChanges(ctx, changes, &token)
+}
+
+// applyChanges upserts and deletes tuples in fga_tuples and, when token is
+// non-nil, updates sync_state before committing
+func (p *PostgresAdapter) applyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	if len(changes) == 0 && token == nil {
 		return nil
 	}
 
-	if p.mode != config.StorageModeStateful {
-		return fmt.Errorf("ApplyChanges is only supported in stateful mode")
+	if !p.mode.HasState() && p.mode != config.StorageModeHistory {
+		return fmt.Errorf("ApplyChanges is only supported in stateful, hybrid and history modes")
 	}
 
 	tx, err := p.db.BeginTx(ctx, nil)
@@ -215,23 +297,63 @@ func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Ch
 	}
 	defer tx.Rollback()
 
-	insertStmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO fga_tuples (object_type, object_id, relation, user_type, user_id, condition)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (object_type, object_id, relation, user_type, user_id)
+	var insertCount, deleteCount int
+	if p.mode == config.StorageModeHistory {
+		insertCount, deleteCount, err = p.applyHistoryTx(ctx, tx, changes)
+	} else {
+		insertCount, deleteCount, err = p.applyTuplesTx(ctx, tx, changes)
+	}
+	if err != nil {
+		return err
+	}
+
+	if token != nil {
+		if err := p.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"inserts": insertCount,
+		"deletes": deleteCount,
+	}).Info("Successfully applied changes to state table")
+	return nil
+}
+
+// applyTuplesTx upserts and deletes tuples in fga_tuples as part of an open
+// transaction, with multi-row statements for large batches
+func (p *PostgresAdapter) applyTuplesTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	if len(changes) >= p.bulkSize {
+		return p.applyTuplesBulkTx(ctx, tx, changes)
+	}
+	return p.applyTupleRowsTx(ctx, tx, changes)
+}
+
+// applyTupleRowsTx upserts and deletes tuples in fga_tuples one change at a
+// time as part of an open transaction, returning the number of writes and
+// deletes applied
+func (p *PostgresAdapter) applyTupleRowsTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	insertStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (store_id, object_type, object_id, relation, user_type, user_id, condition)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (store_id, object_type, object_id, relation, user_type, user_id)
 		DO UPDATE SET condition = EXCLUDED.condition, updated_at = NOW()
-	`)
+	`, p.tables.tuples))
 	if err != nil {
-		return fmt.Errorf("failed to prepare insert statement: %w", err)
+		return 0, 0, fmt.Errorf("failed to prepare insert statement: %w", err)
 	}
 	defer insertStmt.Close()
 
-	deleteStmt, err := tx.PrepareContext(ctx, `
-		DELETE FROM fga_tuples 
-		WHERE object_type = $1 AND object_id = $2 AND relation = $3 AND user_type = $4 AND user_id = $5
-	`)
+	deleteStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE store_id = $1 AND object_type = $2 AND object_id = $3 AND relation = $4 AND user_type = $5 AND user_id = $6
+	`, p.tables.tuples))
 	if err != nil {
-		return fmt.Errorf("failed to prepare delete statement: %w", err)
+		return 0, 0, fmt.Errorf("failed to prepare delete statement: %w", err)
 	}
 	defer deleteStmt.Close()
 
@@ -246,6 +368,7 @@ func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Ch
 			}
 
 			_, err = insertStmt.ExecContext(ctx,
+				p.storeID,
 				change.ObjectType,
 				change.ObjectID,
 				change.Relation,
@@ -254,11 +377,12 @@ func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Ch
 				conditionJSONB,
 			)
 			if err != nil {
-				return fmt.Errorf("failed to insert/update tuple: %w", err)
+				return 0, 0, fmt.Errorf("failed to insert/update tuple: %w", err)
 			}
 			insertCount++
 		case "TUPLE_OPERATION_DELETE":
 			_, err = deleteStmt.ExecContext(ctx,
+				p.storeID,
 				change.ObjectType,
 				change.ObjectID,
 				change.Relation,
@@ -266,7 +390,7 @@ func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Ch
 				change.UserID,
 			)
 			if err != nil {
-				return fmt.Errorf("failed to delete tuple: %w", err)
+				return 0, 0, fmt.Errorf("failed to delete tuple: %w", err)
 			}
 			deleteCount++
 		default:
@@ -274,30 +398,221 @@ func (p *PostgresAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Ch
 		}
 	}
 
+	return insertCount, deleteCount, nil
+}
+
+// applyHistoryTx opens a validity interval in fga_tuple_history for each write
+// and closes the open one for each delete, as part of an open transaction. A
+// write is skipped when the tuple is already open or an interval starting at
+// the same time exists, so replaying a batch does not duplicate intervals.
+func (p *PostgresAdapter) applyHistoryTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	openStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s (store_id, object_type, object_id, relation, user_type, user_id, condition, valid_from)
+		SELECT $1::varchar, $2::varchar, $3::varchar, $4::varchar, $5::varchar, $6::varchar, $7::jsonb, $8::timestamptz
+		WHERE NOT EXISTS (
+			SELECT 1 FROM %[1]s
+			WHERE store_id = $1 AND object_type = $2 AND object_id = $3 AND relation = $4 AND user_type = $5 AND user_id = $6
+			AND (valid_to IS NULL OR valid_from = $8)
+		)
+	`, p.tables.history))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare open interval statement: %w", err)
+	}
+	defer openStmt.Close()
+
+	closeStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET valid_to = $7
+		WHERE store_id = $1 AND object_type = $2 AND object_id = $3 AND relation = $4 AND user_type = $5 AND user_id = $6
+		AND valid_to IS NULL AND valid_from <= $7
+	`, p.tables.history))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare close interval statement: %w", err)
+	}
+	defer closeStmt.Close()
+
+	var openCount, closeCount int
+	for _, change := range changes {
+		switch strings.ToUpper(change.Operation) {
+		case "TUPLE_OPERATION_WRITE":
+			var conditionJSONB interface{}
+			if change.Condition != "" {
+				conditionJSONB = change.Condition
+			}
+
+			result, err := openStmt.ExecContext(ctx,
+				p.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+				conditionJSONB,
+				change.Timestamp,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to open tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				openCount++
+			}
+		case "TUPLE_OPERATION_DELETE":
+			result, err := closeStmt.ExecContext(ctx,
+				p.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+				change.Timestamp,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to close tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				closeCount++
+			}
+		default:
+			p.logger.WithField("operation", change.Operation).Warn("Unknown operation type, skipping")
+		}
+	}
+
+	return openCount, closeCount, nil
+}
+
+// TuplesAsOf returns the tuples that existed at query.At (history mode)
+func (p *PostgresAdapter) TuplesAsOf(ctx context.Context, query AsOfQuery) ([]HistoryTuple, error) {
+	if p.mode != config.StorageModeHistory {
+		return nil, fmt.Errorf("TuplesAsOf is only supported in history mode")
+	}
+
+	statement, args := historyAsOfSQL(p.tables.history, p.storeID, query, query.At, func(n int) string { return fmt.Sprintf("$%d", n) }, "condition")
+	rows, err := p.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tuple history: %w", err)
+	}
+	defer rows.Close()
+
+	return scanHistoryTuples(rows)
+}
+
+// WriteAndApplyChanges appends a batch of changes to fga_changelog and applies
+// it to fga_tuples in one transaction (hybrid mode)
+func (p *PostgresAdapter) WriteAndApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return p.writeAndApplyChanges(ctx, changes, nil)
+}
+
+// WriteAndApplyChangesWithToken does the same as WriteAndApplyChanges and
+// saves the continuation token in the same transaction (hybrid mode)
+func (p *PostgresAdapter) WriteAndApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return p.writeAndApplyChanges(ctx, changes, &token)
+}
+
+// writeAndApplyChanges writes both tables and, when token is non-nil, updates
+// sync_state before committing
+func (p *PostgresAdapter) writeAndApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "postgres.write_and_apply_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(p.mode)),
+			attribute.String("db.system", "postgresql"),
+			attribute.Bool("db.bulk", len(changes) >= p.bulkSize),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if p.mode != config.StorageModeHybrid {
+		err := fmt.Errorf("WriteAndApplyChanges is only supported in hybrid mode")
+		span.RecordError(err)
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := p.insertChangelogTx(ctx, tx, changes); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	insertCount, deleteCount, err := p.applyTuplesTx(ctx, tx, changes)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := p.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.rows_affected", len(changes)),
+		attribute.Int("db.inserts", insertCount),
+		attribute.Int("db.deletes", deleteCount),
+		attribute.String("db.operation", "insert_upsert"),
+	)
+
 	p.logger.WithFields(logrus.Fields{
-		"inserts": insertCount,
-		"deletes": deleteCount,
-	}).Info("Successfully applied changes to state table")
+		"changes_count": len(changes),
+		"inserts":       insertCount,
+		"deletes":       deleteCount,
+	}).Info("Successfully wrote changes to changelog and state table")
 	return nil
 }
 
 // GetLastContinuationToken retrieves the last processed continuation token
 func (p *PostgresAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
-	var token string
-	err := p.db.QueryRowContext(ctx, "SELECT continuation_token FROM sync_state ORDER BY id DESC LIMIT 1").Scan(&token)
+	var token sql.NullString
+	err := p.db.QueryRowContext(ctx, "SELECT continuation_token FROM "+p.tables.syncState+" WHERE store_id = $1 AND object_type = $2", p.storeID, p.objectType).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to get continuation token: %w", err)
 	}
-	return token, nil
+	return token.String, nil
+}
+
+// saveTokenQuery upserts the continuation token of one store cursor
+func (p *PostgresAdapter) saveTokenQuery() string {
+	return fmt.Sprintf(`
+		INSERT INTO %s (store_id, object_type, continuation_token, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (store_id, object_type)
+		DO UPDATE SET continuation_token = EXCLUDED.continuation_token, updated_at = NOW()
+	`, p.tables.syncState)
 }
 
 // SaveContinuationToken saves the continuation token for resuming processing
 func (p *PostgresAdapter) SaveContinuationToken(ctx context.Context, token string) error {
-	_, err := p.db.ExecContext(ctx, "UPDATE sync_state SET continuation_token = $1, updated_at = NOW()", token)
+	_, err := p.db.ExecContext(ctx, p.saveTokenQuery(), p.storeID, p.objectType, token)
+	if err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	return nil
+}
+
+// saveContinuationTokenTx saves the continuation token as part of an open transaction
+func (p *PostgresAdapter) saveContinuationTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
+	_, err := tx.ExecContext(ctx, p.saveTokenQuery(), p.storeID, p.objectType, token)
 	if err != nil {
 		return fmt.Errorf("failed to save continuation token: %w", err)
 	}
@@ -311,6 +626,9 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 	// Basic adapter info
 	stats["adapter_type"] = "postgres"
 	stats["storage_mode"] = string(p.mode)
+	if p.storeID != "" {
+		stats["store_id"] = p.storeID
+	}
 
 	// Test database connection
 	if err := p.db.PingContext(ctx); err != nil {
@@ -320,10 +638,10 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 	}
 	stats["connection_status"] = "healthy"
 
-	// Get database-specific stats based on mode
-	if p.mode == config.StorageModeChangelog {
+	// Get database-specific stats based on mode; hybrid mode reports both
+	if p.mode.HasChangelog() {
 		var count int64
-		err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog").Scan(&count)
+		err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+p.tables.changelog+" WHERE store_id = $1", p.storeID).Scan(&count)
 		if err != nil {
 			stats["query_error"] = err.Error()
 		} else {
@@ -331,7 +649,7 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 		}
 
 		// Get count by change type
-		rows, err := p.db.QueryContext(ctx, "SELECT change_type, COUNT(*) FROM fga_changelog GROUP BY change_type")
+		rows, err := p.db.QueryContext(ctx, "SELECT change_type, COUNT(*) FROM "+p.tables.changelog+" WHERE store_id = $1 GROUP BY change_type", p.storeID)
 		if err == nil {
 			defer rows.Close()
 			changeTypeStats := make(map[string]int64)
@@ -344,9 +662,18 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 			}
 			stats["by_change_type"] = changeTypeStats
 		}
-	} else if p.mode == config.StorageModeStateful {
+
+		if p.partitions != nil {
+			var partitions int64
+			err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pg_inherits WHERE inhparent = $1::regclass", p.tables.changelog).Scan(&partitions)
+			if err == nil {
+				stats["changelog_partitions"] = partitions
+			}
+		}
+	}
+	if p.mode.HasState() {
 		var count int64
-		err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&count)
+		err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+p.tables.tuples+" WHERE store_id = $1", p.storeID).Scan(&count)
 		if err != nil {
 			stats["query_error"] = err.Error()
 		} else {
@@ -354,7 +681,7 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 		}
 
 		// Get count by object type
-		rows, err := p.db.QueryContext(ctx, "SELECT object_type, COUNT(*) FROM fga_tuples GROUP BY object_type")
+		rows, err := p.db.QueryContext(ctx, "SELECT object_type, COUNT(*) FROM "+p.tables.tuples+" WHERE store_id = $1 GROUP BY object_type", p.storeID)
 		if err == nil {
 			defer rows.Close()
 			objectTypeStats := make(map[string]int64)
@@ -368,14 +695,27 @@ func (p *PostgresAdapter) GetStats(ctx context.Context) (map[string]interface{},
 			stats["by_object_type"] = objectTypeStats
 		}
 	}
+	if p.mode == config.StorageModeHistory {
+		var intervals, current int64
+		err := p.db.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) FILTER (WHERE valid_to IS NULL) FROM "+p.tables.history+" WHERE store_id = $1", p.storeID).Scan(&intervals, &current)
+		if err != nil {
+			stats["query_error"] = err.Error()
+		} else {
+			stats["history_intervals"] = intervals
+			stats["current_tuples"] = current
+		}
+	}
 
 	return stats, nil
 }
 
 // Close closes the database connection
 func (p *PostgresAdapter) Close() error {
-	if p.db == nil {
+	if p.db == nil || p.shared {
 		return nil
 	}
+	if p.partitions != nil {
+		p.partitions.close()
+	}
 	return p.db.Close()
 }
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/lib/pq"
+)
+
+// Bulk write tuning. Batches of at least the bulk size are written with COPY
+// (changelog) or multi-row statements (tuples), which avoids a round trip per
+// change during backfills. The default is the largest page ReadChanges
+// returns, and batches are written a page at a time, so only full pages take
+// the bulk path unless backend.bulk_size lowers it. A multi-row statement
+// carries at most bulkStatementRows rows, keeping it well below the 65535
+// bind parameter limit of the protocol.
+const (
+	defaultBulkSize   = 100
+	bulkStatementRows = 1000
+)
+
+// copyChangelogTx appends changes to fga_changelog with COPY FROM STDIN as
+// part of an open transaction
+func (p *PostgresAdapter) copyChangelogTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) error {
+	columns := []string{"store_id", "change_type", "object_type", "object_id", "relation", "user_type", "user_id", "timestamp", "condition", "raw_event"}
+	table := p.naming.Name("fga_changelog")
+	copyIn := pq.CopyIn(table, columns...)
+	if p.naming.Schema != "" {
+		copyIn = pq.CopyInSchema(p.naming.Schema, table, columns...)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, copyIn)
+	if err != nil {
+		return fmt.Errorf("failed to start copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		rawEventJSON, err := json.Marshal(change)
+		if err != nil {
+			p.logger.WithError(err).Warn("Failed to marshal change event to JSON")
+			rawEventJSON = []byte("{}")
+		}
+
+		var conditionJSONB interface{}
+		if change.Condition != "" {
+			conditionJSONB = change.Condition
+		}
+
+		// Rows are buffered by the driver and sent as COPY data
+		_, err = stmt.ExecContext(ctx,
+			p.storeID,
+			change.Operation,
+			change.ObjectType,
+			change.ObjectID,
+			change.Relation,
+			change.UserType,
+			change.UserID,
+			change.Timestamp,
+			conditionJSONB,
+			string(rawEventJSON),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to copy change: %w", err)
+		}
+	}
+
+	// An empty Exec flushes the buffered rows and ends the COPY
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to copy changes: %w", err)
+	}
+	return nil
+}
+
+// applyTuplesBulkTx applies changes to fga_tuples with multi-row upserts and
+// deletes as part of an open transaction. Counts match applyTupleRowsTx.
+func (p *PostgresAdapter) applyTuplesBulkTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	writes, deletes, insertCount, deleteCount := lastTupleChanges(changes, func(operation string) {
+		p.logger.WithField("operation", operation).Warn("Unknown operation type, skipping")
+	})
+
+	for start := 0; start < len(writes); start += bulkStatementRows {
+		chunk := writes[start:min(start+bulkStatementRows, len(writes))]
+		args := make([]interface{}, 0, len(chunk)*7)
+		for _, change := range chunk {
+			var conditionJSONB interface{}
+			if change.Condition != "" {
+				conditionJSONB = change.Condition
+			}
+			args = append(args, p.storeID, change.ObjectType, change.ObjectID, change.Relation, change.UserType, change.UserID, conditionJSONB)
+		}
+
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s (store_id, object_type, object_id, relation, user_type, user_id, condition)
+			VALUES %s
+			ON CONFLICT (store_id, object_type, object_id, relation, user_type, user_id)
+			DO UPDATE SET condition = EXCLUDED.condition, updated_at = NOW()
+		`, p.tables.tuples, bulkValues(len(chunk), 7)), args...)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to insert/update tuples: %w", err)
+		}
+	}
+
+	for start := 0; start < len(deletes); start += bulkStatementRows {
+		chunk := deletes[start:min(start+bulkStatementRows, len(deletes))]
+		args := make([]interface{}, 0, len(chunk)*6)
+		for _, change := range chunk {
+			args = append(args, p.storeID, change.ObjectType, change.ObjectID, change.Relation, change.UserType, change.UserID)
+		}
+
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s t
+			USING (VALUES %s) AS d (store_id, object_type, object_id, relation, user_type, user_id)
+			WHERE t.store_id = d.store_id AND t.object_type = d.object_type AND t.object_id = d.object_id
+			AND t.relation = d.relation AND t.user_type = d.user_type AND t.user_id = d.user_id
+		`, p.tables.tuples, bulkValues(len(chunk), 6)), args...)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to delete tuples: %w", err)
+		}
+	}
+
+	return insertCount, deleteCount, nil
+}
+
+// tupleKey identifies a tuple within a store
+type tupleKey struct {
+	objectType, objectID, relation, userType, userID string
+}
+
+// lastTupleChanges reduces a batch to the last change of each tuple, split
+// into writes and deletes in batch order. Only the last change decides
+// whether a tuple exists afterwards, and a multi-row upsert can't touch the
+// same row twice. The counts are of every write and delete in the batch;
+// unknown operations are passed to skip.
+func lastTupleChanges(changes []fetcher.ChangeEvent, skip func(operation string)) (writes, deletes []fetcher.ChangeEvent, insertCount, deleteCount int) {
+	last := make(map[tupleKey]int, len(changes))
+	var order []tupleKey
+	for i, change := range changes {
+		switch strings.ToUpper(change.Operation) {
+		case "TUPLE_OPERATION_WRITE":
+			insertCount++
+		case "TUPLE_OPERATION_DELETE":
+			deleteCount++
+		default:
+			skip(change.Operation)
+			continue
+		}
+
+		key := tupleKey{change.ObjectType, change.ObjectID, change.Relation, change.UserType, change.UserID}
+		if _, seen := last[key]; !seen {
+			order = append(order, key)
+		}
+		last[key] = i
+	}
+
+	for _, key := range order {
+		change := changes[last[key]]
+		if strings.ToUpper(change.Operation) == "TUPLE_OPERATION_WRITE" {
+			writes = append(writes, change)
+		} else {
+			deletes = append(deletes, change)
+		}
+	}
+	return writes, deletes, insertCount, deleteCount
+}
+
+// bulkValues returns the VALUES rows of a multi-row statement with numbered
+// placeholders, e.g. ($1, $2), ($3, $4)
+func bulkValues(rows, columns int) string {
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for column := 0; column < columns; column++ {
+			if column > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", row*columns+column+1)
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
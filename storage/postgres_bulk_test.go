@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
+	"github.com/sirupsen/logrus"
+)
+
+func bulkChange(operation, objectID, condition string) fetcher.ChangeEvent {
+	return fetcher.ChangeEvent{Operation: operation, ObjectType: "document", ObjectID: objectID, Relation: "viewer", UserType: "user", UserID: "alice", Condition: condition, Timestamp: time.Now()}
+}
+
+func TestLastTupleChanges(t *testing.T) {
+	changes := []fetcher.ChangeEvent{
+		bulkChange("TUPLE_OPERATION_WRITE", "a", ""),
+		bulkChange("TUPLE_OPERATION_WRITE", "b", ""),
+		bulkChange("TUPLE_OPERATION_DELETE", "a", ""),
+		bulkChange("TUPLE_OPERATION_UNKNOWN", "c", ""),
+		bulkChange("tuple_operation_write", "b", `{"name":"in_region"}`),
+		bulkChange("TUPLE_OPERATION_DELETE", "d", ""),
+		bulkChange("TUPLE_OPERATION_WRITE", "d", ""),
+	}
+
+	var skipped []string
+	writes, deletes, insertCount, deleteCount := lastTupleChanges(changes, func(operation string) {
+		skipped = append(skipped, operation)
+	})
+
+	if insertCount != 4 || deleteCount != 2 {
+		t.Errorf("Expected 4 writes and 2 deletes counted, got %d and %d", insertCount, deleteCount)
+	}
+	if len(skipped) != 1 || skipped[0] != "TUPLE_OPERATION_UNKNOWN" {
+		t.Errorf("Expected the unknown operation to be skipped, got %v", skipped)
+	}
+
+	var got []string
+	for _, change := range writes {
+		got = append(got, "write:"+change.ObjectID+change.Condition)
+	}
+	for _, change := range deletes {
+		got = append(got, "delete:"+change.ObjectID)
+	}
+	want := `write:b{"name":"in_region"},write:d,delete:a`
+	if strings.Join(got, ",") != want {
+		t.Errorf("lastTupleChanges() = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestBulkValues(t *testing.T) {
+	if got := bulkValues(2, 3); got != "($1, $2, $3), ($4, $5, $6)" {
+		t.Errorf("bulkValues(2, 3) = %s", got)
+	}
+	if got := bulkValues(1, 1); got != "($1)" {
+		t.Errorf("bulkValues(1, 1) = %s", got)
+	}
+}
+
+// newBulkTestAdapter returns an adapter on a fresh test schema that uses the
+// bulk path for batches of at least bulkSize changes
+func newBulkTestAdapter(tb testing.TB, dsn, schema string, mode config.StorageMode, bulkSize int) *PostgresAdapter {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("DROP SCHEMA IF EXISTS " + schema + " CASCADE"); err != nil {
+		tb.Fatalf("Failed to drop test schema: %v", err)
+	}
+
+	options := PostgresOptions{Naming: migrations.Naming{Schema: schema}, BulkSize: bulkSize}
+	adapter, err := NewPostgresAdapterWithOptions(dsn, mode, options, logger)
+	if err != nil {
+		tb.Fatalf("Failed to create adapter: %v", err)
+	}
+	tb.Cleanup(func() { adapter.Close() })
+	return adapter
+}
+
+func TestPostgresAdapter_BulkMatchesRows(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	ctx := context.Background()
+
+	changes := []fetcher.ChangeEvent{
+		bulkChange("TUPLE_OPERATION_WRITE", "a", ""),
+		bulkChange("TUPLE_OPERATION_WRITE", "b", `{"name":"in_region","context":{"region":"eu"}}`),
+		bulkChange("TUPLE_OPERATION_DELETE", "a", ""),
+		bulkChange("TUPLE_OPERATION_WRITE", "c", ""),
+		bulkChange("TUPLE_OPERATION_DELETE", "c", ""),
+		bulkChange("TUPLE_OPERATION_WRITE", "c", ""),
+	}
+
+	// Both paths leave the same tables behind, in hybrid mode
+	results := map[string][]string{}
+	for name, bulkSize := range map[string]int{"rows": math.MaxInt, "bulk": 1} {
+		adapter := newBulkTestAdapter(t, dsn, "sync_bulk_"+name, config.StorageModeHybrid, bulkSize)
+
+		// A tuple written by an earlier batch is deleted by the bulk statement
+		if err := adapter.WriteAndApplyChanges(ctx, []fetcher.ChangeEvent{bulkChange("TUPLE_OPERATION_WRITE", "a", "")}); err != nil {
+			t.Fatalf("%s: WriteAndApplyChanges() error = %v", name, err)
+		}
+		if err := adapter.WriteAndApplyChangesWithToken(ctx, changes, "token"); err != nil {
+			t.Fatalf("%s: WriteAndApplyChangesWithToken() error = %v", name, err)
+		}
+
+		var state []string
+		rows, err := adapter.db.QueryContext(ctx, "SELECT object_id, COALESCE(condition::text, '') FROM "+adapter.tables.tuples)
+		if err != nil {
+			t.Fatalf("%s: failed to query tuples: %v", name, err)
+		}
+		for rows.Next() {
+			var objectID, condition string
+			if err := rows.Scan(&objectID, &condition); err != nil {
+				t.Fatalf("%s: failed to scan tuple: %v", name, err)
+			}
+			state = append(state, objectID+condition)
+		}
+		rows.Close()
+		sort.Strings(state)
+
+		var changelog int
+		if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+adapter.tables.changelog).Scan(&changelog); err != nil {
+			t.Fatalf("%s: failed to count changelog: %v", name, err)
+		}
+		results[name] = append(state, fmt.Sprintf("changelog=%d", changelog))
+	}
+
+	if strings.Join(results["rows"], ",") != strings.Join(results["bulk"], ",") {
+		t.Errorf("Bulk path diverged from the row path:\nrows: %v\nbulk: %v", results["rows"], results["bulk"])
+	}
+	if want := `b{"name": "in_region", "context": {"region": "eu"}},c,changelog=7`; strings.Join(results["bulk"], ",") != want {
+		t.Errorf("Unexpected result %v, want %s", results["bulk"], want)
+	}
+}
+
+// benchmarkChanges returns n writes of distinct tuples
+func benchmarkChanges(n int) []fetcher.ChangeEvent {
+	changes := make([]fetcher.ChangeEvent, n)
+	for i := range changes {
+		changes[i] = bulkChange("TUPLE_OPERATION_WRITE", fmt.Sprintf("doc-%d", i), "")
+	}
+	return changes
+}
+
+func BenchmarkPostgresAdapter_WriteChanges(b *testing.B) {
+	dsn := skipIfNoPostgreSQL(b)
+	ctx := context.Background()
+	changes := benchmarkChanges(1000)
+
+	for name, bulkSize := range map[string]int{"rows": math.MaxInt, "copy": 1} {
+		b.Run(name, func(b *testing.B) {
+			adapter := newBulkTestAdapter(b, dsn, "sync_bench_"+name, config.StorageModeChangelog, bulkSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := adapter.WriteChanges(ctx, changes); err != nil {
+					b.Fatalf("WriteChanges() error = %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(changes))/b.Elapsed().Seconds(), "changes/s")
+		})
+	}
+}
+
+func BenchmarkPostgresAdapter_ApplyChanges(b *testing.B) {
+	dsn := skipIfNoPostgreSQL(b)
+	ctx := context.Background()
+	changes := benchmarkChanges(1000)
+
+	for name, bulkSize := range map[string]int{"rows": math.MaxInt, "batch": 1} {
+		b.Run(name, func(b *testing.B) {
+			adapter := newBulkTestAdapter(b, dsn, "sync_bench_"+name, config.StorageModeStateful, bulkSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := adapter.ApplyChanges(ctx, changes); err != nil {
+					b.Fatalf("ApplyChanges() error = %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.N*len(changes))/b.Elapsed().Seconds(), "changes/s")
+		})
+	}
+}
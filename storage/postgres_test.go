@@ -11,12 +11,13 @@ import (
 
 	"github.com/aaguiarz/openfga-sync/config"
 	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
 // skipIfNoPostgreSQL skips the test if PostgreSQL is not available
-func skipIfNoPostgreSQL(t *testing.T) string {
+func skipIfNoPostgreSQL(t testing.TB) string {
 	// Check if PostgreSQL environment variables are set
 	dbHost := os.Getenv("POSTGRES_HOST")
 	dbPort := os.Getenv("POSTGRES_PORT")
@@ -484,3 +485,362 @@ func TestPostgresAdapter_ConditionSupport(t *testing.T) {
 		}
 	})
 }
+
+func TestPostgresAdapter_ChangesWithToken(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewPostgresAdapter(dsn, config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "doc123",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	var txAdapter TransactionalAdapter = adapter
+	if err := txAdapter.WriteChangesWithToken(ctx, changes, "atomic-token"); err != nil {
+		t.Fatalf("WriteChangesWithToken() error = %v", err)
+	}
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "atomic-token" {
+		t.Errorf("Expected atomic-token, got %s", token)
+	}
+
+	// A batch rejected by the mode check must leave the token untouched
+	if err := adapter.ApplyChangesWithToken(ctx, changes, "rejected-token"); err == nil {
+		t.Error("Expected ApplyChangesWithToken to fail in changelog mode")
+	}
+
+	token, err = adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "atomic-token" {
+		t.Errorf("Expected token to stay at atomic-token, got %s", token)
+	}
+}
+
+func TestPostgresAdapter_HybridMode(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewPostgresAdapter(dsn, config.StorageModeHybrid, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	// A store of its own keeps rows left by other tests out of the counts
+	view := adapter.ForStore(fmt.Sprintf("hybrid-%d", time.Now().UnixNano())).(*PostgresAdapter)
+
+	ctx := context.Background()
+	now := time.Now()
+	changes := []fetcher.ChangeEvent{
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "doc123", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "doc123", Relation: "editor", UserType: "user", UserID: "bob", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_DELETE", ObjectType: "document", ObjectID: "doc123", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+	}
+
+	var hybridAdapter HybridAdapter = view
+	if err := hybridAdapter.WriteAndApplyChangesWithToken(ctx, changes, "hybrid-token"); err != nil {
+		t.Fatalf("WriteAndApplyChangesWithToken() error = %v", err)
+	}
+
+	stats, err := view.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["changelog_entries"] != int64(3) {
+		t.Errorf("Expected 3 changelog entries, got %v", stats["changelog_entries"])
+	}
+	if stats["current_tuples"] != int64(1) {
+		t.Errorf("Expected 1 current tuple, got %v", stats["current_tuples"])
+	}
+
+	token, err := view.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("GetLastContinuationToken() error = %v", err)
+	}
+	if token != "hybrid-token" {
+		t.Errorf("Expected hybrid-token, got %s", token)
+	}
+}
+
+func TestPostgresAdapter_HistoryMode(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	base, err := NewPostgresAdapter(dsn, config.StorageModeHistory, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer base.Close()
+
+	// A store of its own keeps rows left by other tests out of the counts
+	adapter := base.ForStore(fmt.Sprintf("history-%d", time.Now().UnixNano())).(*PostgresAdapter)
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 12, 0, 0, 0, time.UTC) }
+	change := func(operation string, userID string, ts time.Time) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{Operation: operation, ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: userID, Timestamp: ts}
+	}
+	changes := []fetcher.ChangeEvent{
+		change("TUPLE_OPERATION_WRITE", "anne", day(1)),
+		change("TUPLE_OPERATION_WRITE", "bob", day(2)),
+		change("TUPLE_OPERATION_DELETE", "anne", day(3)),
+		change("TUPLE_OPERATION_WRITE", "anne", day(5)),
+	}
+
+	if err := adapter.ApplyChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("ApplyChangesWithToken() error = %v", err)
+	}
+	// Replaying the batch must not duplicate intervals
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("ApplyChanges() replay error = %v", err)
+	}
+
+	users := func(at time.Time) []string {
+		tuples, err := TuplesAsOf(ctx, adapter, AsOfQuery{At: at, ObjectType: "document", ObjectID: "roadmap"})
+		if err != nil {
+			t.Fatalf("TuplesAsOf() error = %v", err)
+		}
+		var users []string
+		for _, tuple := range tuples {
+			users = append(users, tuple.UserID)
+		}
+		return users
+	}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{day(1).Add(-time.Hour), ""},
+		{day(1), "anne"},
+		{day(2), "anne,bob"},
+		{day(3), "bob"},
+		{day(4), "bob"},
+		{day(6), "anne,bob"},
+	} {
+		if got := strings.Join(users(tt.at), ","); got != tt.want {
+			t.Errorf("Tuples as of %s = %q, want %q", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["history_intervals"] != int64(3) || stats["current_tuples"] != int64(2) {
+		t.Errorf("Expected 3 intervals and 2 current tuples, got %v and %v", stats["history_intervals"], stats["current_tuples"])
+	}
+}
+
+func TestPostgresAdapter_SchemaAndPrefix(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	// Start from an empty schema so migrations run from the first version
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("DROP SCHEMA IF EXISTS sync_test CASCADE"); err != nil {
+		t.Fatalf("Failed to drop test schema: %v", err)
+	}
+
+	naming := migrations.Naming{Schema: "sync_test", Prefix: "eu_"}
+	adapter, err := NewPostgresAdapterWithNaming(dsn, config.StorageModeHybrid, naming, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "readme", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: time.Now()},
+	}
+	if err := adapter.WriteAndApplyChangesWithToken(ctx, changes, "eu-token"); err != nil {
+		t.Fatalf("WriteAndApplyChangesWithToken() error = %v", err)
+	}
+	if token, err := adapter.GetLastContinuationToken(ctx); err != nil || token != "eu-token" {
+		t.Errorf("Expected eu-token, got %q (%v)", token, err)
+	}
+
+	for table, want := range map[string]int{"sync_test.eu_fga_changelog": 1, "sync_test.eu_fga_tuples": 1, "sync_test.eu_fga_tuple_history": 0} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if count != want {
+			t.Errorf("Expected %d rows in %s, got %d", want, table, count)
+		}
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM sync_test.eu_schema_migrations").Scan(&version); err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version < 2 {
+		t.Errorf("Expected every migration to be applied, got version %d", version)
+	}
+}
+
+func TestPostgresAdapter_PartitionedChangelog(t *testing.T) {
+	dsn := skipIfNoPostgreSQL(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("DROP SCHEMA IF EXISTS sync_partition_test CASCADE"); err != nil {
+		t.Fatalf("Failed to drop test schema: %v", err)
+	}
+
+	ctx := context.Background()
+	naming := migrations.Naming{Schema: "sync_partition_test"}
+	now := time.Now()
+	change := func(id string, at time.Time) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: id, Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: at}
+	}
+	count := func() int {
+		var rows int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sync_partition_test.fga_changelog").Scan(&rows); err != nil {
+			t.Fatalf("Failed to count changes: %v", err)
+		}
+		return rows
+	}
+
+	// Rows written before partitioning is enabled are kept
+	plain, err := NewPostgresAdapterWithNaming(dsn, config.StorageModeChangelog, naming, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	if err := plain.WriteChanges(ctx, []fetcher.ChangeEvent{change("old", now.AddDate(-1, 0, 0)), change("recent", now)}); err != nil {
+		t.Fatalf("WriteChanges() error = %v", err)
+	}
+	plain.Close()
+
+	options := PostgresOptions{
+		Naming:       naming,
+		Partitioning: config.PartitionConfig{Enabled: true, Interval: config.PartitionIntervalMonth, Premake: 2},
+	}
+	adapter, err := NewPostgresAdapterWithOptions(dsn, config.StorageModeChangelog, options, logger)
+	if err != nil {
+		t.Fatalf("Failed to create partitioned adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	var kind string
+	if err := db.QueryRowContext(ctx, "SELECT relkind::text FROM pg_class WHERE oid = 'sync_partition_test.fga_changelog'::regclass").Scan(&kind); err != nil {
+		t.Fatalf("Failed to inspect changelog: %v", err)
+	}
+	if kind != "p" {
+		t.Fatalf("Expected a partitioned changelog, got relkind %q", kind)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("Expected the converted changelog to keep 2 rows, got %d", got)
+	}
+
+	if err := adapter.WriteChanges(ctx, []fetcher.ChangeEvent{change("new", now)}); err != nil {
+		t.Fatalf("WriteChanges() on partitioned changelog error = %v", err)
+	}
+	if got := count(); got != 3 {
+		t.Errorf("Expected 3 rows, got %d", got)
+	}
+
+	// Opening again is a no-op
+	again, err := NewPostgresAdapterWithOptions(dsn, config.StorageModeChangelog, options, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen partitioned adapter: %v", err)
+	}
+	again.Close()
+
+	// Two years on with a 30 day retention, every current partition expires
+	later := now.AddDate(2, 0, 0)
+	adapter.partitions.now = func() time.Time { return later }
+	adapter.partitions.cfg.Retention = 30 * 24 * time.Hour
+	created, removed, err := adapter.partitions.maintain(ctx)
+	if err != nil {
+		t.Fatalf("maintain() error = %v", err)
+	}
+	if created == 0 || removed == 0 {
+		t.Errorf("Expected partitions to be created and removed, got %d and %d", created, removed)
+	}
+	if got := count(); got != 0 {
+		t.Errorf("Expected expired rows to be removed, got %d", got)
+	}
+
+	// Expired changes land in the default partition until the next pass
+	if err := adapter.WriteChanges(ctx, []fetcher.ChangeEvent{change("expired", now), change("current", later)}); err != nil {
+		t.Fatalf("WriteChanges() after retention error = %v", err)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("Expected both changes to be written, got %d rows", got)
+	}
+	if _, _, err := adapter.partitions.maintain(ctx); err != nil {
+		t.Fatalf("maintain() error = %v", err)
+	}
+	if got := count(); got != 1 {
+		t.Errorf("Expected the expired change to be pruned, got %d rows", got)
+	}
+
+	// Changes written past the last partition are moved into a new one
+	adapter.partitions.cfg.Retention = 0
+	adapter.partitions.cfg.Premake = 0
+	beyond := later.AddDate(0, 6, 0)
+	if err := adapter.WriteChanges(ctx, []fetcher.ChangeEvent{change("beyond", beyond)}); err != nil {
+		t.Fatalf("WriteChanges() past the last partition error = %v", err)
+	}
+	adapter.partitions.now = func() time.Time { return beyond }
+	if _, _, err := adapter.partitions.maintain(ctx); err != nil {
+		t.Fatalf("maintain() with rows in the default partition error = %v", err)
+	}
+	var stranded int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sync_partition_test.fga_changelog_default").Scan(&stranded); err != nil {
+		t.Fatalf("Failed to count default partition rows: %v", err)
+	}
+	if stranded != 0 {
+		t.Errorf("Expected the default partition to be empty, got %d rows", stranded)
+	}
+
+	// Rolling back the migration restores a plain table with every row
+	migrator, err := newPostgresMigrator(db, naming, true, logger)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT relkind::text FROM pg_class WHERE oid = 'sync_partition_test.fga_changelog'::regclass").Scan(&kind); err != nil {
+		t.Fatalf("Failed to inspect changelog: %v", err)
+	}
+	if kind != "r" {
+		t.Errorf("Expected a plain changelog after rolling back, got relkind %q", kind)
+	}
+	if got := count(); got != 2 {
+		t.Errorf("Expected 2 rows after rolling back, got %d", got)
+	}
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultRedisPrefix = "fga:"
+
+// RedisAdapter implements StorageAdapter on Redis. In stateful mode it keeps
+// two families of sets for reverse lookups:
+//
+//	<prefix>objects:<user>:<relation>:<object_type>  object IDs the user has the relation on
+//	<prefix>users:<object_type>:<object_id>:<relation>  users with the relation on the object
+//
+// In changelog mode changes are appended to the <prefix>changelog stream. Each
+// batch and the continuation token (<prefix>continuation_token) are written in
+// one MULTI/EXEC transaction.
+type RedisAdapter struct {
+	client *redis.Client
+	prefix string
+	logger *logrus.Logger
+	mode   config.StorageMode
+}
+
+// RedisConfig represents the configuration for the Redis adapter
+type RedisConfig struct {
+	URL    string `json:"url"`              // redis:// or rediss:// URL
+	Prefix string `json:"prefix,omitempty"` // Key prefix, defaults to "fga:"
+}
+
+// NewRedisAdapter creates a new Redis storage adapter
+func NewRedisAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*RedisAdapter, error) {
+	cfg, err := parseRedisDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis DSN: %w", err)
+	}
+
+	options, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	adapter := &RedisAdapter{
+		client: client,
+		prefix: cfg.Prefix,
+		logger: logger,
+		mode:   mode,
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":         options.Addr,
+		"db":           options.DB,
+		"prefix":       cfg.Prefix,
+		"storage_mode": mode,
+	}).Info("Successfully created Redis storage adapter")
+
+	return adapter, nil
+}
+
+// parseRedisDSN parses the Redis DSN configuration string
+// Supports two formats:
+// 1. Simple: a Redis URL (e.g., "redis://localhost:6379/0")
+// 2. JSON: {"url":"redis://localhost:6379/0","prefix":"fga:"}
+func parseRedisDSN(dsn string) (*RedisConfig, error) {
+	var cfg RedisConfig
+
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("JSON DSN must contain a non-empty 'url' field")
+		}
+	} else {
+		cfg.URL = dsn
+	}
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultRedisPrefix
+	}
+
+	return &cfg, nil
+}
+
+// objectsKey returns the key of the set of object IDs of objectType on which
+// user has relation
+func (r *RedisAdapter) objectsKey(user, relation, objectType string) string {
+	return r.prefix + "objects:" + user + ":" + relation + ":" + objectType
+}
+
+// usersKey returns the key of the set of users with relation on the object
+func (r *RedisAdapter) usersKey(objectType, objectID, relation string) string {
+	return r.prefix + "users:" + objectType + ":" + objectID + ":" + relation
+}
+
+func (r *RedisAdapter) tokenKey() string {
+	return r.prefix + "continuation_token"
+}
+
+func (r *RedisAdapter) changelogKey() string {
+	return r.prefix + "changelog"
+}
+
+// redisUser returns the user of a change in OpenFGA notation (user:anne,
+// group:eng#member)
+func redisUser(change fetcher.ChangeEvent) string {
+	return change.UserType + ":" + change.UserID
+}
+
+// WriteChanges appends a batch of change events to the changelog stream (changelog mode)
+func (r *RedisAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return r.writeChanges(ctx, changes, nil)
+}
+
+// WriteChangesWithToken appends a batch of change events and saves the
+// continuation token in the same transaction (changelog mode)
+func (r *RedisAdapter) WriteChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return r.writeChanges(ctx, changes, &token)
+}
+
+// writeChanges adds one stream entry per change and, when token is non-nil,
+// sets the continuation token in the same MULTI/EXEC
+func (r *RedisAdapter) writeChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "redis.write_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(r.mode)),
+			attribute.String("db.system", "redis"),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if r.mode != config.StorageModeChangelog {
+		err := fmt.Errorf("WriteChanges is only supported in changelog mode")
+		span.RecordError(err)
+		return err
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, change := range changes {
+			rawEventJSON, err := json.Marshal(change)
+			if err != nil {
+				return fmt.Errorf("failed to marshal change event: %w", err)
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: r.changelogKey(),
+				Values: map[string]interface{}{
+					"change_type": change.Operation,
+					"event":       string(rawEventJSON),
+				},
+			})
+		}
+		if token != nil {
+			pipe.Set(ctx, r.tokenKey(), *token, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to write changes to Redis: %w", err)
+	}
+
+	r.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
+	return nil
+}
+
+// ApplyChanges updates the reverse-lookup sets (stateful mode)
+func (r *RedisAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return r.applyChanges(ctx, changes, nil)
+}
+
+// ApplyChangesWithToken updates the reverse-lookup sets and saves the
+// continuation token in the same transaction (stateful mode)
+func (r *RedisAdapter) ApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return r.applyChanges(ctx, changes, &token)
+}
+
+// applyChanges issues SADD for writes and SREM for deletes on both set
+// families and, when token is non-nil, sets the continuation token in the
+// same MULTI/EXEC. Conditions are not evaluated: a conditional tuple is added
+// like any other.
+func (r *RedisAdapter) applyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "redis.apply_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(r.mode)),
+			attribute.String("db.system", "redis"),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if r.mode != config.StorageModeStateful {
+		err := fmt.Errorf("ApplyChanges is only supported in stateful mode")
+		span.RecordError(err)
+		return err
+	}
+
+	var insertCount, deleteCount int
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, change := range changes {
+			user := redisUser(change)
+			objectsKey := r.objectsKey(user, change.Relation, change.ObjectType)
+			usersKey := r.usersKey(change.ObjectType, change.ObjectID, change.Relation)
+
+			switch strings.ToUpper(change.Operation) {
+			case "TUPLE_OPERATION_WRITE":
+				pipe.SAdd(ctx, objectsKey, change.ObjectID)
+				pipe.SAdd(ctx, usersKey, user)
+				insertCount++
+			case "TUPLE_OPERATION_DELETE":
+				pipe.SRem(ctx, objectsKey, change.ObjectID)
+				pipe.SRem(ctx, usersKey, user)
+				deleteCount++
+			default:
+				r.logger.WithField("operation", change.Operation).Warn("Unknown operation type, skipping")
+			}
+		}
+		if token != nil {
+			pipe.Set(ctx, r.tokenKey(), *token, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to apply changes to Redis: %w", err)
+	}
+
+	span.SetAttributes(
+		attribute.Int("db.inserts", insertCount),
+		attribute.Int("db.deletes", deleteCount),
+	)
+
+	r.logger.WithFields(logrus.Fields{
+		"inserts": insertCount,
+		"deletes": deleteCount,
+	}).Info("Successfully applied changes to Redis")
+	return nil
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (r *RedisAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := r.client.Get(ctx, r.tokenKey()).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get continuation token: %w", err)
+	}
+	return token, nil
+}
+
+// SaveContinuationToken saves the continuation token for resuming processing
+func (r *RedisAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if err := r.client.Set(ctx, r.tokenKey(), token, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns statistics about the Redis adapter
+func (r *RedisAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	stats := map[string]interface{}{
+		"adapter_type":      "redis",
+		"prefix":            r.prefix,
+		"storage_mode":      string(r.mode),
+		"connection_status": "connected",
+	}
+
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		stats["connection_status"] = "disconnected"
+		return stats, nil
+	}
+
+	token, err := r.GetLastContinuationToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats["last_token"] = token
+
+	if r.mode == config.StorageModeChangelog {
+		count, err := r.client.XLen(ctx, r.changelogKey()).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get changelog length: %w", err)
+		}
+		stats["changelog_entries"] = count
+	}
+
+	return stats, nil
+}
+
+// Close closes the Redis connection
+func (r *RedisAdapter) Close() error {
+	r.logger.Info("Closing Redis adapter")
+	return r.client.Close()
+}
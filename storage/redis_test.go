@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestRedisAdapter(t *testing.T, mode config.StorageMode) (*RedisAdapter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	adapter, err := NewRedisAdapter("redis://"+server.Addr(), mode, logger)
+	if err != nil {
+		t.Fatalf("Failed to create Redis adapter: %v", err)
+	}
+	t.Cleanup(func() { adapter.Close() })
+	return adapter, server
+}
+
+func TestParseRedisDSN(t *testing.T) {
+	cfg, err := parseRedisDSN("redis://localhost:6379/0")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if cfg.URL != "redis://localhost:6379/0" || cfg.Prefix != defaultRedisPrefix {
+		t.Errorf("Unexpected simple DSN config: %+v", cfg)
+	}
+
+	cfg, err = parseRedisDSN(`{"url":"rediss://cache:6380/1","prefix":"authz:"}`)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON DSN: %v", err)
+	}
+	if cfg.Prefix != "authz:" {
+		t.Errorf("Unexpected JSON DSN config: %+v", cfg)
+	}
+
+	if _, err := parseRedisDSN(`{"prefix":"authz:"}`); err == nil {
+		t.Error("Expected error for JSON DSN without url")
+	}
+}
+
+func TestRedisAdapter_ApplyChanges(t *testing.T) {
+	adapter, server := newTestRedisAdapter(t, config.StorageModeStateful)
+	ctx := context.Background()
+
+	write := func(objectID, relation, userType, userID string) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{ObjectType: "document", ObjectID: objectID, Relation: relation, UserType: userType, UserID: userID, Operation: "TUPLE_OPERATION_WRITE"}
+	}
+	changes := []fetcher.ChangeEvent{
+		write("roadmap", "viewer", "user", "anne"),
+		write("budget", "viewer", "user", "anne"),
+		write("roadmap", "viewer", "group", "eng#member"),
+	}
+	if err := adapter.ApplyChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("Failed to apply changes: %v", err)
+	}
+
+	deleted := write("budget", "viewer", "user", "anne")
+	deleted.Operation = "TUPLE_OPERATION_DELETE"
+	if err := adapter.ApplyChangesWithToken(ctx, []fetcher.ChangeEvent{deleted}, "token-2"); err != nil {
+		t.Fatalf("Failed to apply delete: %v", err)
+	}
+
+	objects, _ := server.Members("fga:objects:user:anne:viewer:document")
+	if len(objects) != 1 || objects[0] != "roadmap" {
+		t.Errorf("Expected anne to view only roadmap, got %v", objects)
+	}
+	users, _ := server.Members("fga:users:document:roadmap:viewer")
+	if len(users) != 2 || users[0] != "group:eng#member" || users[1] != "user:anne" {
+		t.Errorf("Expected roadmap viewers group:eng#member and user:anne, got %v", users)
+	}
+	if server.Exists("fga:users:document:budget:viewer") {
+		t.Error("Expected the budget viewers set to be removed once empty")
+	}
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get continuation token: %v", err)
+	}
+	if token != "token-2" {
+		t.Errorf("Expected token-2, got %q", token)
+	}
+
+	if err := adapter.WriteChanges(ctx, changes); err == nil {
+		t.Error("Expected WriteChanges to fail in stateful mode")
+	}
+}
+
+func TestRedisAdapter_WriteChanges(t *testing.T) {
+	adapter, _ := newTestRedisAdapter(t, config.StorageModeChangelog)
+	ctx := context.Background()
+
+	changes := []fetcher.ChangeEvent{
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_WRITE", Timestamp: time.Now().UTC()},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", Operation: "TUPLE_OPERATION_DELETE", Timestamp: time.Now().UTC()},
+	}
+	if err := adapter.WriteChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("Failed to write changes: %v", err)
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats["changelog_entries"] != int64(2) || stats["last_token"] != "token-1" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// retentionBatchSize bounds the rows deleted per statement, so writers are
+// only held up briefly while a large backlog is pruned
+const retentionBatchSize = 5000
+
+// sqliteRetention bounds a SQLite fga_changelog in the background. Each pass
+// deletes changes past the maximum age and row count, compacts old
+// write/delete pairs, checkpoints the WAL and, at most once per vacuum
+// interval, runs VACUUM.
+type sqliteRetention struct {
+	db         *sql.DB
+	logger     *logrus.Logger
+	cfg        config.RetentionConfig
+	table      string
+	now        func() time.Time
+	lastVacuum time.Time
+	stop       chan struct{}
+	done       chan struct{}
+
+	mu       sync.Mutex
+	observer func(MaintenanceReport)
+}
+
+// newSQLiteRetention returns the retention task for a changelog table; cfg
+// is defaulted
+func newSQLiteRetention(db *sql.DB, cfg config.RetentionConfig, table string, logger *logrus.Logger) *sqliteRetention {
+	return &sqliteRetention{
+		db:     db,
+		logger: logger,
+		cfg:    cfg.WithDefaults(),
+		table:  table,
+		now:    time.Now,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// start runs a pass every check interval until close. The first pass runs
+// after one interval, once the service is up and observers are registered.
+func (r *sqliteRetention) start() {
+	go r.loop()
+}
+
+// close stops the background task, waiting for a running pass to finish
+func (r *sqliteRetention) close() {
+	close(r.stop)
+	<-r.done
+}
+
+// onReport registers fn to receive the report of every pass
+func (r *sqliteRetention) onReport(fn func(MaintenanceReport)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = fn
+}
+
+// loop runs maintenance passes on a schedule. Failures are retried on the
+// next tick.
+func (r *sqliteRetention) loop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if _, err := r.run(context.Background()); err != nil {
+				r.logger.WithError(err).Warn("Failed to maintain changelog, will retry")
+			}
+		}
+	}
+}
+
+// run performs one maintenance pass and reports it to the observer
+func (r *sqliteRetention) run(ctx context.Context) (MaintenanceReport, error) {
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "sqlite.maintain_changelog",
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+		),
+	)
+	defer span.End()
+
+	var report MaintenanceReport
+	var err error
+	now := r.now()
+
+	if r.cfg.MaxAge > 0 {
+		report.ExpiredRows, err = r.deleteBatches(ctx, fmt.Sprintf(
+			"DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE timestamp < ? ORDER BY id LIMIT ?)", r.table),
+			sqliteChangelogTime(now.Add(-r.cfg.MaxAge)))
+		if err != nil {
+			span.RecordError(err)
+			return report, fmt.Errorf("failed to delete expired changes: %w", err)
+		}
+	}
+
+	if r.cfg.MaxRows > 0 {
+		report.OverflowRows, err = r.deleteOverflow(ctx)
+		if err != nil {
+			span.RecordError(err)
+			return report, fmt.Errorf("failed to delete changes beyond max_rows: %w", err)
+		}
+	}
+
+	if r.cfg.CompactAfter > 0 {
+		report.CompactedRows, err = r.compact(ctx, now.Add(-r.cfg.CompactAfter))
+		if err != nil {
+			span.RecordError(err)
+			return report, fmt.Errorf("failed to compact changelog: %w", err)
+		}
+	}
+
+	if err := r.reclaim(ctx, now, &report); err != nil {
+		span.RecordError(err)
+		return report, err
+	}
+
+	span.SetAttributes(
+		attribute.Int64("db.expired_rows", report.ExpiredRows),
+		attribute.Int64("db.overflow_rows", report.OverflowRows),
+		attribute.Int64("db.compacted_rows", report.CompactedRows),
+		attribute.Int64("db.reclaimed_bytes", report.ReclaimedBytes),
+	)
+
+	if report.ExpiredRows > 0 || report.OverflowRows > 0 || report.CompactedRows > 0 || report.ReclaimedBytes > 0 {
+		r.logger.WithFields(logrus.Fields{
+			"table":           r.table,
+			"expired_rows":    report.ExpiredRows,
+			"overflow_rows":   report.OverflowRows,
+			"compacted_rows":  report.CompactedRows,
+			"reclaimed_bytes": report.ReclaimedBytes,
+			"database_bytes":  report.DatabaseBytes,
+		}).Info("Maintained changelog")
+	}
+
+	r.mu.Lock()
+	observer := r.observer
+	r.mu.Unlock()
+	if observer != nil {
+		observer(report)
+	}
+	return report, nil
+}
+
+// deleteBatches runs a DELETE whose last parameter is the batch size until it
+// deletes less than a full batch, and returns the rows deleted
+func (r *sqliteRetention) deleteBatches(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var total int64
+	args = append(args, retentionBatchSize)
+	for {
+		result, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < retentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// deleteOverflow deletes the oldest changes beyond max_rows
+func (r *sqliteRetention) deleteOverflow(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+r.table).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for excess := count - r.cfg.MaxRows; excess > 0; {
+		batch := excess
+		if batch > retentionBatchSize {
+			batch = retentionBatchSize
+		}
+		result, err := r.db.ExecContext(ctx, fmt.Sprintf(
+			"DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s ORDER BY id LIMIT ?)", r.table), batch)
+		if err != nil {
+			return total, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		if deleted == 0 {
+			break
+		}
+		total += deleted
+		excess -= deleted
+	}
+	return total, nil
+}
+
+// compact removes each delete older than horizon together with the write of
+// the same tuple right before it, since together they leave no trace in the
+// current tuple set
+func (r *sqliteRetention) compact(ctx context.Context, horizon time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+		WITH pairs AS MATERIALIZED (
+			SELECT id, previous_id FROM (
+				SELECT id, change_type, timestamp,
+					LAG(id) OVER tuple AS previous_id,
+					LAG(change_type) OVER tuple AS previous_type
+				FROM %[1]s
+				WINDOW tuple AS (PARTITION BY store_id, object_type, object_id, relation, user_type, user_id ORDER BY id)
+			)
+			WHERE change_type = 'TUPLE_OPERATION_DELETE' AND previous_type = 'TUPLE_OPERATION_WRITE' AND timestamp < ?
+			LIMIT ?
+		)
+		DELETE FROM %[1]s WHERE id IN (SELECT id FROM pairs UNION ALL SELECT previous_id FROM pairs)
+	`, r.table)
+
+	// Each pair is two rows, so half a batch of pairs fills a batch of rows
+	var total int64
+	for {
+		result, err := r.db.ExecContext(ctx, query, sqliteChangelogTime(horizon), retentionBatchSize/2)
+		if err != nil {
+			return total, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < retentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// reclaim checkpoints and truncates the WAL and, when a vacuum is due, runs
+// VACUUM, recording the space returned to the file system
+func (r *sqliteRetention) reclaim(ctx context.Context, now time.Time, report *MaintenanceReport) error {
+	pageSize, pages, err := r.pages(ctx)
+	if err != nil {
+		return err
+	}
+
+	var busy, walFrames, checkpointed int64
+	if err := r.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &walFrames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	// A busy checkpoint leaves the WAL in place for the next pass
+	if busy == 0 && walFrames > 0 {
+		report.ReclaimedBytes += walFrames * pageSize
+	}
+
+	if r.cfg.VacuumInterval > 0 && now.Sub(r.lastVacuum) >= r.cfg.VacuumInterval {
+		var free int64
+		if err := r.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+			return fmt.Errorf("failed to read the free page count: %w", err)
+		}
+		if free > 0 {
+			if _, err := r.db.ExecContext(ctx, "VACUUM"); err != nil {
+				return fmt.Errorf("failed to vacuum database: %w", err)
+			}
+			_, after, err := r.pages(ctx)
+			if err != nil {
+				return err
+			}
+			if after < pages {
+				report.ReclaimedBytes += (pages - after) * pageSize
+			}
+			pages = after
+		}
+		r.lastVacuum = now
+	}
+
+	report.DatabaseBytes = pages * pageSize
+	return nil
+}
+
+// pages returns the page size and page count of the database
+func (r *sqliteRetention) pages(ctx context.Context) (int64, int64, error) {
+	var pageSize, pages int64
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, 0, fmt.Errorf("failed to read the page size: %w", err)
+	}
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, 0, fmt.Errorf("failed to read the page count: %w", err)
+	}
+	return pageSize, pages, nil
+}
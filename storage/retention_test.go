@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+func newRetentionTestAdapter(t *testing.T, retention config.RetentionConfig) *SQLiteAdapter {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	// A long check interval keeps the background task out of the way
+	retention.CheckInterval = time.Hour
+	adapter, err := NewSQLiteAdapterWithOptions(t.TempDir()+"/retention.db", config.StorageModeChangelog, SQLiteOptions{Retention: retention}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	t.Cleanup(func() { adapter.Close() })
+	return adapter
+}
+
+func retentionChange(operation, objectID string, at time.Time) fetcher.ChangeEvent {
+	return fetcher.ChangeEvent{Operation: operation, ObjectType: "document", ObjectID: objectID, Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: at}
+}
+
+func changelogObjects(t *testing.T, adapter *SQLiteAdapter) []string {
+	rows, err := adapter.db.Query("SELECT change_type, object_id FROM fga_changelog ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query changelog: %v", err)
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var changeType, objectID string
+		if err := rows.Scan(&changeType, &objectID); err != nil {
+			t.Fatalf("Failed to scan changelog: %v", err)
+		}
+		objects = append(objects, strings.TrimPrefix(changeType, "TUPLE_OPERATION_")+":"+objectID)
+	}
+	return objects
+}
+
+func TestSQLiteRetention_MaxAgeAndRows(t *testing.T) {
+	ctx := context.Background()
+	adapter := newRetentionTestAdapter(t, config.RetentionConfig{MaxAge: 30 * 24 * time.Hour, MaxRows: 3})
+
+	now := time.Now()
+	changes := []fetcher.ChangeEvent{
+		retentionChange("TUPLE_OPERATION_WRITE", "old-1", now.AddDate(0, 0, -60)),
+		retentionChange("TUPLE_OPERATION_WRITE", "old-2", now.AddDate(0, 0, -45)),
+	}
+	for i := 1; i <= 4; i++ {
+		changes = append(changes, retentionChange("TUPLE_OPERATION_WRITE", fmt.Sprintf("recent-%d", i), now.Add(-time.Hour)))
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("WriteChanges() error = %v", err)
+	}
+
+	report, err := adapter.retention.run(ctx)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if report.ExpiredRows != 2 || report.OverflowRows != 1 {
+		t.Errorf("Expected 2 expired and 1 overflow rows, got %+v", report)
+	}
+	if got := strings.Join(changelogObjects(t, adapter), ","); got != "WRITE:recent-2,WRITE:recent-3,WRITE:recent-4" {
+		t.Errorf("Unexpected changelog after retention: %s", got)
+	}
+	if report.DatabaseBytes <= 0 {
+		t.Errorf("Expected the database size to be reported, got %d", report.DatabaseBytes)
+	}
+}
+
+func TestSQLiteRetention_Compaction(t *testing.T) {
+	ctx := context.Background()
+	adapter := newRetentionTestAdapter(t, config.RetentionConfig{CompactAfter: 7 * 24 * time.Hour})
+
+	days := func(n int) time.Time { return time.Now().AddDate(0, 0, -n) }
+	changes := []fetcher.ChangeEvent{
+		retentionChange("TUPLE_OPERATION_WRITE", "a", days(10)),
+		retentionChange("TUPLE_OPERATION_DELETE", "a", days(9)),
+		retentionChange("TUPLE_OPERATION_WRITE", "a", days(8)), // Still exists
+		retentionChange("TUPLE_OPERATION_WRITE", "b", days(10)),
+		retentionChange("TUPLE_OPERATION_DELETE", "b", days(1)), // Newer than the horizon
+		retentionChange("TUPLE_OPERATION_WRITE", "c", days(20)),
+		retentionChange("TUPLE_OPERATION_DELETE", "c", days(19)),
+		retentionChange("TUPLE_OPERATION_WRITE", "c", days(18)),
+		retentionChange("TUPLE_OPERATION_DELETE", "c", days(17)),
+		retentionChange("TUPLE_OPERATION_DELETE", "d", days(30)), // No write to pair with
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("WriteChanges() error = %v", err)
+	}
+
+	report, err := adapter.retention.run(ctx)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if report.CompactedRows != 6 {
+		t.Errorf("Expected 6 compacted rows, got %d", report.CompactedRows)
+	}
+	if got := strings.Join(changelogObjects(t, adapter), ","); got != "WRITE:a,WRITE:b,DELETE:b,DELETE:d" {
+		t.Errorf("Unexpected changelog after compaction: %s", got)
+	}
+}
+
+func TestSQLiteRetention_VacuumAndReport(t *testing.T) {
+	ctx := context.Background()
+	adapter := newRetentionTestAdapter(t, config.RetentionConfig{MaxRows: 10, VacuumInterval: 24 * time.Hour})
+
+	var reports []MaintenanceReport
+	adapter.OnMaintenance(func(report MaintenanceReport) {
+		reports = append(reports, report)
+	})
+
+	var changes []fetcher.ChangeEvent
+	for i := 0; i < 2000; i++ {
+		changes = append(changes, retentionChange("TUPLE_OPERATION_WRITE", fmt.Sprintf("%s-%d", strings.Repeat("x", 200), i), time.Now()))
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("WriteChanges() error = %v", err)
+	}
+
+	report, err := adapter.retention.run(ctx)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if report.OverflowRows != 1990 {
+		t.Errorf("Expected 1990 overflow rows, got %d", report.OverflowRows)
+	}
+	if report.ReclaimedBytes <= 0 {
+		t.Errorf("Expected VACUUM and the WAL checkpoint to reclaim space, got %d", report.ReclaimedBytes)
+	}
+	if len(reports) != 1 || reports[0] != report {
+		t.Errorf("Expected the observer to receive the report, got %+v", reports)
+	}
+
+	// The next vacuum waits for the interval
+	second, err := adapter.retention.run(ctx)
+	if err != nil {
+		t.Fatalf("second run() error = %v", err)
+	}
+	if second.ReclaimedBytes != 0 {
+		t.Errorf("Expected nothing to reclaim on the second pass, got %d", second.ReclaimedBytes)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aaguiarz/openfga-sync/config"
 	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
@@ -18,13 +20,87 @@ import (
 
 // SQLiteAdapter implements StorageAdapter for SQLite
 type SQLiteAdapter struct {
-	db     *sql.DB
-	logger *logrus.Logger
-	mode   config.StorageMode
+	db         *sql.DB
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	storeID    string // Rows are scoped to this store; "" in single-store mode
+	objectType string // Continuation token cursor; "" when fetching all types
+	shared     bool   // Views don't own the connection
+	naming     migrations.Naming
+	tables     sqlTables
+	retention  *sqliteRetention // nil unless changelog retention is configured
 }
 
+// SQLiteOptions configures how a SQLiteAdapter names its tables and bounds
+// its changelog
+type SQLiteOptions struct {
+	Naming    migrations.Naming
+	Retention config.RetentionConfig
+}
+
+// sqliteTuplesTable is the stateful table definition of the baseline
+// migration, used to rebuild tables created before multi-store support
+const sqliteTuplesTable = `CREATE TABLE IF NOT EXISTS fga_tuples (
+	store_id TEXT NOT NULL DEFAULT '',
+	object_type TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	relation TEXT NOT NULL,
+	user_type TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	condition TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (store_id, object_type, object_id, relation, user_type, user_id)
+)`
+
 // NewSQLiteAdapter creates a new SQLite storage adapter
 func NewSQLiteAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger) (*SQLiteAdapter, error) {
+	return NewSQLiteAdapterWithNaming(dsn, mode, migrations.Naming{}, logger)
+}
+
+// NewSQLiteAdapterWithNaming creates a SQLite storage adapter whose tables
+// are prefixed by naming, so several pipelines can share a database file
+func NewSQLiteAdapterWithNaming(dsn string, mode config.StorageMode, naming migrations.Naming, logger *logrus.Logger) (*SQLiteAdapter, error) {
+	return NewSQLiteAdapterWithOptions(dsn, mode, SQLiteOptions{Naming: naming}, logger)
+}
+
+// NewSQLiteAdapterWithOptions creates a SQLite storage adapter prefixed by
+// options.Naming. With options.Retention set in a changelog mode, the
+// changelog is pruned in the background until Close.
+func NewSQLiteAdapterWithOptions(dsn string, mode config.StorageMode, options SQLiteOptions, logger *logrus.Logger) (*SQLiteAdapter, error) {
+	naming := options.Naming
+	if naming.Schema != "" {
+		return nil, fmt.Errorf("sqlite does not support schemas, use a table prefix")
+	}
+
+	db, err := openSQLite(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &SQLiteAdapter{
+		db:     db,
+		logger: logger,
+		mode:   mode,
+		naming: naming,
+		tables: newSQLTables(naming),
+	}
+
+	// Initialize database schema
+	if err := adapter.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+	}
+
+	if options.Retention.Enabled() && mode.HasChangelog() {
+		adapter.retention = newSQLiteRetention(db, options.Retention, adapter.tables.changelog, logger)
+		adapter.retention.start()
+	}
+
+	return adapter, nil
+}
+
+// openSQLite opens and configures a SQLite database
+func openSQLite(dsn string) (*sql.DB, error) {
 	// SQLite DSN format: file:path/to/db.sqlite?cache=shared&mode=rwc
 	// If no file prefix, add it
 	if !strings.HasPrefix(dsn, "file:") && dsn != ":memory:" {
@@ -48,89 +124,120 @@ func NewSQLiteAdapter(dsn string, mode config.StorageMode, logger *logrus.Logger
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	adapter := &SQLiteAdapter{
-		db:     db,
-		logger: logger,
-		mode:   mode,
-	}
+	return db, nil
+}
 
-	// Initialize database schema
-	if err := adapter.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
-	}
+// ForStore returns a view of the adapter scoped to storeID
+func (s *SQLiteAdapter) ForStore(storeID string) StorageAdapter {
+	view := *s
+	view.storeID = storeID
+	view.shared = true
+	return &view
+}
 
-	return adapter, nil
+// ForObjectType returns a view of the adapter with its own cursor for objectType
+func (s *SQLiteAdapter) ForObjectType(objectType string) StorageAdapter {
+	view := *s
+	view.objectType = objectType
+	view.shared = true
+	return &view
 }
 
-// initSchema creates the necessary database tables
+// initSchema brings the database schema up to the latest migration
 func (s *SQLiteAdapter) initSchema() error {
-	var queries []string
-
-	// Common sync state table
-	queries = append(queries, []string{
-		`CREATE TABLE IF NOT EXISTS sync_state (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			continuation_token TEXT,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`INSERT OR IGNORE INTO sync_state (id, continuation_token) VALUES (1, '')`,
-	}...)
-
-	// Mode-specific tables
-	if s.mode == config.StorageModeChangelog {
-		// Changelog mode: append-only table with all change events
-		queries = append(queries, []string{
-			`CREATE TABLE IF NOT EXISTS fga_changelog (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				change_type TEXT NOT NULL,
-				object_type TEXT NOT NULL,
-				object_id TEXT NOT NULL,
-				relation TEXT NOT NULL,
-				user_type TEXT NOT NULL,
-				user_id TEXT NOT NULL,
-				timestamp DATETIME NOT NULL,
-				condition TEXT,
-				raw_event TEXT,
-				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-			)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_timestamp ON fga_changelog(timestamp)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_user_type ON fga_changelog(user_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_object_type ON fga_changelog(object_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_relation ON fga_changelog(relation)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_changelog_change_type ON fga_changelog(change_type)`,
-		}...)
-	} else {
-		// Stateful mode: current state table
-		queries = append(queries, []string{
-			`CREATE TABLE IF NOT EXISTS fga_tuples (
-				object_type TEXT NOT NULL,
-				object_id TEXT NOT NULL,
-				relation TEXT NOT NULL,
-				user_type TEXT NOT NULL,
-				user_id TEXT NOT NULL,
-				condition TEXT,
-				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-				PRIMARY KEY (object_type, object_id, relation, user_type, user_id)
-			)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_user_type ON fga_tuples(user_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_object_type ON fga_tuples(object_type)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_relation ON fga_tuples(relation)`,
-			`CREATE INDEX IF NOT EXISTS idx_fga_tuples_updated_at ON fga_tuples(updated_at)`,
-		}...)
-	}
-
-	for _, query := range queries {
-		if _, err := s.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute schema query '%s': %w", query, err)
+	migrator, err := newSQLiteMigrator(s.db, s.naming, s.logger)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return nil
+}
+
+// newSQLiteMigrator returns the schema migrator for a SQLite database
+func newSQLiteMigrator(db *sql.DB, naming migrations.Naming, logger *logrus.Logger) (*migrations.Migrator, error) {
+	migrator, err := migrations.New(db, migrations.SQLite, naming, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	// Prefixed tables postdate versioning, so only the default ones can
+	// need the legacy upgrade
+	if naming == (migrations.Naming{}) {
+		migrator.Baseline = sqliteBaseline
+	}
+	return migrator, nil
+}
+
+// sqliteBaseline adds the columns introduced by multi-store support to
+// databases created before schema migrations. It runs inside the migration
+// transaction.
+func sqliteBaseline(ctx context.Context, conn migrations.Conn) error {
+	for _, column := range [][2]string{
+		{"sync_state", "store_id"},
+		{"sync_state", "object_type"},
+		{"fga_changelog", "store_id"},
+	} {
+		table, name := column[0], column[1]
+		missing, err := sqliteMissingColumn(ctx, conn, table, name)
+		if err != nil {
+			return err
+		}
+		if missing {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT NOT NULL DEFAULT ''", table, name)); err != nil {
+				return fmt.Errorf("failed to migrate %s.%s: %w", table, name, err)
+			}
+		}
+	}
+
+	legacy, err := sqliteMissingColumn(ctx, conn, "fga_tuples", "store_id")
+	if err != nil || !legacy {
+		return err
+	}
+
+	// SQLite can't change a primary key in place, so rebuild the table. The
+	// old indexes are dropped with it and recreated by the baseline migration.
+	for _, query := range []string{
+		`ALTER TABLE fga_tuples RENAME TO fga_tuples_legacy`,
+		sqliteTuplesTable,
+		`INSERT INTO fga_tuples (object_type, object_id, relation, user_type, user_id, condition, created_at, updated_at)
+		 SELECT object_type, object_id, relation, user_type, user_id, condition, created_at, updated_at FROM fga_tuples_legacy`,
+		`DROP TABLE fga_tuples_legacy`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to migrate fga_tuples: %w", err)
 		}
 	}
 
 	return nil
 }
 
+// sqliteMissingColumn reports whether table exists without column
+func sqliteMissingColumn(ctx context.Context, conn migrations.Conn, table, column string) (bool, error) {
+	var tables, columns int
+	err := conn.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?),
+		(SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?)`, table, table, column).Scan(&tables, &columns)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	return tables > 0 && columns == 0, nil
+}
+
 // WriteChanges writes a batch of change events to SQLite (changelog mode)
 func (s *SQLiteAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return s.writeChanges(ctx, changes, nil)
+}
+
+// WriteChangesWithToken writes a batch of change events and saves the
+// continuation token in the same transaction (changelog mode)
+func (s *SQLiteAdapter) WriteChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return s.writeChanges(ctx, changes, &token)
+}
+
+// writeChanges inserts changes into fga_changelog and, when token is non-nil,
+// updates sync_state before committing
+func (s *SQLiteAdapter) writeChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
 	// Start OpenTelemetry span
 	tracer := otel.Tracer("openfga-sync/storage")
 	ctx, span := tracer.Start(ctx, "sqlite.write_changes",
@@ -142,12 +249,12 @@ func (s *SQLiteAdapter) WriteChanges(ctx context.Context, changes []fetcher.Chan
 	)
 	defer span.End()
 
-	if len(changes) == 0 {
+	if len(changes) == 0 && token == nil {
 		return nil
 	}
 
-	if s.mode != config.StorageModeChangelog {
-		err := fmt.Errorf("WriteChanges is only supported in changelog mode")
+	if !s.mode.HasChangelog() {
+		err := fmt.Errorf("WriteChanges is only supported in changelog and hybrid modes")
 		span.RecordError(err)
 		return err
 	}
@@ -159,12 +266,40 @@ func (s *SQLiteAdapter) WriteChanges(ctx context.Context, changes []fetcher.Chan
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO fga_changelog (change_type, object_type, object_id, relation, user_type, user_id, timestamp, condition, raw_event)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`)
-	if err != nil {
+	if err := s.insertChangelogTx(ctx, tx, changes); err != nil {
 		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := s.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.rows_affected", len(changes)),
+		attribute.String("db.operation", "insert"),
+	)
+
+	s.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
+	return nil
+}
+
+// insertChangelogTx appends changes to fga_changelog as part of an open transaction
+func (s *SQLiteAdapter) insertChangelogTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) error {
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (store_id, change_type, object_type, object_id, relation, user_type, user_id, timestamp, condition, raw_event)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.tables.changelog))
+	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
@@ -183,39 +318,39 @@ func (s *SQLiteAdapter) WriteChanges(ctx context.Context, changes []fetcher.Chan
 		}
 
 		_, err = stmt.ExecContext(ctx,
+			s.storeID,
 			change.Operation,
 			change.ObjectType,
 			change.ObjectID,
 			change.Relation,
 			change.UserType,
 			change.UserID,
-			change.Timestamp.Format("2006-01-02 15:04:05.000"),
+			sqliteChangelogTime(change.Timestamp),
 			conditionText,
 			string(rawEventJSON),
 		)
 		if err != nil {
-			span.RecordError(err)
 			return fmt.Errorf("failed to insert change: %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	// Add success attributes to span
-	span.SetAttributes(
-		attribute.Int("db.rows_affected", len(changes)),
-		attribute.String("db.operation", "insert"),
-	)
-
-	s.logger.WithField("changes_count", len(changes)).Info("Successfully wrote changes to changelog")
 	return nil
 }
 
 // ApplyChanges applies a batch of changes to state table (stateful mode)
 func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return s.applyChanges(ctx, changes, nil)
+}
+
+// ApplyChangesWithToken applies a batch of changes and saves the continuation
+// token in the same transaction (stateful mode)
+func (s *SQLiteAdapter) ApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return s.applyChanges(ctx, changes, &token)
+}
+
+// applyChanges upserts and deletes tuples in fga_tuples and, when token is
+// non-nil, updates sync_state before committing
+func (s *SQLiteAdapter) applyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
 	// Start OpenTelemetry span
 	tracer := otel.Tracer("openfga-sync/storage")
 	ctx, span := tracer.Start(ctx, "sqlite.apply_changes",
@@ -227,12 +362,12 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 	)
 	defer span.End()
 
-	if len(changes) == 0 {
+	if len(changes) == 0 && token == nil {
 		return nil
 	}
 
-	if s.mode != config.StorageModeStateful {
-		err := fmt.Errorf("ApplyChanges is only supported in stateful mode")
+	if !s.mode.HasState() && s.mode != config.StorageModeHistory {
+		err := fmt.Errorf("ApplyChanges is only supported in stateful, hybrid and history modes")
 		span.RecordError(err)
 		return err
 	}
@@ -244,26 +379,64 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 	}
 	defer tx.Rollback()
 
+	var insertCount, deleteCount int
+	if s.mode == config.StorageModeHistory {
+		insertCount, deleteCount, err = s.applyHistoryTx(ctx, tx, changes)
+	} else {
+		insertCount, deleteCount, err = s.applyTuplesTx(ctx, tx, changes)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := s.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Add success attributes to span
+	span.SetAttributes(
+		attribute.Int("db.inserts", insertCount),
+		attribute.Int("db.deletes", deleteCount),
+		attribute.String("db.operation", "upsert"),
+	)
+
+	s.logger.WithFields(logrus.Fields{
+		"inserts": insertCount,
+		"deletes": deleteCount,
+	}).Info("Successfully applied changes to state table")
+	return nil
+}
+
+// applyTuplesTx upserts and deletes tuples in fga_tuples as part of an open
+// transaction, returning the number of writes and deletes applied
+func (s *SQLiteAdapter) applyTuplesTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
 	// SQLite uses INSERT OR REPLACE for upsert functionality
-	insertStmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO fga_tuples (object_type, object_id, relation, user_type, user_id, condition, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, 
-			COALESCE((SELECT created_at FROM fga_tuples WHERE object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?), CURRENT_TIMESTAMP),
+	insertStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT OR REPLACE INTO %[1]s (store_id, object_type, object_id, relation, user_type, user_id, condition, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?,
+			COALESCE((SELECT created_at FROM %[1]s WHERE store_id = ? AND object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?), CURRENT_TIMESTAMP),
 			CURRENT_TIMESTAMP)
-	`)
+	`, s.tables.tuples))
 	if err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to prepare insert statement: %w", err)
+		return 0, 0, fmt.Errorf("failed to prepare insert statement: %w", err)
 	}
 	defer insertStmt.Close()
 
-	deleteStmt, err := tx.PrepareContext(ctx, `
-		DELETE FROM fga_tuples 
-		WHERE object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?
-	`)
+	deleteStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE store_id = ? AND object_type = ? AND object_id = ? AND relation = ? AND user_type = ? AND user_id = ?
+	`, s.tables.tuples))
 	if err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to prepare delete statement: %w", err)
+		return 0, 0, fmt.Errorf("failed to prepare delete statement: %w", err)
 	}
 	defer deleteStmt.Close()
 
@@ -278,6 +451,7 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 			}
 
 			_, err = insertStmt.ExecContext(ctx,
+				s.storeID,
 				change.ObjectType,
 				change.ObjectID,
 				change.Relation,
@@ -285,6 +459,7 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 				change.UserID,
 				conditionText,
 				// Parameters for the COALESCE subquery
+				s.storeID,
 				change.ObjectType,
 				change.ObjectID,
 				change.Relation,
@@ -292,12 +467,12 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 				change.UserID,
 			)
 			if err != nil {
-				span.RecordError(err)
-				return fmt.Errorf("failed to insert/update tuple: %w", err)
+				return 0, 0, fmt.Errorf("failed to insert/update tuple: %w", err)
 			}
 			insertCount++
 		case "TUPLE_OPERATION_DELETE":
 			_, err = deleteStmt.ExecContext(ctx,
+				s.storeID,
 				change.ObjectType,
 				change.ObjectID,
 				change.Relation,
@@ -305,8 +480,7 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 				change.UserID,
 			)
 			if err != nil {
-				span.RecordError(err)
-				return fmt.Errorf("failed to delete tuple: %w", err)
+				return 0, 0, fmt.Errorf("failed to delete tuple: %w", err)
 			}
 			deleteCount++
 		default:
@@ -314,6 +488,176 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 		}
 	}
 
+	return insertCount, deleteCount, nil
+}
+
+// sqliteChangelogTime formats a change timestamp. Changelog timestamps are
+// stored in UTC so that retention can compare them as text.
+func sqliteChangelogTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+// sqliteHistoryTime formats an interval bound. History timestamps are stored
+// in UTC with a fixed width so that text comparison orders them correctly.
+func sqliteHistoryTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000000")
+}
+
+// applyHistoryTx opens a validity interval in fga_tuple_history for each write
+// and closes the open one for each delete, as part of an open transaction. A
+// write is skipped when the tuple is already open or an interval starting at
+// the same time exists, so replaying a batch does not duplicate intervals.
+func (s *SQLiteAdapter) applyHistoryTx(ctx context.Context, tx *sql.Tx, changes []fetcher.ChangeEvent) (int, int, error) {
+	openStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s (store_id, object_type, object_id, relation, user_type, user_id, condition, valid_from)
+		SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8
+		WHERE NOT EXISTS (
+			SELECT 1 FROM %[1]s
+			WHERE store_id = ?1 AND object_type = ?2 AND object_id = ?3 AND relation = ?4 AND user_type = ?5 AND user_id = ?6
+			AND (valid_to IS NULL OR valid_from = ?8)
+		)
+	`, s.tables.history))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare open interval statement: %w", err)
+	}
+	defer openStmt.Close()
+
+	closeStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET valid_to = ?7
+		WHERE store_id = ?1 AND object_type = ?2 AND object_id = ?3 AND relation = ?4 AND user_type = ?5 AND user_id = ?6
+		AND valid_to IS NULL AND valid_from <= ?7
+	`, s.tables.history))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prepare close interval statement: %w", err)
+	}
+	defer closeStmt.Close()
+
+	var openCount, closeCount int
+	for _, change := range changes {
+		switch strings.ToUpper(change.Operation) {
+		case "TUPLE_OPERATION_WRITE":
+			var conditionText interface{}
+			if change.Condition != "" {
+				conditionText = change.Condition
+			}
+
+			result, err := openStmt.ExecContext(ctx,
+				s.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+				conditionText,
+				sqliteHistoryTime(change.Timestamp),
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to open tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				openCount++
+			}
+		case "TUPLE_OPERATION_DELETE":
+			result, err := closeStmt.ExecContext(ctx,
+				s.storeID,
+				change.ObjectType,
+				change.ObjectID,
+				change.Relation,
+				change.UserType,
+				change.UserID,
+				sqliteHistoryTime(change.Timestamp),
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to close tuple interval: %w", err)
+			}
+			if rows, _ := result.RowsAffected(); rows > 0 {
+				closeCount++
+			}
+		default:
+			s.logger.WithField("operation", change.Operation).Warn("Unknown operation type, skipping")
+		}
+	}
+
+	return openCount, closeCount, nil
+}
+
+// TuplesAsOf returns the tuples that existed at query.At (history mode)
+func (s *SQLiteAdapter) TuplesAsOf(ctx context.Context, query AsOfQuery) ([]HistoryTuple, error) {
+	if s.mode != config.StorageModeHistory {
+		return nil, fmt.Errorf("TuplesAsOf is only supported in history mode")
+	}
+
+	statement, args := historyAsOfSQL(s.tables.history, s.storeID, query, sqliteHistoryTime(query.At), func(int) string { return "?" }, "condition")
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tuple history: %w", err)
+	}
+	defer rows.Close()
+
+	return scanHistoryTuples(rows)
+}
+
+// WriteAndApplyChanges appends a batch of changes to fga_changelog and applies
+// it to fga_tuples in one transaction (hybrid mode)
+func (s *SQLiteAdapter) WriteAndApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return s.writeAndApplyChanges(ctx, changes, nil)
+}
+
+// WriteAndApplyChangesWithToken does the same as WriteAndApplyChanges and
+// saves the continuation token in the same transaction (hybrid mode)
+func (s *SQLiteAdapter) WriteAndApplyChangesWithToken(ctx context.Context, changes []fetcher.ChangeEvent, token string) error {
+	return s.writeAndApplyChanges(ctx, changes, &token)
+}
+
+// writeAndApplyChanges writes both tables and, when token is non-nil, updates
+// sync_state before committing
+func (s *SQLiteAdapter) writeAndApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent, token *string) error {
+	// Start OpenTelemetry span
+	tracer := otel.Tracer("openfga-sync/storage")
+	ctx, span := tracer.Start(ctx, "sqlite.write_and_apply_changes",
+		trace.WithAttributes(
+			attribute.Int("db.changes_count", len(changes)),
+			attribute.String("db.storage_mode", string(s.mode)),
+			attribute.String("db.system", "sqlite"),
+		),
+	)
+	defer span.End()
+
+	if len(changes) == 0 && token == nil {
+		return nil
+	}
+
+	if s.mode != config.StorageModeHybrid {
+		err := fmt.Errorf("WriteAndApplyChanges is only supported in hybrid mode")
+		span.RecordError(err)
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.insertChangelogTx(ctx, tx, changes); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	insertCount, deleteCount, err := s.applyTuplesTx(ctx, tx, changes)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if token != nil {
+		if err := s.saveContinuationTokenTx(ctx, tx, *token); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		span.RecordError(err)
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -321,31 +665,55 @@ func (s *SQLiteAdapter) ApplyChanges(ctx context.Context, changes []fetcher.Chan
 
 	// Add success attributes to span
 	span.SetAttributes(
+		attribute.Int("db.rows_affected", len(changes)),
 		attribute.Int("db.inserts", insertCount),
 		attribute.Int("db.deletes", deleteCount),
-		attribute.String("db.operation", "upsert"),
+		attribute.String("db.operation", "insert_upsert"),
 	)
 
 	s.logger.WithFields(logrus.Fields{
-		"inserts": insertCount,
-		"deletes": deleteCount,
-	}).Info("Successfully applied changes to state table")
+		"changes_count": len(changes),
+		"inserts":       insertCount,
+		"deletes":       deleteCount,
+	}).Info("Successfully wrote changes to changelog and state table")
 	return nil
 }
 
 // GetLastContinuationToken retrieves the last processed continuation token
 func (s *SQLiteAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
-	var token string
-	err := s.db.QueryRowContext(ctx, "SELECT continuation_token FROM sync_state WHERE id = 1").Scan(&token)
+	var token sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT continuation_token FROM "+s.tables.syncState+" WHERE store_id = ? AND object_type = ?", s.storeID, s.objectType).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to get continuation token: %w", err)
 	}
-	return token, nil
+	return token.String, nil
+}
+
+// saveTokenQuery upserts the continuation token of one store cursor
+func (s *SQLiteAdapter) saveTokenQuery() string {
+	return fmt.Sprintf(`
+		INSERT INTO %s (store_id, object_type, continuation_token, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (store_id, object_type)
+		DO UPDATE SET continuation_token = excluded.continuation_token, updated_at = CURRENT_TIMESTAMP
+	`, s.tables.syncState)
 }
 
 // SaveContinuationToken saves the continuation token for resuming processing
 func (s *SQLiteAdapter) SaveContinuationToken(ctx context.Context, token string) error {
-	_, err := s.db.ExecContext(ctx, "UPDATE sync_state SET continuation_token = ?, updated_at = CURRENT_TIMESTAMP WHERE id = 1", token)
+	_, err := s.db.ExecContext(ctx, s.saveTokenQuery(), s.storeID, s.objectType, token)
+	if err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	return nil
+}
+
+// saveContinuationTokenTx saves the continuation token as part of an open transaction
+func (s *SQLiteAdapter) saveContinuationTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
+	_, err := tx.ExecContext(ctx, s.saveTokenQuery(), s.storeID, s.objectType, token)
 	if err != nil {
 		return fmt.Errorf("failed to save continuation token: %w", err)
 	}
@@ -354,23 +722,41 @@ func (s *SQLiteAdapter) SaveContinuationToken(ctx context.Context, token string)
 
 // Close closes the database connection
 func (s *SQLiteAdapter) Close() error {
+	if s.shared {
+		return nil
+	}
+	if s.retention != nil {
+		s.retention.close()
+	}
 	return s.db.Close()
 }
 
+// OnMaintenance registers fn to receive the report of every changelog
+// retention pass. It does nothing when retention is not configured.
+func (s *SQLiteAdapter) OnMaintenance(fn func(MaintenanceReport)) {
+	if s.retention != nil {
+		s.retention.onReport(fn)
+	}
+}
+
 // GetStats returns statistics about the SQLite database
 func (s *SQLiteAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
+	if s.storeID != "" {
+		stats["store_id"] = s.storeID
+	}
 
-	if s.mode == config.StorageModeChangelog {
+	// Hybrid mode reports both tables
+	if s.mode.HasChangelog() {
 		var count int64
-		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog").Scan(&count)
+		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.tables.changelog+" WHERE store_id = ?", s.storeID).Scan(&count)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get changelog count: %w", err)
 		}
 		stats["changelog_entries"] = count
 
 		// Get count by change type
-		rows, err := s.db.QueryContext(ctx, "SELECT change_type, COUNT(*) FROM fga_changelog GROUP BY change_type")
+		rows, err := s.db.QueryContext(ctx, "SELECT change_type, COUNT(*) FROM "+s.tables.changelog+" WHERE store_id = ? GROUP BY change_type", s.storeID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get changelog stats by type: %w", err)
 		}
@@ -386,16 +772,17 @@ func (s *SQLiteAdapter) GetStats(ctx context.Context) (map[string]interface{}, e
 			changeTypeStats[changeType] = count
 		}
 		stats["by_change_type"] = changeTypeStats
-	} else {
+	}
+	if s.mode.HasState() {
 		var count int64
-		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&count)
+		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.tables.tuples+" WHERE store_id = ?", s.storeID).Scan(&count)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get tuples count: %w", err)
 		}
 		stats["current_tuples"] = count
 
 		// Get count by object type
-		rows, err := s.db.QueryContext(ctx, "SELECT object_type, COUNT(*) FROM fga_tuples GROUP BY object_type")
+		rows, err := s.db.QueryContext(ctx, "SELECT object_type, COUNT(*) FROM "+s.tables.tuples+" WHERE store_id = ? GROUP BY object_type", s.storeID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get tuples stats by object type: %w", err)
 		}
@@ -412,6 +799,15 @@ func (s *SQLiteAdapter) GetStats(ctx context.Context) (map[string]interface{}, e
 		}
 		stats["by_object_type"] = objectTypeStats
 	}
+	if s.mode == config.StorageModeHistory {
+		var intervals, current int64
+		err := s.db.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) FILTER (WHERE valid_to IS NULL) FROM "+s.tables.history+" WHERE store_id = ?", s.storeID).Scan(&intervals, &current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tuple history count: %w", err)
+		}
+		stats["history_intervals"] = intervals
+		stats["current_tuples"] = current
+	}
 
 	return stats, nil
 }
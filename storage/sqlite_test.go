@@ -9,6 +9,7 @@ import (
 
 	"github.com/aaguiarz/openfga-sync/config"
 	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/storage/migrations"
 	"github.com/sirupsen/logrus"
 )
 
@@ -429,3 +430,505 @@ func TestSQLiteAdapter_ConditionSupport(t *testing.T) {
 		}
 	})
 }
+
+func TestSQLiteAdapter_ChangesWithToken(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	ctx := context.Background()
+
+	changes := []fetcher.ChangeEvent{
+		{
+			Operation:  "TUPLE_OPERATION_WRITE",
+			ObjectType: "document",
+			ObjectID:   "readme",
+			Relation:   "viewer",
+			UserType:   "user",
+			UserID:     "alice",
+			Timestamp:  time.Now(),
+		},
+	}
+
+	t.Run("changelog", func(t *testing.T) {
+		adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeChangelog, logger)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		defer adapter.Close()
+
+		var txAdapter TransactionalAdapter = adapter
+		if err := txAdapter.WriteChangesWithToken(ctx, changes, "token-1"); err != nil {
+			t.Fatalf("WriteChangesWithToken() error = %v", err)
+		}
+
+		token, err := adapter.GetLastContinuationToken(ctx)
+		if err != nil {
+			t.Fatalf("GetLastContinuationToken() error = %v", err)
+		}
+		if token != "token-1" {
+			t.Errorf("Expected token-1, got %s", token)
+		}
+
+		var count int
+		if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog").Scan(&count); err != nil {
+			t.Fatalf("Failed to count changelog entries: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 changelog entry, got %d", count)
+		}
+	})
+
+	t.Run("stateful", func(t *testing.T) {
+		adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeStateful, logger)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		defer adapter.Close()
+
+		if err := adapter.ApplyChangesWithToken(ctx, changes, "token-2"); err != nil {
+			t.Fatalf("ApplyChangesWithToken() error = %v", err)
+		}
+
+		token, err := adapter.GetLastContinuationToken(ctx)
+		if err != nil {
+			t.Fatalf("GetLastContinuationToken() error = %v", err)
+		}
+		if token != "token-2" {
+			t.Errorf("Expected token-2, got %s", token)
+		}
+
+		var count int
+		if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&count); err != nil {
+			t.Fatalf("Failed to count tuples: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 tuple, got %d", count)
+		}
+	})
+
+	t.Run("failed batch does not advance token", func(t *testing.T) {
+		adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeChangelog, logger)
+		if err != nil {
+			t.Fatalf("Failed to create adapter: %v", err)
+		}
+		defer adapter.Close()
+
+		if err := adapter.SaveContinuationToken(ctx, "before"); err != nil {
+			t.Fatalf("SaveContinuationToken() error = %v", err)
+		}
+
+		// Dropping the table makes the insert fail after the transaction has begun
+		if _, err := adapter.db.ExecContext(ctx, "DROP TABLE fga_changelog"); err != nil {
+			t.Fatalf("Failed to drop changelog table: %v", err)
+		}
+
+		if err := adapter.WriteChangesWithToken(ctx, changes, "after"); err == nil {
+			t.Fatal("Expected WriteChangesWithToken to fail without a changelog table")
+		}
+
+		token, err := adapter.GetLastContinuationToken(ctx)
+		if err != nil {
+			t.Fatalf("GetLastContinuationToken() error = %v", err)
+		}
+		if token != "before" {
+			t.Errorf("Expected token to stay at 'before', got %s", token)
+		}
+	})
+}
+
+func TestSQLiteAdapter_ForStore(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	var multi MultiStoreAdapter = adapter
+	storeA := multi.ForStore("store-a")
+	storeB := multi.ForStore("store-b")
+
+	tuple := fetcher.ChangeEvent{
+		Operation:  "TUPLE_OPERATION_WRITE",
+		ObjectType: "document",
+		ObjectID:   "readme",
+		Relation:   "viewer",
+		UserType:   "user",
+		UserID:     "alice",
+		Timestamp:  time.Now(),
+	}
+
+	// The same tuple can exist in both stores
+	if err := storeA.(TransactionalAdapter).ApplyChangesWithToken(ctx, []fetcher.ChangeEvent{tuple}, "token-a"); err != nil {
+		t.Fatalf("ApplyChangesWithToken(store-a) error = %v", err)
+	}
+	if err := storeB.(TransactionalAdapter).ApplyChangesWithToken(ctx, []fetcher.ChangeEvent{tuple}, "token-b"); err != nil {
+		t.Fatalf("ApplyChangesWithToken(store-b) error = %v", err)
+	}
+
+	// Deleting it from one store leaves the other untouched
+	deleted := tuple
+	deleted.Operation = "TUPLE_OPERATION_DELETE"
+	if err := storeB.ApplyChanges(ctx, []fetcher.ChangeEvent{deleted}); err != nil {
+		t.Fatalf("ApplyChanges(store-b) error = %v", err)
+	}
+
+	for storeID, expected := range map[string]int{"store-a": 1, "store-b": 0} {
+		var count int
+		if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples WHERE store_id = ?", storeID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count tuples: %v", err)
+		}
+		if count != expected {
+			t.Errorf("Expected %d tuples in %s, got %d", expected, storeID, count)
+		}
+	}
+
+	for view, expected := range map[StorageAdapter]string{storeA: "token-a", storeB: "token-b", adapter: ""} {
+		token, err := view.GetLastContinuationToken(ctx)
+		if err != nil {
+			t.Fatalf("GetLastContinuationToken() error = %v", err)
+		}
+		if token != expected {
+			t.Errorf("Expected token %q, got %q", expected, token)
+		}
+	}
+
+	// A new store starts without a checkpoint
+	token, err := multi.ForStore("store-c").GetLastContinuationToken(ctx)
+	if err != nil || token != "" {
+		t.Errorf("Expected empty token for a new store, got %q (%v)", token, err)
+	}
+
+	// Closing a view keeps the shared connection open
+	if err := storeA.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := adapter.db.PingContext(ctx); err != nil {
+		t.Errorf("Expected connection to stay open after closing a view, got %v", err)
+	}
+}
+
+func TestSQLiteAdapter_MigratesLegacySchema(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	dsn := t.TempDir() + "/legacy.db"
+
+	adapter, err := NewSQLiteAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	// Recreate the tables as they were before multi-store support, when the
+	// schema wasn't versioned yet
+	for _, query := range []string{
+		`DROP TABLE schema_migrations`,
+		`DROP TABLE sync_state`,
+		`DROP TABLE fga_tuples`,
+		`CREATE TABLE sync_state (id INTEGER PRIMARY KEY AUTOINCREMENT, continuation_token TEXT, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO sync_state (id, continuation_token) VALUES (1, 'legacy-token')`,
+		`CREATE TABLE fga_tuples (
+			object_type TEXT NOT NULL, object_id TEXT NOT NULL, relation TEXT NOT NULL,
+			user_type TEXT NOT NULL, user_id TEXT NOT NULL, condition TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (object_type, object_id, relation, user_type, user_id)
+		)`,
+		`INSERT INTO fga_tuples (object_type, object_id, relation, user_type, user_id) VALUES ('document', 'readme', 'viewer', 'user', 'alice')`,
+	} {
+		if _, err := adapter.db.ExecContext(ctx, query); err != nil {
+			t.Fatalf("Failed to create legacy schema: %v", err)
+		}
+	}
+	adapter.Close()
+
+	adapter, err = NewSQLiteAdapter(dsn, config.StorageModeStateful, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen legacy database: %v", err)
+	}
+	defer adapter.Close()
+
+	// Existing rows stay with the single-store adapter
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil || token != "legacy-token" {
+		t.Errorf("Expected legacy-token, got %q (%v)", token, err)
+	}
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["current_tuples"] != int64(1) {
+		t.Errorf("Expected 1 migrated tuple, got %v", stats["current_tuples"])
+	}
+
+	// The rebuilt primary key lets another store hold the same tuple
+	tuple := fetcher.ChangeEvent{
+		Operation:  "TUPLE_OPERATION_WRITE",
+		ObjectType: "document",
+		ObjectID:   "readme",
+		Relation:   "viewer",
+		UserType:   "user",
+		UserID:     "alice",
+	}
+	if err := adapter.ForStore("store-a").ApplyChanges(ctx, []fetcher.ChangeEvent{tuple}); err != nil {
+		t.Fatalf("ApplyChanges(store-a) error = %v", err)
+	}
+	var count int
+	if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_tuples").Scan(&count); err != nil {
+		t.Fatalf("Failed to count tuples: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 tuples across stores, got %d", count)
+	}
+
+	// The upgraded database is versioned from then on
+	var version int
+	if err := adapter.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected schema version 1, got %d", version)
+	}
+}
+
+func TestSQLiteAdapter_ForObjectType(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	var cursors CursorAdapter = adapter
+	documents := cursors.ForObjectType("document")
+	folders := adapter.ForStore("store-a").(CursorAdapter).ForObjectType("folder")
+
+	if err := documents.SaveContinuationToken(ctx, "document-token"); err != nil {
+		t.Fatalf("SaveContinuationToken(document) error = %v", err)
+	}
+	if err := folders.SaveContinuationToken(ctx, "folder-token"); err != nil {
+		t.Fatalf("SaveContinuationToken(folder) error = %v", err)
+	}
+	if err := adapter.SaveContinuationToken(ctx, "all-types-token"); err != nil {
+		t.Fatalf("SaveContinuationToken() error = %v", err)
+	}
+
+	for view, expected := range map[StorageAdapter]string{
+		documents:                     "document-token",
+		folders:                       "folder-token",
+		adapter:                       "all-types-token",
+		adapter.ForStore("store-a"):   "",
+		cursors.ForObjectType("team"): "",
+	} {
+		token, err := view.GetLastContinuationToken(ctx)
+		if err != nil {
+			t.Fatalf("GetLastContinuationToken() error = %v", err)
+		}
+		if token != expected {
+			t.Errorf("Expected token %q, got %q", expected, token)
+		}
+	}
+}
+
+func TestSQLiteAdapter_HybridMode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	ctx := context.Background()
+
+	adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeHybrid, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	now := time.Now()
+	changes := []fetcher.ChangeEvent{
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "readme", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "readme", Relation: "editor", UserType: "user", UserID: "bob", Timestamp: now},
+		{Operation: "TUPLE_OPERATION_DELETE", ObjectType: "document", ObjectID: "readme", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+	}
+
+	var hybridAdapter HybridAdapter = adapter
+	if err := hybridAdapter.WriteAndApplyChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("WriteAndApplyChangesWithToken() error = %v", err)
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["changelog_entries"] != int64(3) {
+		t.Errorf("Expected 3 changelog entries, got %v", stats["changelog_entries"])
+	}
+	if stats["current_tuples"] != int64(1) {
+		t.Errorf("Expected 1 current tuple, got %v", stats["current_tuples"])
+	}
+	if token, _ := adapter.GetLastContinuationToken(ctx); token != "token-1" {
+		t.Errorf("Expected token-1, got %s", token)
+	}
+
+	// A failure on the state table rolls back the changelog rows and the token
+	if _, err := adapter.db.ExecContext(ctx, "DROP TABLE fga_tuples"); err != nil {
+		t.Fatalf("Failed to drop tuples table: %v", err)
+	}
+	if err := adapter.WriteAndApplyChangesWithToken(ctx, changes, "token-2"); err == nil {
+		t.Fatal("Expected WriteAndApplyChangesWithToken to fail without a tuples table")
+	}
+
+	var count int
+	if err := adapter.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fga_changelog").Scan(&count); err != nil {
+		t.Fatalf("Failed to count changelog entries: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the failed batch to be rolled back, got %d changelog entries", count)
+	}
+	if token, _ := adapter.GetLastContinuationToken(ctx); token != "token-1" {
+		t.Errorf("Expected token to stay at token-1, got %s", token)
+	}
+
+	// Other modes reject the combined write
+	changelogAdapter, err := NewSQLiteAdapter(":memory:", config.StorageModeChangelog, logger)
+	if err != nil {
+		t.Fatalf("Failed to create changelog adapter: %v", err)
+	}
+	defer changelogAdapter.Close()
+	if err := changelogAdapter.WriteAndApplyChanges(ctx, changes); err == nil {
+		t.Error("Expected WriteAndApplyChanges to fail in changelog mode")
+	}
+}
+
+func TestSQLiteAdapter_HistoryMode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	adapter, err := NewSQLiteAdapter(":memory:", config.StorageModeHistory, logger)
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	if _, err := TuplesAsOf(context.Background(), adapter, AsOfQuery{}); err == nil {
+		t.Error("Expected TuplesAsOf to require a point in time")
+	}
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 12, 0, 0, 0, time.UTC) }
+	change := func(operation string, userID string, ts time.Time) fetcher.ChangeEvent {
+		return fetcher.ChangeEvent{Operation: operation, ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: userID, Timestamp: ts}
+	}
+	changes := []fetcher.ChangeEvent{
+		change("TUPLE_OPERATION_WRITE", "anne", day(1)),
+		change("TUPLE_OPERATION_WRITE", "bob", day(2)),
+		change("TUPLE_OPERATION_DELETE", "anne", day(3)),
+		change("TUPLE_OPERATION_WRITE", "anne", day(5)),
+	}
+
+	if err := adapter.ApplyChangesWithToken(ctx, changes, "token-1"); err != nil {
+		t.Fatalf("ApplyChangesWithToken() error = %v", err)
+	}
+	// Replaying the batch must not duplicate intervals
+	if err := adapter.ApplyChanges(ctx, changes); err != nil {
+		t.Fatalf("ApplyChanges() replay error = %v", err)
+	}
+
+	users := func(at time.Time) []string {
+		tuples, err := TuplesAsOf(ctx, adapter, AsOfQuery{At: at, ObjectType: "document", ObjectID: "roadmap"})
+		if err != nil {
+			t.Fatalf("TuplesAsOf() error = %v", err)
+		}
+		var users []string
+		for _, tuple := range tuples {
+			users = append(users, tuple.UserID)
+		}
+		return users
+	}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{day(1).Add(-time.Hour), ""},
+		{day(1), "anne"},
+		{day(2), "anne,bob"},
+		{day(3), "bob"},
+		{day(4), "bob"},
+		{day(6), "anne,bob"},
+	} {
+		if got := strings.Join(users(tt.at), ","); got != tt.want {
+			t.Errorf("Tuples as of %s = %q, want %q", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+
+	stats, err := adapter.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["history_intervals"] != int64(3) || stats["current_tuples"] != int64(2) {
+		t.Errorf("Expected 3 intervals and 2 current tuples, got %v and %v", stats["history_intervals"], stats["current_tuples"])
+	}
+}
+
+func TestSQLiteAdapter_TablePrefix(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	dsn := t.TempDir() + "/shared.db"
+
+	// Two pipelines share one database file with their own tables
+	us, err := NewSQLiteAdapterWithNaming(dsn, config.StorageModeHybrid, migrations.Naming{Prefix: "us_"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create us_ adapter: %v", err)
+	}
+	defer us.Close()
+	eu, err := NewSQLiteAdapterWithNaming(dsn, config.StorageModeHistory, migrations.Naming{Prefix: "eu_"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create eu_ adapter: %v", err)
+	}
+	defer eu.Close()
+
+	now := time.Now()
+	changes := []fetcher.ChangeEvent{
+		{Operation: "TUPLE_OPERATION_WRITE", ObjectType: "document", ObjectID: "readme", Relation: "viewer", UserType: "user", UserID: "alice", Timestamp: now},
+	}
+	if err := us.WriteAndApplyChangesWithToken(ctx, changes, "us-token"); err != nil {
+		t.Fatalf("WriteAndApplyChangesWithToken() error = %v", err)
+	}
+	if err := eu.ApplyChangesWithToken(ctx, changes, "eu-token"); err != nil {
+		t.Fatalf("ApplyChangesWithToken() error = %v", err)
+	}
+
+	for adapter, want := range map[*SQLiteAdapter]string{us: "us-token", eu: "eu-token"} {
+		if token, err := adapter.GetLastContinuationToken(ctx); err != nil || token != want {
+			t.Errorf("Expected %s, got %q (%v)", want, token, err)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, table := range []string{"us_fga_changelog", "us_fga_tuples", "eu_fga_tuple_history", "eu_fga_tuples", "fga_tuples"} {
+		var count int
+		err := us.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+		if err != nil {
+			t.Fatalf("Failed to inspect table %s: %v", table, err)
+		}
+		if count > 0 {
+			if err := us.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+				t.Fatalf("Failed to count %s: %v", table, err)
+			}
+		} else {
+			count = -1
+		}
+		counts[table] = count
+	}
+	want := map[string]int{"us_fga_changelog": 1, "us_fga_tuples": 1, "eu_fga_tuple_history": 1, "eu_fga_tuples": 0, "fga_tuples": -1}
+	for table, count := range want {
+		if counts[table] != count {
+			t.Errorf("Expected %d rows in %s, got %d (-1 means missing)", count, table, counts[table])
+		}
+	}
+
+	if _, err := NewSQLiteAdapterWithNaming(dsn, config.StorageModeStateful, migrations.Naming{Schema: "authz"}, logger); err == nil {
+		t.Error("Expected a schema to be rejected for sqlite")
+	}
+}
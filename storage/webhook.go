@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of timestamp.body>"
+	WebhookSignatureHeader = "X-OpenFGA-Sync-Signature"
+	// WebhookTimestampHeader carries the Unix time the request was signed at
+	WebhookTimestampHeader = "X-OpenFGA-Sync-Timestamp"
+	// WebhookDeliveryHeader identifies a batch; it is the same on every retry
+	WebhookDeliveryHeader = "X-OpenFGA-Sync-Delivery"
+)
+
+// WebhookAdapter implements StorageAdapter by POSTing batches of change events
+// as JSON to an HTTP endpoint. Requests are signed with an HMAC of the body and
+// retried with exponential backoff; the continuation token is kept in a
+// checkpoint store and only saved once the endpoint answered with a 2xx.
+type WebhookAdapter struct {
+	client     *http.Client
+	url        string
+	secret     []byte
+	headers    map[string]string
+	retry      fetcher.RetryConfig
+	logger     *logrus.Logger
+	mode       config.StorageMode
+	lastToken  string
+	deliveries atomic.Int64 // Read by GetStats from the monitoring goroutine
+	failures   atomic.Int64
+	checkpoint CheckpointStore
+}
+
+// WebhookConfig represents the configuration for the webhook adapter
+type WebhookConfig struct {
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret,omitempty"`  // HMAC-SHA256 signing key; requests are unsigned without it
+	Headers    map[string]string `json:"headers,omitempty"` // Extra request headers, e.g. Authorization
+	Timeout    string            `json:"timeout,omitempty"` // String format like "10s"
+	Checkpoint CheckpointConfig  `json:"checkpoint,omitempty"`
+}
+
+// WebhookPayload is the JSON body of a webhook request
+type WebhookPayload struct {
+	Mode    config.StorageMode    `json:"mode"`
+	Changes []fetcher.ChangeEvent `json:"changes"`
+}
+
+// webhookStatusError is returned for a non-2xx response
+type webhookStatusError struct {
+	status int
+	body   string
+}
+
+func (e *webhookStatusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("webhook returned status %d", e.status)
+	}
+	return fmt.Sprintf("webhook returned status %d: %s", e.status, e.body)
+}
+
+// NewWebhookAdapter creates a new webhook storage adapter. Failed deliveries
+// are retried with the given backoff settings.
+func NewWebhookAdapter(dsn string, mode config.StorageMode, retry fetcher.RetryConfig, logger *logrus.Logger) (*WebhookAdapter, error) {
+	cfg, err := parseWebhookDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook DSN: %w", err)
+	}
+
+	timeout := 10 * time.Second
+	if cfg.Timeout != "" {
+		parsed, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout format: %w", err)
+		}
+		timeout = parsed
+	}
+
+	endpoint, _ := url.Parse(cfg.URL)
+	checkpoint, err := NewCheckpointStore(cfg.Checkpoint, "webhook:"+endpoint.Host+endpoint.Path, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	adapter := &WebhookAdapter{
+		client:     &http.Client{Timeout: timeout},
+		url:        cfg.URL,
+		secret:     []byte(cfg.Secret),
+		headers:    cfg.Headers,
+		retry:      retry,
+		logger:     logger,
+		mode:       mode,
+		checkpoint: checkpoint,
+	}
+
+	logger.WithFields(logrus.Fields{
+		"url":             endpoint.Redacted(),
+		"signed":          cfg.Secret != "",
+		"storage_mode":    mode,
+		"max_retries":     retry.MaxRetries,
+		"checkpoint_type": checkpoint.Type(),
+	}).Info("Successfully created webhook storage adapter")
+
+	return adapter, nil
+}
+
+// parseWebhookDSN parses the webhook DSN configuration string
+// Supports two formats:
+// 1. Simple: the endpoint URL (e.g., "https://hooks.example.com/openfga")
+// 2. JSON: {"url":"https://hooks.example.com/openfga","secret":"...","checkpoint":{"type":"file","dsn":"/var/lib/openfga-sync/token"}}
+func parseWebhookDSN(dsn string) (*WebhookConfig, error) {
+	var cfg WebhookConfig
+
+	// If DSN starts with {, treat it as JSON format
+	if strings.HasPrefix(dsn, "{") {
+		if err := json.Unmarshal([]byte(dsn), &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON DSN: %w", err)
+		}
+	} else {
+		cfg.URL = dsn
+	}
+
+	endpoint, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("DSN must contain an http or https URL")
+	}
+
+	return &cfg, nil
+}
+
+// WriteChanges delivers a batch of change events (changelog mode)
+func (w *WebhookAdapter) WriteChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return w.deliver(ctx, changes)
+}
+
+// ApplyChanges delivers a batch of change events (stateful mode). The
+// receiver applies writes and deletes to its own state.
+func (w *WebhookAdapter) ApplyChanges(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	return w.deliver(ctx, changes)
+}
+
+// deliver POSTs the batch, retrying until the endpoint answers with a 2xx or
+// the retries are exhausted
+func (w *WebhookAdapter) deliver(ctx context.Context, changes []fetcher.ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(WebhookPayload{Mode: w.mode, Changes: changes})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	// The delivery ID depends only on the batch, so receivers can drop a batch
+	// redelivered after a crash or a lost response
+	sum := sha256.Sum256(body)
+	deliveryID := hex.EncodeToString(sum[:16])
+
+	err = fetcher.RetryWithBackoff(ctx, w.retry, w.logger, func() error {
+		return w.post(ctx, body, deliveryID)
+	})
+	if err != nil {
+		w.failures.Add(1)
+		return fmt.Errorf("failed to deliver changes to webhook: %w", err)
+	}
+	w.deliveries.Add(1)
+
+	w.logger.WithFields(logrus.Fields{
+		"delivery": deliveryID,
+		"changes":  len(changes),
+	}).Debug("Delivered changes to webhook")
+
+	return nil
+}
+
+// post sends one signed request
+func (w *WebhookAdapter) post(ctx context.Context, body []byte, deliveryID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "openfga-sync")
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	if len(w.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &webhookStatusError{status: resp.StatusCode, body: strings.TrimSpace(string(snippet))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// SignWebhookPayload returns the signature header value for a request body
+// signed at timestamp. Receivers recompute it over the raw body and the
+// timestamp header and compare with hmac.Equal.
+func SignWebhookPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetLastContinuationToken retrieves the last processed continuation token
+func (w *WebhookAdapter) GetLastContinuationToken(ctx context.Context) (string, error) {
+	token, err := w.checkpoint.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load continuation token: %w", err)
+	}
+	w.lastToken = token
+	return token, nil
+}
+
+// SaveContinuationToken saves the continuation token for resuming processing
+func (w *WebhookAdapter) SaveContinuationToken(ctx context.Context, token string) error {
+	if err := w.checkpoint.Save(ctx, token); err != nil {
+		return fmt.Errorf("failed to save continuation token: %w", err)
+	}
+	w.lastToken = token
+	w.logger.WithField("token", token).Debug("Saved continuation token")
+	return nil
+}
+
+// Close closes the checkpoint store
+func (w *WebhookAdapter) Close() error {
+	w.logger.Info("Closing webhook adapter")
+	w.client.CloseIdleConnections()
+	return w.checkpoint.Close()
+}
+
+// GetStats returns statistics about the webhook adapter
+func (w *WebhookAdapter) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	endpoint, _ := url.Parse(w.url)
+	return map[string]interface{}{
+		"adapter_type":    "webhook",
+		"url":             endpoint.Redacted(),
+		"storage_mode":    string(w.mode),
+		"last_token":      w.lastToken,
+		"checkpoint_type": w.checkpoint.Type(),
+		"deliveries":      w.deliveries.Load(),
+		"failures":        w.failures.Load(),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseWebhookDSN(t *testing.T) {
+	cfg, err := parseWebhookDSN("https://hooks.example.com/openfga")
+	if err != nil {
+		t.Fatalf("Failed to parse simple DSN: %v", err)
+	}
+	if cfg.URL != "https://hooks.example.com/openfga" {
+		t.Errorf("Unexpected simple DSN config: %+v", cfg)
+	}
+
+	cfg, err = parseWebhookDSN(`{"url":"http://localhost:9000/hook","secret":"s3cret","headers":{"Authorization":"Bearer x"}}`)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON DSN: %v", err)
+	}
+	if cfg.Secret != "s3cret" || cfg.Headers["Authorization"] != "Bearer x" {
+		t.Errorf("Unexpected JSON DSN config: %+v", cfg)
+	}
+
+	for _, dsn := range []string{"", "hooks.example.com/openfga", "ftp://hooks.example.com", `{"secret":"s3cret"}`} {
+		if _, err := parseWebhookDSN(dsn); err == nil {
+			t.Errorf("Expected error for DSN %q", dsn)
+		}
+	}
+}
+
+func TestWebhookAdapter_SignsAndRetries(t *testing.T) {
+	secret := []byte("s3cret")
+
+	var mu sync.Mutex
+	var attempts int
+	var deliveryIDs []string
+	var received WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		deliveryIDs = append(deliveryIDs, r.Header.Get(WebhookDeliveryHeader))
+
+		expected := SignWebhookPayload(secret, r.Header.Get(WebhookTimestampHeader), body)
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get(WebhookSignatureHeader))) {
+			t.Errorf("Invalid signature %q", r.Header.Get(WebhookSignatureHeader))
+		}
+
+		// Fail the first attempt to exercise the retry
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("Body is not a webhook payload: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	retry := fetcher.RetryConfig{MaxRetries: 2, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, BackoffFactor: 2}
+
+	dsn, _ := json.Marshal(WebhookConfig{
+		URL:        server.URL + "/hook",
+		Secret:     string(secret),
+		Checkpoint: CheckpointConfig{Type: "file", DSN: filepath.Join(t.TempDir(), "token")},
+	})
+	adapter, err := NewWebhookAdapter(string(dsn), config.StorageModeChangelog, retry, logger)
+	if err != nil {
+		t.Fatalf("Failed to create webhook adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	changes := []fetcher.ChangeEvent{
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_write", Timestamp: time.Now().UTC()},
+		{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_delete", Timestamp: time.Now().UTC()},
+	}
+	if err := adapter.WriteChanges(ctx, changes); err != nil {
+		t.Fatalf("Failed to deliver changes: %v", err)
+	}
+
+	mu.Lock()
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if len(deliveryIDs) == 2 && (deliveryIDs[0] == "" || deliveryIDs[0] != deliveryIDs[1]) {
+		t.Errorf("Expected the same delivery ID on retry, got %v", deliveryIDs)
+	}
+	if received.Mode != config.StorageModeChangelog || len(received.Changes) != 2 || received.Changes[1].ChangeType != "tuple_delete" {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+	mu.Unlock()
+}
+
+func TestWebhookAdapter_FailedDeliveryKeepsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "receiver down", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	retry := fetcher.RetryConfig{MaxRetries: 1, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 2}
+
+	adapter, err := NewWebhookAdapter(server.URL, config.StorageModeStateful, retry, logger)
+	if err != nil {
+		t.Fatalf("Failed to create webhook adapter: %v", err)
+	}
+	defer adapter.Close()
+
+	ctx := context.Background()
+	if err := adapter.SaveContinuationToken(ctx, "token-1"); err != nil {
+		t.Fatalf("Failed to save continuation token: %v", err)
+	}
+
+	changes := []fetcher.ChangeEvent{{ObjectType: "document", ObjectID: "roadmap", Relation: "viewer", UserType: "user", UserID: "anne", ChangeType: "tuple_write"}}
+	if err := adapter.ApplyChanges(ctx, changes); err == nil {
+		t.Fatal("Expected an error for a non-2xx response")
+	}
+
+	token, err := adapter.GetLastContinuationToken(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load continuation token: %v", err)
+	}
+	if token != "token-1" {
+		t.Errorf("Expected token-1, got %q", token)
+	}
+
+	stats, _ := adapter.GetStats(ctx)
+	if stats["failures"] != int64(1) || stats["deliveries"] != int64(0) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
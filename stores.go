@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aaguiarz/openfga-sync/config"
+	"github.com/aaguiarz/openfga-sync/fetcher"
+	"github.com/aaguiarz/openfga-sync/metrics"
+	"github.com/aaguiarz/openfga-sync/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// runStores runs the sync loop for every configured store. A single store uses
+// the adapter as is, so existing databases keep their rows and checkpoint.
+// Several stores each get their own fetcher and a store-scoped view of the
+// adapter, and run independently until ctx is cancelled.
+func runStores(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) error {
+	if !cfg.OpenFGA.MultiStore() {
+		metrics.UpdateSyncedStores(1)
+		defer metrics.UpdateSyncedStores(0)
+		return runStore(ctx, fgaFetcher, storageAdapter, cfg, logger, metrics)
+	}
+
+	multiAdapter, ok := storageAdapter.(storage.MultiStoreAdapter)
+	if !ok {
+		return fmt.Errorf("backend %s does not support multi-store sync", cfg.Backend.Type)
+	}
+
+	syncer := &storeSyncer{
+		cfg:     cfg,
+		adapter: multiAdapter,
+		logger:  logger,
+		metrics: metrics,
+		running: make(map[string]context.CancelFunc),
+	}
+	defer syncer.wait()
+
+	storeIDs, err := resolveStores(ctx, fgaFetcher, cfg)
+	if err != nil {
+		return err
+	}
+	syncer.reconcile(ctx, storeIDs)
+
+	// Restart failed loops and, with all_stores, pick up stores created or
+	// deleted after startup
+	ticker := time.NewTicker(cfg.Service.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			storeIDs, err := resolveStores(ctx, fgaFetcher, cfg)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				logger.WithError(err).Error("Failed to discover stores")
+				continue
+			}
+			syncer.reconcile(ctx, storeIDs)
+		}
+	}
+}
+
+// runStore syncs one store. With service.object_types it runs one sync loop per
+// type, each fetching with the ReadChanges type filter and keeping its own
+// cursor; if any of them fails the others are stopped so the store restarts
+// as a whole.
+func runStore(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, storageAdapter storage.StorageAdapter, cfg *config.Config, logger *logrus.Logger, metrics *metrics.Metrics) error {
+	objectTypes := cfg.Service.ObjectTypes
+	if len(objectTypes) == 0 {
+		return runSyncLoop(ctx, fgaFetcher, storageAdapter, cfg, logger, metrics)
+	}
+
+	cursorAdapter, perType := storageAdapter.(storage.CursorAdapter)
+	if !perType && len(objectTypes) > 1 {
+		return fmt.Errorf("backend %s does not support per-type cursors", cfg.Backend.Type)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(objectTypes))
+	for i, objectType := range objectTypes {
+		typeFetcher, err := newFetcher(cfg, fgaFetcher.StoreID(), objectType, logger)
+		if err != nil {
+			cancel()
+			errs[i] = fmt.Errorf("failed to initialize OpenFGA fetcher for type %s: %w", objectType, err)
+			break
+		}
+
+		typeAdapter := storageAdapter
+		if perType {
+			typeAdapter = cursorAdapter.ForObjectType(objectType)
+			defer typeAdapter.Close()
+		}
+
+		wg.Add(1)
+		go func(i int, objectType string) {
+			defer wg.Done()
+			defer typeFetcher.Close()
+
+			if err := runSyncLoop(ctx, typeFetcher, typeAdapter, cfg, logger, metrics); err != nil {
+				errs[i] = fmt.Errorf("type %s: %w", objectType, err)
+				cancel()
+			}
+		}(i, objectType)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// resolveStores returns the stores to sync, listing them from OpenFGA when
+// openfga.all_stores is enabled
+func resolveStores(ctx context.Context, fgaFetcher *fetcher.OpenFGAFetcher, cfg *config.Config) ([]string, error) {
+	if !cfg.OpenFGA.AllStores {
+		return cfg.OpenFGA.Stores(), nil
+	}
+
+	storeIDs, err := fgaFetcher.ListStores(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover stores: %w", err)
+	}
+	return storeIDs, nil
+}
+
+// storeSyncer tracks the per-store sync loops of a multi-store process
+type storeSyncer struct {
+	cfg     *config.Config
+	adapter storage.MultiStoreAdapter
+	logger  *logrus.Logger
+	metrics *metrics.Metrics
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running map[string]context.CancelFunc
+}
+
+// reconcile starts a sync loop for each new store and stops the loops of
+// stores that are no longer listed
+func (s *storeSyncer) reconcile(ctx context.Context, storeIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(storeIDs))
+	for _, storeID := range storeIDs {
+		wanted[storeID] = true
+	}
+
+	var stopped []string
+	for storeID, cancel := range s.running {
+		if !wanted[storeID] {
+			cancel()
+			delete(s.running, storeID)
+			stopped = append(stopped, storeID)
+		}
+	}
+
+	var started []string
+	for _, storeID := range storeIDs {
+		if _, ok := s.running[storeID]; ok {
+			continue
+		}
+		if err := s.start(ctx, storeID); err != nil {
+			s.logger.WithError(err).WithField("store_id", storeID).Error("Failed to start store sync")
+			continue
+		}
+		started = append(started, storeID)
+	}
+
+	s.metrics.UpdateSyncedStores(len(s.running))
+	if len(started) > 0 || len(stopped) > 0 {
+		sort.Strings(stopped)
+		s.logger.WithFields(logrus.Fields{
+			"started": started,
+			"stopped": stopped,
+			"stores":  len(s.running),
+		}).Info("Updated synced stores")
+	}
+}
+
+// start launches the sync loop of one store. Callers must hold s.mu.
+func (s *storeSyncer) start(ctx context.Context, storeID string) error {
+	storeFetcher, err := newFetcher(s.cfg, storeID, "", s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize OpenFGA fetcher: %w", err)
+	}
+
+	storeCtx, cancel := context.WithCancel(ctx)
+	s.running[storeID] = cancel
+	storeAdapter := s.adapter.ForStore(storeID)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer storeFetcher.Close()
+		defer storeAdapter.Close()
+
+		if err := runStore(storeCtx, storeFetcher, storeAdapter, s.cfg, s.logger, s.metrics); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.WithError(err).WithField("store_id", storeID).Error("Store sync loop terminated with error")
+			s.metrics.RecordChangesError()
+		}
+
+		// Allow a failed store to be restarted by the next reconcile
+		s.mu.Lock()
+		if _, ok := s.running[storeID]; ok && storeCtx.Err() == nil {
+			delete(s.running, storeID)
+			s.metrics.UpdateSyncedStores(len(s.running))
+		}
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	return nil
+}
+
+// wait stops all store loops and waits for them to return
+func (s *storeSyncer) wait() {
+	s.mu.Lock()
+	for _, cancel := range s.running {
+		cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.metrics.UpdateSyncedStores(0)
+}